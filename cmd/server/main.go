@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -12,10 +13,23 @@ import (
 
 	"sd/pkg/core"
 	"sd/pkg/env"
+	"sd/pkg/focus"
+	"sd/pkg/models"
 	"sd/pkg/natsconn"
+	"sd/pkg/plugins/audio"
 	"sd/pkg/plugins/browser"
+	"sd/pkg/plugins/clipboard"
 	"sd/pkg/plugins/command"
+	"sd/pkg/plugins/homeassistant"
 	"sd/pkg/plugins/keyboard"
+	"sd/pkg/plugins/media"
+	"sd/pkg/plugins/obsstudio"
+	"sd/pkg/plugins/sysmon"
+	"sd/pkg/plugins/timer"
+	"sd/pkg/plugins/webhook"
+	"sd/pkg/plugins/window"
+	"sd/pkg/plugins/workspace"
+	"sd/pkg/scheduler"
 	"sd/pkg/store"
 	"sd/pkg/streamdeck"
 	"sd/pkg/types"
@@ -23,32 +37,6 @@ import (
 	"sd/pkg/watchers"
 )
 
-const (
-	// Device Types
-	DeviceTypeXL    = "xl"
-	DeviceTypePlus  = "plus"
-	DeviceTypePedal = "pedal"
-
-	// USB IDs
-	VendorIDElgato = 0x0fd9
-	ProductIDXL    = 0x006c // Stream Deck XL
-	ProductIDPlus  = 0x0084 // Stream Deck +
-	ProductIDPedal = 0x0086 // Stream Deck Pedal
-)
-
-func DetermineDeviceType(productID uint16) string {
-	switch productID {
-	case ProductIDXL:
-		return DeviceTypeXL
-	case ProductIDPlus:
-		return DeviceTypePlus
-	case ProductIDPedal:
-		return DeviceTypePedal
-	default:
-		return "unknown"
-	}
-}
-
 func disconnectDevice(instanceID string, deviceID string, status string) error {
 	_, kv := natsconn.GetNATSConn()
 	if kv == nil {
@@ -107,7 +95,7 @@ func connectDevice(instanceID string, deviceID string, productID uint16) error {
 	// Update device fields while preserving others
 	device.ID = deviceID
 	device.Instance = instanceID
-	device.Type = DetermineDeviceType(productID)
+	device.Type = models.DeviceType(productID)
 	device.Status = "connected"
 
 	data, err := json.Marshal(device)
@@ -185,9 +173,19 @@ func main() {
 
 	// Register plugins.
 	registry := core.NewPluginRegistry()
-	registry.Register(&browser.BrowserPlugin{})
-	registry.Register(&command.CommandPlugin{})
-	registry.Register(&keyboard.KeyboardPlugin{})
+	registry.Register(&audio.AudioPlugin{InstanceID: instanceID})
+	registry.Register(&browser.BrowserPlugin{InstanceID: instanceID})
+	registry.Register(&clipboard.ClipboardPlugin{InstanceID: instanceID})
+	registry.Register(&command.CommandPlugin{InstanceID: instanceID})
+	registry.Register(&homeassistant.HomeAssistantPlugin{InstanceID: instanceID})
+	registry.Register(&keyboard.KeyboardPlugin{InstanceID: instanceID})
+	registry.Register(&media.MediaPlugin{InstanceID: instanceID})
+	registry.Register(&obsstudio.OBSPlugin{InstanceID: instanceID})
+	registry.Register(&sysmon.SysmonPlugin{InstanceID: instanceID})
+	registry.Register(&timer.TimerPlugin{InstanceID: instanceID})
+	registry.Register(&webhook.WebhookPlugin{InstanceID: instanceID})
+	registry.Register(&window.WindowPlugin{InstanceID: instanceID})
+	registry.Register(&workspace.WorkspacePlugin{InstanceID: instanceID})
 
 	// Initialize plugins.
 	for _, plugin := range registry.All() {
@@ -196,6 +194,11 @@ func main() {
 		log.Info().Str("plugin", plugin.Name()).Msg("Plugin subscribed successfully")
 	}
 
+	// Run schedules, before devices connect so that their connect schedules run.
+	if err := scheduler.Start(context.Background(), instanceID); err != nil {
+		log.Error().Err(err).Msg("Failed to start scheduler")
+	}
+
 	// Start watching Stream Deck devices with connect/disconnect handlers
 	go func() {
 		err := watchers.WatchStreamDecks(
@@ -214,6 +217,12 @@ func main() {
 		}
 	}()
 
+	// Switch profiles automatically with the focused window.
+	if err := focus.SubscribeSwitcher(instanceID); err != nil {
+		log.Error().Err(err).Msg("Failed to subscribe to focus changes")
+	}
+	go focus.Watch(context.Background(), instanceID)
+
 	log.Info().Msg("Watching Stream Decks")
 	// Keep the main program running.
 	select {}
@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sd/cmd/web/views/partials"
+	"sd/pkg/actions"
+	"sd/pkg/models"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const maxImageUploadSize = 10 << 20
+
+// buttonContext holds the entities a button request refers to.
+type buttonContext struct {
+	instance types.Instance
+	device   *types.Device
+	profile  *types.Profile
+	page     *types.Page
+	buttonID string
+}
+
+func loadButtonContext(r *http.Request) (*buttonContext, error) {
+	instanceID := r.FormValue("instanceId")
+	deviceID := r.FormValue("deviceId")
+	profileID := r.FormValue("profileId")
+	pageID := r.FormValue("pageId")
+	buttonID := r.FormValue("buttonId")
+
+	if instanceID == "" || deviceID == "" || profileID == "" || pageID == "" || buttonID == "" {
+		return nil, fmt.Errorf("instanceId, deviceId, profileId, pageId and buttonId are required")
+	}
+
+	device := store.GetDevice(instanceID, deviceID)
+	if device == nil {
+		return nil, fmt.Errorf("device not found")
+	}
+
+	profile := store.GetProfile(instanceID, device, profileID)
+	if profile == nil {
+		return nil, fmt.Errorf("profile not found")
+	}
+
+	page := store.GetPage(instanceID, deviceID, profileID, pageID)
+	if page == nil {
+		return nil, fmt.Errorf("page not found")
+	}
+
+	return &buttonContext{
+		instance: store.GetInstance(instanceID),
+		device:   device,
+		profile:  profile,
+		page:     page,
+		buttonID: buttonID,
+	}, nil
+}
+
+func (c *buttonContext) key(buttonID string) string {
+	return fmt.Sprintf("instances.%s.devices.%s.profiles.%s.pages.%s.buttons.%s",
+		c.instance.ID, c.device.ID, c.profile.ID, c.page.ID, buttonID)
+}
+
+// keyCount returns the number of keys on a device type.
+func keyCount(deviceType string) int {
+	model, _ := models.ByType(deviceType)
+	return model.Keys()
+}
+
+// renderInspector renders the inspector for button and refreshes the given key images.
+func renderInspector(w http.ResponseWriter, r *http.Request, c *buttonContext, button *types.Button, refresh ...string) {
+	err := partials.ButtonInspector(c.instance, c.device, c.profile, c.page, button, store.GetImages(), keyCount(c.device.Type)).Render(r.Context(), w)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to render button inspector")
+		return
+	}
+
+	version := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	for _, buttonID := range refresh {
+		if err := partials.ButtonImage(c.instance, c.device, c.profile, c.page, buttonID, version, true).Render(r.Context(), w); err != nil {
+			log.Error().Err(err).Msg("Failed to render button image")
+		}
+	}
+}
+
+// buttonFromForm applies the inspector form values to a button.
+func buttonFromForm(button types.Button, form url.Values) (types.Button, error) {
+	button.UUID = form.Get("uuid")
+	if button.UUID == "" {
+		button.UUID = actions.None
+	}
+
+	button.Settings = types.Settings{}
+	if definition, ok := actions.Get(button.UUID); ok {
+		settings, err := actions.ParseSettings(definition, form)
+		if err != nil {
+			return button, err
+		}
+		button.Settings = settings
+	}
+
+	button.Title = strings.ReplaceAll(form.Get("title"), "\r\n", "\n")
+
+	fontSize, _ := strconv.Atoi(form.Get("titleStyle.fontSize"))
+	button.TitleStyle = types.TitleStyle{
+		FontSize:  fontSize,
+		Color:     form.Get("titleStyle.color"),
+		Alignment: form.Get("titleStyle.alignment"),
+		Hidden:    form.Get("titleStyle.hidden") != "",
+	}
+
+	states := make([]types.State, 0, len(button.States))
+	for i, state := range button.States {
+		if id := form.Get("states." + strconv.Itoa(i)); id != "" && id != state.ImageID {
+			if !store.ImageExists(id) {
+				return button, fmt.Errorf("image is not in the library: %s", id)
+			}
+			state.ImageID = id
+			state.ImagePath = ""
+		}
+		states = append(states, state)
+	}
+
+	op := form.Get("op")
+	applyStepOp(&button.Settings, op)
+
+	// Give toggles and outcomes their second state.
+	if definition, ok := actions.Get(button.UUID); ok && len(states) > 0 {
+		count := definition.States
+		if button.Settings.Output == "state" {
+			count = 2
+		}
+		for len(states) < count {
+			states = append(states, types.State{ImageID: states[0].ImageID, ImagePath: states[0].ImagePath})
+		}
+	}
+
+	switch {
+	case op == "add-state" && len(states) > 0:
+		states = append(states, types.State{ImageID: states[0].ImageID, ImagePath: states[0].ImagePath})
+	case strings.HasPrefix(op, "remove-state."):
+		i, err := strconv.Atoi(strings.TrimPrefix(op, "remove-state."))
+		if err == nil && i >= 0 && i < len(states) && len(states) > 1 {
+			states = append(states[:i], states[i+1:]...)
+		}
+	}
+
+	// State IDs are their index, renumber after adding or removing.
+	for i := range states {
+		states[i].ID = strconv.Itoa(i)
+	}
+	button.States = states
+
+	if button.CurrentState().ID != button.State {
+		button.State = "0"
+	}
+
+	return button, nil
+}
+
+// applyStepOp adds, removes or moves up a multi-action step for an
+// "add-step.<key>", "remove-step.<key>.<i>" or "move-step.<key>.<i>" op.
+func applyStepOp(settings *types.Settings, op string) {
+	name, rest, _ := strings.Cut(op, ".")
+	if name != "add-step" && name != "remove-step" && name != "move-step" {
+		return
+	}
+
+	key, index, _ := strings.Cut(rest, ".")
+	steps := actions.Steps(settings, key)
+	if steps == nil {
+		return
+	}
+
+	if name == "add-step" {
+		*steps = append(*steps, types.Step{UUID: actions.None})
+		return
+	}
+
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(*steps) {
+		return
+	}
+
+	switch name {
+	case "remove-step":
+		*steps = append((*steps)[:i], (*steps)[i+1:]...)
+	case "move-step":
+		if i > 0 {
+			(*steps)[i-1], (*steps)[i] = (*steps)[i], (*steps)[i-1]
+		}
+	}
+}
+
+func HandleButtonInspector() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := loadButtonContext(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		button, err := store.GetButton(c.key(c.buttonID))
+		if err != nil {
+			http.Error(w, "Button not found", http.StatusNotFound)
+			return
+		}
+
+		// Changing the action re-renders the inspector with the unsaved form values.
+		if r.URL.Query().Has("uuid") {
+			button, err = buttonFromForm(button, r.URL.Query())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		renderInspector(w, r, c, &button)
+	}
+}
+
+func HandleButtonUpdate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := loadButtonContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	button, err := store.GetButton(c.key(c.buttonID))
+	if err != nil {
+		http.Error(w, "Button not found", http.StatusNotFound)
+		return
+	}
+
+	button, err = buttonFromForm(button, r.PostForm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := store.UpdateButton(c.instance.ID, c.device, c.profile.ID, c.page.ID, &button)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update button")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderInspector(w, r, c, updated, updated.ID)
+}
+
+func HandleButtonImageUpload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageUploadSize)
+
+	if err := r.ParseMultipartForm(maxImageUploadSize); err != nil {
+		http.Error(w, "Image is too large", http.StatusBadRequest)
+		return
+	}
+
+	c, err := loadButtonContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	button, err := store.GetButton(c.key(c.buttonID))
+	if err != nil {
+		http.Error(w, "Button not found", http.StatusNotFound)
+		return
+	}
+
+	state, err := strconv.Atoi(r.FormValue("state"))
+	if err != nil || state < 0 || state >= len(button.States) {
+		http.Error(w, "Invalid state", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		http.Error(w, "Image is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read image", http.StatusBadRequest)
+		return
+	}
+
+	id, err := store.PutImage(header.Filename, data)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to store image")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	button.States[state].ImageID = id
+	button.States[state].ImagePath = ""
+
+	updated, err := store.UpdateButton(c.instance.ID, c.device, c.profile.ID, c.page.ID, &button)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update button")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderInspector(w, r, c, updated, updated.ID)
+}
+
+func HandleButtonClear(w http.ResponseWriter, r *http.Request) {
+	c, err := loadButtonContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	button, err := store.ClearButton(c.instance.ID, c.device, c.profile.ID, c.page.ID, c.buttonID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to clear button")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderInspector(w, r, c, button, button.ID)
+}
+
+func HandleButtonCopy(w http.ResponseWriter, r *http.Request) {
+	c, err := loadButtonContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := store.CopyButton(c.instance.ID, c.device, c.profile.ID, c.page.ID, c.buttonID); err != nil {
+		log.Error().Err(err).Msg("Failed to copy button")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	button, err := store.GetButton(c.key(c.buttonID))
+	if err != nil {
+		http.Error(w, "Button not found", http.StatusNotFound)
+		return
+	}
+
+	renderInspector(w, r, c, &button)
+}
+
+func HandleButtonCut(w http.ResponseWriter, r *http.Request) {
+	c, err := loadButtonContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	button, err := store.CutButton(c.instance.ID, c.device, c.profile.ID, c.page.ID, c.buttonID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to cut button")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderInspector(w, r, c, button, button.ID)
+}
+
+func HandleButtonPaste(w http.ResponseWriter, r *http.Request) {
+	c, err := loadButtonContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	button, err := store.PasteButton(c.instance.ID, c.device, c.profile.ID, c.page.ID, c.buttonID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to paste button")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderInspector(w, r, c, button, button.ID)
+}
+
+func HandleButtonSwap(w http.ResponseWriter, r *http.Request) {
+	c, err := loadButtonContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	otherButtonID := r.FormValue("otherButtonId")
+	if otherButtonID == "" || otherButtonID == c.buttonID {
+		http.Error(w, "A different key to swap with is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := store.SwapButtons(c.instance.ID, c.device, c.profile.ID, c.page.ID, c.buttonID, otherButtonID); err != nil {
+		log.Error().Err(err).Msg("Failed to swap buttons")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	button, err := store.GetButton(c.key(c.buttonID))
+	if err != nil {
+		http.Error(w, "Button not found", http.StatusNotFound)
+		return
+	}
+
+	renderInspector(w, r, c, &button, c.buttonID, otherButtonID)
+}
+
+// HandleImage serves an image from the library.
+func HandleImage(w http.ResponseWriter, r *http.Request) {
+	data, err := store.GetImage(chi.URLParam(r, "imageId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Images are addressed by content hash so they never change.
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Write(data)
+}
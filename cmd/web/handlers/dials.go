@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sd/cmd/web/views/partials"
+	"sd/pkg/actions"
+	"sd/pkg/models"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// dialContext holds the entities a dial request refers to.
+type dialContext struct {
+	instance types.Instance
+	device   *types.Device
+	profile  *types.Profile
+	dial     int
+}
+
+func loadDialContext(r *http.Request) (*dialContext, error) {
+	instanceID := r.FormValue("instanceId")
+	deviceID := r.FormValue("deviceId")
+	profileID := r.FormValue("profileId")
+
+	if instanceID == "" || deviceID == "" || profileID == "" {
+		return nil, fmt.Errorf("instanceId, deviceId, profileId and dial are required")
+	}
+
+	device := store.GetDevice(instanceID, deviceID)
+	if device == nil {
+		return nil, fmt.Errorf("device not found")
+	}
+
+	model, _ := models.ByType(device.Type)
+	dial, err := strconv.Atoi(r.FormValue("dial"))
+	if err != nil || dial < 1 || dial > model.Dials {
+		return nil, fmt.Errorf("invalid dial")
+	}
+
+	profile := store.GetProfile(instanceID, device, profileID)
+	if profile == nil {
+		return nil, fmt.Errorf("profile not found")
+	}
+
+	return &dialContext{
+		instance: store.GetInstance(instanceID),
+		device:   device,
+		profile:  profile,
+		dial:     dial,
+	}, nil
+}
+
+func (c *dialContext) key() string {
+	return store.DialKey(c.instance.ID, c.device.ID, c.profile.ID, c.dial)
+}
+
+func renderDialInspector(w http.ResponseWriter, r *http.Request, c *dialContext, dial *types.Button) {
+	if err := partials.DialInspector(c.instance, c.device, c.profile, c.dial, dial).Render(r.Context(), w); err != nil {
+		log.Error().Err(err).Msg("Failed to render dial inspector")
+	}
+}
+
+// dialFromForm applies the dial inspector form values to a dial.
+func dialFromForm(dial types.Button, form url.Values) (types.Button, error) {
+	dial.UUID = form.Get("uuid")
+	if dial.UUID == "" {
+		dial.UUID = actions.None
+	}
+
+	dial.Settings = types.Settings{}
+	if definition, ok := actions.Get(dial.UUID); ok {
+		if !definition.Dial {
+			return dial, fmt.Errorf("%s cannot be bound to a dial", definition.Name)
+		}
+		settings, err := actions.ParseSettings(definition, form)
+		if err != nil {
+			return dial, err
+		}
+		dial.Settings = settings
+	}
+
+	dial.Title = form.Get("title")
+
+	return dial, nil
+}
+
+func HandleDialInspector() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := loadDialContext(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		dial, err := store.GetDial(c.key())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Changing the action re-renders the inspector with the unsaved form values.
+		if r.URL.Query().Has("uuid") {
+			dial, err = dialFromForm(dial, r.URL.Query())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		renderDialInspector(w, r, c, &dial)
+	}
+}
+
+func HandleDialUpdate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := loadDialContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dial, err := store.GetDial(c.key())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	dial, err = dialFromForm(dial, r.PostForm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := store.UpdateDial(c.key(), dial); err != nil {
+		log.Error().Err(err).Msg("Failed to update dial")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderDialInspector(w, r, c, &dial)
+}
+
+func HandleDialClear(w http.ResponseWriter, r *http.Request) {
+	c, err := loadDialContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := store.DeleteDial(c.key()); err != nil {
+		log.Error().Err(err).Msg("Failed to clear dial")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderDialInspector(w, r, c, &types.Button{})
+}
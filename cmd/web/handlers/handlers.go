@@ -5,8 +5,13 @@ import (
 	"fmt"
 	"net/http"
 	"sd/cmd/web/views/partials"
+	"sd/pkg/actions"
+	"sd/pkg/multiaction"
 	"sd/pkg/natsconn"
+	"sd/pkg/navigation"
 	"sd/pkg/store"
+	"sd/pkg/types"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
@@ -48,7 +53,9 @@ func HandleButtonPress(w http.ResponseWriter, r *http.Request) {
 	pageID := chi.URLParam(r, "pageId")
 	buttonID := chi.URLParam(r, "buttonId")
 
-	var button, err = store.GetButton("instances." + instanceID + ".devices." + deviceID + ".profiles." + profileID + ".pages." + pageID + ".buttons." + buttonID)
+	key := "instances." + instanceID + ".devices." + deviceID + ".profiles." + profileID + ".pages." + pageID + ".buttons." + buttonID
+
+	var button, err = store.GetButton(key)
 
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get button")
@@ -61,7 +68,46 @@ func HandleButtonPress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	nc.Publish(button.ID, buttonData)
+	if button.UUID == "" || button.UUID == "none" {
+		return
+	}
+
+	// Drivers run navigation and device actions themselves, nothing
+	// subscribes to them.
+	if navigation.IsNavigation(button.UUID) {
+		device := store.GetDevice(instanceID, deviceID)
+		if device == nil {
+			http.Error(w, "Device not found", http.StatusNotFound)
+			return
+		}
+		if err := navigation.Handle(instanceID, device, button); err != nil {
+			log.Error().Err(err).Msg("Failed to navigate")
+		}
+		return
+	}
+
+	if multiaction.IsMultiAction(button.UUID) {
+		device := store.GetDevice(instanceID, deviceID)
+		if device == nil {
+			http.Error(w, "Device not found", http.StatusNotFound)
+			return
+		}
+		location := navigation.Location{ProfileID: profileID, PageID: pageID}
+		if err := multiaction.Handle(instanceID, device, location, button); err != nil {
+			log.Error().Err(err).Msg("Failed to run multi action")
+		}
+		return
+	}
+
+	if err := actions.Publish(nc, button.UUID, key, buttonData); err != nil {
+		log.Error().Err(err).Msg("Failed to publish button press")
+		return
+	}
+
+	// There is no key to let go of in the browser, release right away.
+	if err := actions.PublishRelease(nc, key, button); err != nil {
+		log.Error().Err(err).Msg("Failed to publish button release")
+	}
 }
 
 func HandleDeviceCardList(w http.ResponseWriter, r *http.Request) {
@@ -127,6 +173,152 @@ func HandleProfileDeleteDialog() http.HandlerFunc {
 	}
 }
 
+func HandleProfileCloneDialog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		instanceID := r.URL.Query().Get("instanceId")
+		deviceID := r.URL.Query().Get("deviceId")
+		profileID := r.URL.Query().Get("profileId")
+
+		instance := store.GetInstance(instanceID)
+		device := store.GetDevice(instanceID, deviceID)
+		profile := store.GetProfile(instanceID, device, profileID)
+
+		instances := store.GetInstances()
+		devices := make(map[string][]types.Device)
+		for _, i := range instances {
+			devices[i.ID] = store.GetDevices(i.ID)
+		}
+
+		component := partials.ProfileCloneDialog(instance, device, profile, instances, devices)
+		component.Render(r.Context(), w)
+	}
+}
+
+func HandleFocusRulesDialog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		instanceID := r.URL.Query().Get("instanceId")
+		deviceID := r.URL.Query().Get("deviceId")
+		profileID := r.URL.Query().Get("profileId")
+
+		instance := store.GetInstance(instanceID)
+		device := store.GetDevice(instanceID, deviceID)
+		if device == nil {
+			http.Error(w, "Device not found", http.StatusNotFound)
+			return
+		}
+		profile := store.GetProfile(instanceID, device, profileID)
+		if profile == nil {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+
+		rules, err := store.GetFocusRules(instanceID, deviceID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get focus rules")
+		}
+
+		component := partials.FocusRulesDialog(instance, device, profile, rules)
+		component.Render(r.Context(), w)
+	}
+}
+
+func HandleFocusRuleCreate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	instanceID := r.FormValue("instanceId")
+	deviceID := r.FormValue("deviceId")
+	profileID := r.FormValue("profileId")
+
+	rule := types.FocusRule{
+		Class:     r.FormValue("class"),
+		Title:     r.FormValue("title"),
+		ProfileID: profileID,
+	}
+
+	if _, err := store.AddFocusRule(instanceID, deviceID, rule); err != nil {
+		log.Error().Err(err).Msg("Failed to add focus rule")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	renderFocusRuleList(w, r, instanceID, deviceID, profileID)
+}
+
+func HandleFocusRuleDelete(w http.ResponseWriter, r *http.Request) {
+	instanceID := r.URL.Query().Get("instanceId")
+	deviceID := r.URL.Query().Get("deviceId")
+	profileID := r.URL.Query().Get("profileId")
+	ruleID := r.URL.Query().Get("ruleId")
+
+	if err := store.DeleteFocusRule(instanceID, deviceID, ruleID); err != nil {
+		log.Error().Err(err).Msg("Failed to delete focus rule")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderFocusRuleList(w, r, instanceID, deviceID, profileID)
+}
+
+func renderFocusRuleList(w http.ResponseWriter, r *http.Request, instanceID string, deviceID string, profileID string) {
+	instance := store.GetInstance(instanceID)
+	device := store.GetDevice(instanceID, deviceID)
+	if device == nil {
+		http.Error(w, "Device not found", http.StatusNotFound)
+		return
+	}
+	profile := store.GetProfile(instanceID, device, profileID)
+	if profile == nil {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+
+	rules, err := store.GetFocusRules(instanceID, deviceID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	partials.FocusRuleList(instance, device, profile, rules).Render(r.Context(), w)
+}
+
+func HandleCommandAllowlistDialog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		instanceID := r.URL.Query().Get("instanceId")
+		instance := store.GetInstance(instanceID)
+
+		patterns, err := store.GetCommandAllowlist(instanceID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get command allowlist")
+		}
+
+		partials.CommandAllowlistDialog(instance, patterns, "").Render(r.Context(), w)
+	}
+}
+
+func HandleCommandAllowlistUpdate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	instanceID := r.FormValue("instanceId")
+	instance := store.GetInstance(instanceID)
+	patterns := strings.Split(strings.ReplaceAll(r.FormValue("patterns"), "\r\n", "\n"), "\n")
+
+	message := "Saved."
+	if err := store.UpdateCommandAllowlist(instanceID, patterns); err != nil {
+		log.Error().Err(err).Msg("Failed to update command allowlist")
+		message = err.Error()
+	} else if patterns, err = store.GetCommandAllowlist(instanceID); err != nil {
+		message = err.Error()
+	}
+
+	partials.CommandAllowlistDialog(instance, patterns, message).Render(r.Context(), w)
+}
+
 func HandleProfileDelete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		instanceID := r.URL.Query().Get("instanceId")
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sd/pkg/elgato"
+	"sd/pkg/store"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const maxProfileArchiveSize = 100 << 20
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// HandleProfileExport downloads a profile as an archive.
+func HandleProfileExport(w http.ResponseWriter, r *http.Request) {
+	instanceID := r.URL.Query().Get("instanceId")
+	deviceID := r.URL.Query().Get("deviceId")
+	profileID := r.URL.Query().Get("profileId")
+
+	device := store.GetDevice(instanceID, deviceID)
+	if device == nil {
+		http.Error(w, "Device not found", http.StatusNotFound)
+		return
+	}
+
+	profile := store.GetProfile(instanceID, device, profileID)
+	if profile == nil {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+
+	// Build the archive first so errors can still be reported.
+	var buf bytes.Buffer
+	if err := store.ExportProfile(instanceID, device, profileID, &buf); err != nil {
+		log.Error().Err(err).Msg("Failed to export profile")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filename := unsafeFilenameChars.ReplaceAllString(profile.Name, "_") + ".sdprofile"
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(buf.Bytes())
+}
+
+// HandleProfileImport creates a profile from an uploaded archive and opens it.
+// Elgato profile exports are recognised by their file extension.
+func HandleProfileImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxProfileArchiveSize)
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Profile archive is too large", http.StatusBadRequest)
+		return
+	}
+
+	instanceID := r.FormValue("instanceId")
+	deviceID := r.FormValue("deviceId")
+
+	device := store.GetDevice(instanceID, deviceID)
+	if device == nil {
+		http.Error(w, "Device not found", http.StatusNotFound)
+		return
+	}
+
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		http.Error(w, "Profile archive is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read profile archive", http.StatusBadRequest)
+		return
+	}
+
+	if elgato.IsArchive(header.Filename) {
+		profiles, err := elgato.Import(instanceID, device, bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to import Elgato profile")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		profile := profiles[0]
+		w.Header().Add("Hx-Redirect", "/instance/"+instanceID+"/device/"+deviceID+"/profile/"+profile.ID+"/page/"+profile.CurrentPage)
+		return
+	}
+
+	profile, err := store.ImportProfile(instanceID, device, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to import profile")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Hx-Redirect", "/instance/"+instanceID+"/device/"+deviceID+"/profile/"+profile.ID+"/page/"+profile.CurrentPage)
+}
+
+// HandleProfileClone copies a profile to a device, possibly on another
+// instance, and opens the copy.
+func HandleProfileClone(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	instanceID := r.FormValue("instanceId")
+	deviceID := r.FormValue("deviceId")
+	profileID := r.FormValue("profileId")
+	strategy := store.RemapStrategy(r.FormValue("strategy"))
+
+	targetInstanceID, targetDeviceID, ok := strings.Cut(r.FormValue("target"), "/")
+	if !ok {
+		http.Error(w, "Target device is required", http.StatusBadRequest)
+		return
+	}
+
+	device := store.GetDevice(instanceID, deviceID)
+	if device == nil {
+		http.Error(w, "Device not found", http.StatusNotFound)
+		return
+	}
+
+	target := store.GetDevice(targetInstanceID, targetDeviceID)
+	if target == nil {
+		http.Error(w, "Target device not found", http.StatusNotFound)
+		return
+	}
+
+	profile, err := store.CloneProfile(instanceID, device, profileID, targetInstanceID, target, strategy)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to clone profile")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Hx-Redirect", "/instance/"+targetInstanceID+"/device/"+targetDeviceID+"/profile/"+profile.ID+"/page/"+profile.CurrentPage)
+}
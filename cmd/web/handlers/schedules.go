@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"sd/cmd/web/views/partials"
+	"sd/pkg/actions"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// scheduleSteps describes the steps of a schedule so that they are parsed
+// like the steps of a multi-action.
+var scheduleSteps = actions.Definition{Fields: []actions.Field{{Key: "steps", Type: "steps"}}}
+
+// newSchedule returns the schedule shown in an empty editor.
+func newSchedule() types.Schedule {
+	return types.Schedule{Enabled: true, Trigger: types.TriggerCron}
+}
+
+// scheduleFromForm returns the schedule edited in a form, with step ops
+// applied. Only the setting of the chosen trigger is kept.
+func scheduleFromForm(form url.Values) (types.Schedule, error) {
+	settings, err := actions.ParseSettings(scheduleSteps, form)
+	if err != nil {
+		return types.Schedule{}, err
+	}
+	applyStepOp(&settings, form.Get("op"))
+
+	schedule := types.Schedule{
+		ID:      form.Get("scheduleId"),
+		Name:    strings.TrimSpace(form.Get("name")),
+		Enabled: form.Get("enabled") != "",
+		Trigger: form.Get("trigger"),
+		Steps:   settings.Steps,
+	}
+
+	switch schedule.Trigger {
+	case types.TriggerCron:
+		schedule.Cron = strings.TrimSpace(form.Get("cron"))
+	case types.TriggerProfile:
+		schedule.Profile = form.Get("profile")
+	case types.TriggerSubject:
+		schedule.Subject = strings.TrimSpace(form.Get("subject"))
+	}
+
+	return schedule, nil
+}
+
+func HandleSchedulesDialog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		instanceID := r.URL.Query().Get("instanceId")
+		deviceID := r.URL.Query().Get("deviceId")
+
+		instance := store.GetInstance(instanceID)
+		device := store.GetDevice(instanceID, deviceID)
+		if device == nil {
+			http.Error(w, "Device not found", http.StatusNotFound)
+			return
+		}
+
+		schedules, err := store.GetSchedules(instanceID, deviceID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get schedules")
+		}
+
+		profiles := store.GetProfiles(instanceID, device)
+
+		partials.SchedulesDialog(instance, device, profiles, schedules, newSchedule(), "").Render(r.Context(), w)
+	}
+}
+
+// HandleSchedulesPanel renders the schedules of a device with a stored
+// schedule in the editor, or, when the trigger or the action of a step
+// changes, with the unsaved form values.
+func HandleSchedulesPanel() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		schedule := newSchedule()
+		message := ""
+		if query.Has("trigger") {
+			var err error
+			if schedule, err = scheduleFromForm(query); err != nil {
+				message = err.Error()
+			}
+		} else if scheduleID := query.Get("scheduleId"); scheduleID != "" {
+			schedules, err := store.GetSchedules(query.Get("instanceId"), query.Get("deviceId"))
+			if err != nil {
+				message = err.Error()
+			}
+			for _, s := range schedules {
+				if s.ID == scheduleID {
+					schedule = s
+				}
+			}
+		}
+
+		renderSchedulesPanel(w, r, query.Get("instanceId"), query.Get("deviceId"), schedule, message)
+	}
+}
+
+// HandleScheduleUpdate saves the schedule of the editor, or applies a step
+// op to it without saving.
+func HandleScheduleUpdate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	instanceID := r.FormValue("instanceId")
+	deviceID := r.FormValue("deviceId")
+
+	schedule, err := scheduleFromForm(r.PostForm)
+	if err != nil {
+		renderSchedulesPanel(w, r, instanceID, deviceID, schedule, err.Error())
+		return
+	}
+
+	message := ""
+	if r.PostForm.Get("op") == "save" {
+		saved, err := store.SaveSchedule(instanceID, deviceID, schedule)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to save schedule")
+			message = err.Error()
+		} else {
+			schedule, message = *saved, "Saved."
+		}
+	}
+
+	renderSchedulesPanel(w, r, instanceID, deviceID, schedule, message)
+}
+
+func HandleScheduleDelete(w http.ResponseWriter, r *http.Request) {
+	instanceID := r.URL.Query().Get("instanceId")
+	deviceID := r.URL.Query().Get("deviceId")
+	scheduleID := r.URL.Query().Get("scheduleId")
+
+	if err := store.DeleteSchedule(instanceID, deviceID, scheduleID); err != nil {
+		log.Error().Err(err).Msg("Failed to delete schedule")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderSchedulesPanel(w, r, instanceID, deviceID, newSchedule(), "")
+}
+
+func renderSchedulesPanel(w http.ResponseWriter, r *http.Request, instanceID string, deviceID string, schedule types.Schedule, message string) {
+	instance := store.GetInstance(instanceID)
+	device := store.GetDevice(instanceID, deviceID)
+	if device == nil {
+		http.Error(w, "Device not found", http.StatusNotFound)
+		return
+	}
+
+	schedules, err := store.GetSchedules(instanceID, deviceID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	profiles := store.GetProfiles(instanceID, device)
+
+	partials.SchedulesPanel(instance, device, profiles, schedules, schedule, message).Render(r.Context(), w)
+}
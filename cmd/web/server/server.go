@@ -22,35 +22,6 @@ type Server struct {
 	router *chi.Mux
 }
 
-// Add these constants for device types
-const (
-	DeviceTypeXL    = "xl"
-	DeviceTypePlus  = "plus"
-	DeviceTypePedal = "pedal"
-
-	// USB IDs
-	VendorIDElgato = 0x0fd9
-	ProductIDXL    = 0x006c // Stream Deck XL
-	ProductIDPlus  = 0x0084 // Stream Deck +
-	ProductIDPedal = 0x0086 // Stream Deck Pedal
-)
-
-// DeviceInfo represents the device data stored in NATS KV
-
-// DetermineDeviceType returns the device type based on USB product ID
-func DetermineDeviceType(productID uint16) string {
-	switch productID {
-	case ProductIDXL:
-		return DeviceTypeXL
-	case ProductIDPlus:
-		return DeviceTypePlus
-	case ProductIDPedal:
-		return DeviceTypePedal
-	default:
-		return "unknown"
-	}
-}
-
 func NewServer() *Server {
 	r := chi.NewRouter()
 
@@ -145,7 +116,14 @@ func (s *Server) setupRoutes() {
 		w.Write([]byte(""))
 	})
 	s.router.Get("/partials/profile/delete-dialog", handlers.HandleProfileDeleteDialog())
+	s.router.Get("/partials/profile/clone-dialog", handlers.HandleProfileCloneDialog())
+	s.router.Get("/partials/profile/focus-rules", handlers.HandleFocusRulesDialog())
+	s.router.Get("/partials/device/schedules", handlers.HandleSchedulesDialog())
+	s.router.Get("/partials/device/schedules/panel", handlers.HandleSchedulesPanel())
+	s.router.Get("/partials/instance/command-allowlist", handlers.HandleCommandAllowlistDialog())
 	s.router.Get("/partials/page/delete-dialog", handlers.HandlePageDeleteDialog())
+	s.router.Get("/partials/button/inspector", handlers.HandleButtonInspector())
+	s.router.Get("/partials/dial/inspector", handlers.HandleDialInspector())
 
 	// Add SSE endpoint for device updates
 	s.router.Get("/stream/instance/{instanceId}/devices", func(w http.ResponseWriter, r *http.Request) {
@@ -274,10 +252,32 @@ func (s *Server) setupRoutes() {
 	// REST API routes.
 
 	s.router.Post("/api/profile/create", handlers.HandleProfileCreate)
+	s.router.Post("/api/profile/import", handlers.HandleProfileImport)
+	s.router.Post("/api/profile/clone", handlers.HandleProfileClone)
+	s.router.Get("/api/profile/export", handlers.HandleProfileExport)
 	s.router.Delete("/api/profile/delete", handlers.HandleProfileDelete())
 
+	s.router.Post("/api/focus-rule", handlers.HandleFocusRuleCreate)
+	s.router.Delete("/api/focus-rule", handlers.HandleFocusRuleDelete)
+	s.router.Post("/api/schedule", handlers.HandleScheduleUpdate)
+	s.router.Delete("/api/schedule", handlers.HandleScheduleDelete)
+	s.router.Post("/api/command-allowlist", handlers.HandleCommandAllowlistUpdate)
+
 	s.router.Post("/api/page/create", handlers.HandlePageCreate)
 	s.router.Delete("/api/page", handlers.HandlePageDelete())
+
+	s.router.Post("/api/button/update", handlers.HandleButtonUpdate)
+	s.router.Post("/api/button/image", handlers.HandleButtonImageUpload)
+	s.router.Post("/api/button/clear", handlers.HandleButtonClear)
+	s.router.Post("/api/button/copy", handlers.HandleButtonCopy)
+	s.router.Post("/api/button/cut", handlers.HandleButtonCut)
+	s.router.Post("/api/button/paste", handlers.HandleButtonPaste)
+	s.router.Post("/api/button/swap", handlers.HandleButtonSwap)
+
+	s.router.Post("/api/dial/update", handlers.HandleDialUpdate)
+	s.router.Post("/api/dial/clear", handlers.HandleDialClear)
+
+	s.router.Get("/api/image/{imageId}", handlers.HandleImage)
 }
 
 func (s *Server) sendDeviceList(w http.ResponseWriter, ctx context.Context, instance types.Instance, devices []types.Device) error {
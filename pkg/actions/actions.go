@@ -0,0 +1,677 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sd/pkg/types"
+	"strconv"
+	"strings"
+)
+
+// None is the UUID of a button without an action.
+const None = "none"
+
+// Field describes a single setting edited in the button inspector.
+type Field struct {
+	Key     string // JSON key inside types.Settings
+	Label   string
+	Type    string   // text, textarea, number, checkbox, select or steps
+	Options []string // values of a select
+}
+
+// Definition describes an action a button can be bound to.
+type Definition struct {
+	UUID   string // NATS subject the button publishes to
+	Plugin string
+	Name   string
+	Fields []Field
+
+	// States is the number of button states the action switches between.
+	States int
+
+	// Release actions also receive the button on ReleaseSubject(UUID) when
+	// the key is let go.
+	Release bool
+
+	// Dial actions are bound to the dials of a Stream Deck + rather than to
+	// keys, and receive rotations and presses on DialSubject(UUID).
+	Dial bool
+}
+
+var definitions = []Definition{
+	{
+		UUID:   "sd.plugin.browser.open_url",
+		Plugin: "browser",
+		Name:   "Open URL",
+		Fields: []Field{
+			{Key: "url", Label: "URL ({date}, {time}, {clipboard} and {selection} are replaced)", Type: "text"},
+			{Key: "browser", Label: "Firefox or Chromium based browser (empty for the default browser)", Type: "text"},
+			{Key: "browserProfile", Label: "Profile", Type: "text"},
+			{Key: "private", Label: "Private window", Type: "checkbox"},
+			{Key: "newWindow", Label: "New window", Type: "checkbox"},
+			{Key: "window", Label: "Focus existing window matching title", Type: "text"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.keyboard.type",
+		Plugin: "keyboard",
+		Name:   "Type Text",
+		Fields: []Field{{Key: "text", Label: "Text", Type: "textarea"}},
+	},
+	{
+		UUID:   "sd.plugin.keyboard.hotkey",
+		Plugin: "keyboard",
+		Name:   "Hotkey",
+		Fields: []Field{{Key: "keys", Label: "Keys (e.g. ctrl+shift+t)", Type: "text"}},
+	},
+	{
+		UUID:   "sd.plugin.keyboard.sequence",
+		Plugin: "keyboard",
+		Name:   "Key Sequence",
+		Fields: []Field{{Key: "sequence", Label: "Keys or delays (e.g. 250ms), one per line", Type: "textarea"}},
+	},
+	{
+		UUID:    "sd.plugin.keyboard.hold",
+		Plugin:  "keyboard",
+		Name:    "Hold Keys",
+		Fields:  []Field{{Key: "keys", Label: "Keys held while the key is down", Type: "text"}},
+		Release: true,
+	},
+	{
+		UUID:   "sd.plugin.keyboard.media",
+		Plugin: "keyboard",
+		Name:   "Media Key",
+		Fields: []Field{{
+			Key:     "keys",
+			Label:   "Media key",
+			Type:    "select",
+			Options: []string{"play", "pause", "stop", "next", "previous", "mute", "volume_up", "volume_down"},
+		}},
+	},
+	{
+		UUID:   "sd.plugin.clipboard.copy",
+		Plugin: "clipboard",
+		Name:   "Copy Snippet",
+		Fields: []Field{{Key: "text", Label: "Snippet ({date}, {time}, {clipboard}, {previous} and {env:SD_BUTTON_NAME} are replaced)", Type: "textarea"}},
+	},
+	{
+		UUID:   "sd.plugin.clipboard.paste",
+		Plugin: "clipboard",
+		Name:   "Paste Snippet",
+		Fields: []Field{
+			{Key: "text", Label: "Snippet ({date}, {time}, {clipboard}, {previous} and {env:SD_BUTTON_NAME} are replaced)", Type: "textarea"},
+			{Key: "keys", Label: "Paste keys (default ctrl+v)", Type: "text"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.clipboard.type",
+		Plugin: "clipboard",
+		Name:   "Type Template",
+		Fields: []Field{{Key: "text", Label: "Text ({date}, {time}, {clipboard}, {previous} and {env:SD_BUTTON_NAME} are replaced)", Type: "textarea"}},
+	},
+	{
+		UUID:   "sd.plugin.clipboard.history",
+		Plugin: "clipboard",
+		Name:   "Clipboard History",
+		Fields: []Field{
+			{Key: "slot", Label: "Entry (1 for the latest)", Type: "number"},
+			{Key: "keys", Label: "Paste keys (empty to only copy)", Type: "text"},
+			{Key: "preview", Label: "Show the entry on the key (saved with the key image, leave off if you copy passwords)", Type: "checkbox"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.window.focus",
+		Plugin: "window",
+		Name:   "Focus Window",
+		Fields: []Field{
+			{Key: "class", Label: "Window class (regular expression)", Type: "text"},
+			{Key: "window", Label: "Window title (regular expression)", Type: "text"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.window.raise",
+		Plugin: "window",
+		Name:   "Raise Window",
+		Fields: []Field{
+			{Key: "class", Label: "Window class (regular expression)", Type: "text"},
+			{Key: "window", Label: "Window title (regular expression)", Type: "text"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.window.minimize",
+		Plugin: "window",
+		Name:   "Minimize Window",
+		Fields: []Field{
+			{Key: "class", Label: "Window class (regular expression)", Type: "text"},
+			{Key: "window", Label: "Window title (regular expression)", Type: "text"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.window.maximize",
+		Plugin: "window",
+		Name:   "Maximize Window",
+		Fields: []Field{
+			{Key: "class", Label: "Window class (regular expression)", Type: "text"},
+			{Key: "window", Label: "Window title (regular expression)", Type: "text"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.window.monitor",
+		Plugin: "window",
+		Name:   "Move Window to Monitor",
+		Fields: []Field{
+			{Key: "class", Label: "Window class (regular expression)", Type: "text"},
+			{Key: "window", Label: "Window title (regular expression)", Type: "text"},
+			{Key: "monitor", Label: "Monitor (1, 2..., next or previous, default next)", Type: "text"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.window.close",
+		Plugin: "window",
+		Name:   "Close Window",
+		Fields: []Field{
+			{Key: "class", Label: "Window class (regular expression)", Type: "text"},
+			{Key: "window", Label: "Window title (regular expression)", Type: "text"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.window.launch",
+		Plugin: "window",
+		Name:   "Launch or Focus",
+		Fields: []Field{
+			{Key: "class", Label: "Window class (regular expression)", Type: "text"},
+			{Key: "window", Label: "Window title (regular expression)", Type: "text"},
+			{Key: "command", Label: "Command run when no window matches (must match the command allowlist when one is set)", Type: "text"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.command.exec",
+		Plugin: "command",
+		Name:   "Execute Command",
+		Fields: []Field{
+			{Key: "command", Label: "Command (must match the command allowlist when one is set)", Type: "text"},
+			{Key: "workDir", Label: "Working directory", Type: "text"},
+			{Key: "env", Label: "Environment (KEY=value per line)", Type: "textarea"},
+			{Key: "timeout", Label: "Timeout (seconds)", Type: "number"},
+			{Key: "terminal", Label: "Run in terminal", Type: "checkbox"},
+			{Key: "output", Label: "Show outcome", Type: "select", Options: []string{"none", "title", "state"}},
+		},
+	},
+	{
+		UUID:   "sd.plugin.command.toggle",
+		Plugin: "command",
+		Name:   "Toggle Process",
+		Fields: []Field{
+			{Key: "command", Label: "Command (must match the command allowlist when one is set)", Type: "text"},
+			{Key: "workDir", Label: "Working directory", Type: "text"},
+			{Key: "env", Label: "Environment (KEY=value per line)", Type: "textarea"},
+			{Key: "terminal", Label: "Run in terminal", Type: "checkbox"},
+		},
+		States: 2,
+	},
+	{
+		UUID:   "sd.plugin.audio.volume_up",
+		Plugin: "audio",
+		Name:   "Volume Up",
+		Fields: []Field{
+			{Key: "audioTarget", Label: "Target", Type: "select", Options: []string{"sink", "source", "app"}},
+			{Key: "audioDevice", Label: "Device or application (empty for the default)", Type: "text"},
+			{Key: "volumeStep", Label: "Step (%)", Type: "number"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.audio.volume_down",
+		Plugin: "audio",
+		Name:   "Volume Down",
+		Fields: []Field{
+			{Key: "audioTarget", Label: "Target", Type: "select", Options: []string{"sink", "source", "app"}},
+			{Key: "audioDevice", Label: "Device or application (empty for the default)", Type: "text"},
+			{Key: "volumeStep", Label: "Step (%)", Type: "number"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.audio.volume_set",
+		Plugin: "audio",
+		Name:   "Set Volume",
+		Fields: []Field{
+			{Key: "audioTarget", Label: "Target", Type: "select", Options: []string{"sink", "source", "app"}},
+			{Key: "audioDevice", Label: "Device or application (empty for the default)", Type: "text"},
+			{Key: "volume", Label: "Volume (%)", Type: "number"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.audio.mute",
+		Plugin: "audio",
+		Name:   "Toggle Mute",
+		Fields: []Field{
+			{Key: "audioTarget", Label: "Target", Type: "select", Options: []string{"sink", "source", "app"}},
+			{Key: "audioDevice", Label: "Device or application (empty for the default)", Type: "text"},
+		},
+		States: 2,
+	},
+	{
+		UUID:   "sd.plugin.audio.default",
+		Plugin: "audio",
+		Name:   "Set Default Device",
+		Fields: []Field{
+			{Key: "audioTarget", Label: "Target", Type: "select", Options: []string{"sink", "source"}},
+			{Key: "audioDevice", Label: "Device", Type: "text"},
+		},
+		States: 2,
+	},
+	{
+		UUID:   "sd.plugin.audio.dial",
+		Plugin: "audio",
+		Name:   "Volume",
+		Fields: []Field{
+			{Key: "audioTarget", Label: "Target", Type: "select", Options: []string{"sink", "source", "app"}},
+			{Key: "audioDevice", Label: "Device or application (empty for the default)", Type: "text"},
+			{Key: "volumeStep", Label: "Step per tick (%)", Type: "number"},
+		},
+		Dial: true,
+	},
+	{
+		UUID:   "sd.plugin.media.play_pause",
+		Plugin: "media",
+		Name:   "Play / Pause",
+		Fields: []Field{{Key: "player", Label: "Player (such as spotify, empty for the active player)", Type: "text"}},
+		States: 2,
+	},
+	{
+		UUID:   "sd.plugin.media.next",
+		Plugin: "media",
+		Name:   "Next Track",
+		Fields: []Field{{Key: "player", Label: "Player (such as spotify, empty for the active player)", Type: "text"}},
+	},
+	{
+		UUID:   "sd.plugin.media.previous",
+		Plugin: "media",
+		Name:   "Previous Track",
+		Fields: []Field{{Key: "player", Label: "Player (such as spotify, empty for the active player)", Type: "text"}},
+	},
+	{
+		UUID:   "sd.plugin.media.seek",
+		Plugin: "media",
+		Name:   "Seek",
+		Fields: []Field{
+			{Key: "player", Label: "Player (such as spotify, empty for the active player)", Type: "text"},
+			{Key: "offset", Label: "Offset (seconds, negative to seek back)", Type: "number"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.media.now_playing",
+		Plugin: "media",
+		Name:   "Now Playing",
+		Fields: []Field{{Key: "player", Label: "Player (such as spotify, empty for the active player)", Type: "text"}},
+	},
+	{
+		UUID:   "sd.plugin.media.dial",
+		Plugin: "media",
+		Name:   "Media",
+		Fields: []Field{
+			{Key: "player", Label: "Player (such as spotify, empty for the active player)", Type: "text"},
+			{Key: "offset", Label: "Seek per tick (seconds)", Type: "number"},
+		},
+		Dial: true,
+	},
+	{
+		UUID:   "sd.plugin.sysmon.monitor",
+		Plugin: "sysmon",
+		Name:   "System Monitor",
+		Fields: []Field{
+			{Key: "metric", Label: "Metric", Type: "select", Options: []string{"cpu", "memory", "disk", "network", "temperature", "load"}},
+			{Key: "target", Label: "Mount point, interface or sensor (empty for /, all interfaces or the CPU)", Type: "text"},
+			{Key: "style", Label: "Style", Type: "select", Options: []string{"sparkline", "gauge"}},
+			{Key: "interval", Label: "Interval (seconds)", Type: "number"},
+		},
+	},
+	{
+		UUID:    "sd.plugin.timer.stopwatch",
+		Plugin:  "timer",
+		Name:    "Stopwatch",
+		Release: true,
+	},
+	{
+		UUID:   "sd.plugin.timer.countdown",
+		Plugin: "timer",
+		Name:   "Countdown",
+		Fields: []Field{
+			{Key: "duration", Label: "Duration (such as 5m or 1h30m)", Type: "text"},
+			{Key: "steps", Label: "On completion", Type: "steps"},
+		},
+		Release: true,
+	},
+	{
+		UUID:   "sd.plugin.timer.pomodoro",
+		Plugin: "timer",
+		Name:   "Pomodoro",
+		Fields: []Field{
+			{Key: "duration", Label: "Work (default 25m)", Type: "text"},
+			{Key: "break", Label: "Break (default 5m)", Type: "text"},
+			{Key: "longBreak", Label: "Long break after four work phases (default 15m)", Type: "text"},
+		},
+		Release: true,
+	},
+	{
+		UUID:   "sd.plugin.obs.scene",
+		Plugin: "obs",
+		Name:   "Switch Scene",
+		Fields: []Field{{Key: "scene", Label: "Scene", Type: "text"}},
+	},
+	{
+		UUID:   "sd.plugin.obs.source",
+		Plugin: "obs",
+		Name:   "Toggle Source",
+		Fields: []Field{
+			{Key: "scene", Label: "Scene (empty for the current scene)", Type: "text"},
+			{Key: "source", Label: "Source", Type: "text"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.obs.stream",
+		Plugin: "obs",
+		Name:   "Toggle Streaming",
+	},
+	{
+		UUID:   "sd.plugin.obs.record",
+		Plugin: "obs",
+		Name:   "Toggle Recording",
+	},
+	{
+		UUID:   "sd.plugin.obs.mute",
+		Plugin: "obs",
+		Name:   "Toggle Mute",
+		Fields: []Field{{Key: "source", Label: "Audio source", Type: "text"}},
+	},
+	{
+		UUID:   "sd.plugin.homeassistant.toggle",
+		Plugin: "homeassistant",
+		Name:   "Toggle Entity",
+		Fields: []Field{{Key: "entity", Label: "Entity (such as light.kitchen)", Type: "text"}},
+		States: 2,
+	},
+	{
+		UUID:   "sd.plugin.homeassistant.service",
+		Plugin: "homeassistant",
+		Name:   "Call Service",
+		Fields: []Field{
+			{Key: "service", Label: "Service (such as light.turn_on)", Type: "text"},
+			{Key: "entity", Label: "Entity (optional)", Type: "text"},
+			{Key: "serviceData", Label: "Service data (JSON)", Type: "textarea"},
+		},
+	},
+	{
+		UUID:   "sd.plugin.homeassistant.state",
+		Plugin: "homeassistant",
+		Name:   "Show Entity State",
+		Fields: []Field{{Key: "entity", Label: "Entity (such as sensor.temperature)", Type: "text"}},
+	},
+	{
+		UUID:   "sd.plugin.webhook.request",
+		Plugin: "webhook",
+		Name:   "HTTP Request",
+		Fields: []Field{
+			{Key: "method", Label: "Method", Type: "select", Options: []string{"GET", "POST", "PUT", "PATCH", "DELETE"}},
+			{Key: "url", Label: "URL ({date}, {time}, {clipboard} and {selection} are replaced)", Type: "text"},
+			{Key: "headers", Label: "Headers (Name: value per line, ${SD_BUTTON_VAR} reads the environment)", Type: "textarea"},
+			{Key: "body", Label: "Body ({date}, {time}, {clipboard} and {selection} are replaced)", Type: "textarea"},
+			{Key: "auth", Label: "Authentication", Type: "select", Options: []string{"none", "basic", "bearer"}},
+			{Key: "credentials", Label: "Credentials (user:password or token, ${SD_BUTTON_VAR} reads the environment)", Type: "text"},
+			{Key: "jsonPath", Label: "Response value to show (such as data.0.status)", Type: "text"},
+			{Key: "timeout", Label: "Timeout (seconds)", Type: "number"},
+			{Key: "retries", Label: "Retries", Type: "number"},
+			{Key: "retryUnsafe", Label: "Retry POST, PUT and PATCH after server errors (the endpoint must be safe to call twice)", Type: "checkbox"},
+			{Key: "output", Label: "Show outcome", Type: "select", Options: []string{"none", "title", "state"}},
+		},
+	},
+	{
+		UUID:   "sd.navigation.page.next",
+		Plugin: "navigation",
+		Name:   "Next Page",
+	},
+	{
+		UUID:   "sd.navigation.page.previous",
+		Plugin: "navigation",
+		Name:   "Previous Page",
+	},
+	{
+		UUID:   "sd.navigation.page.goto",
+		Plugin: "navigation",
+		Name:   "Go to Page",
+		Fields: []Field{{Key: "page", Label: "Page", Type: "number"}},
+	},
+	{
+		UUID:   "sd.navigation.profile",
+		Plugin: "navigation",
+		Name:   "Switch Profile",
+		Fields: []Field{{Key: "profile", Label: "Profile name", Type: "text"}},
+	},
+	{
+		UUID:   "sd.device.brightness",
+		Plugin: "device",
+		Name:   "Set Brightness",
+		Fields: []Field{{Key: "brightness", Label: "Brightness (1-100 percent)", Type: "number"}},
+	},
+	{
+		UUID:   "sd.plugin.i3.workspace",
+		Plugin: "i3",
+		Name:   "Switch Workspace",
+		Fields: []Field{{Key: "workspace", Label: "Workspace", Type: "text"}},
+	},
+	{
+		UUID:   "sd.plugin.i3.move_to_workspace",
+		Plugin: "i3",
+		Name:   "Move Window to Workspace",
+		Fields: []Field{{Key: "workspace", Label: "Workspace", Type: "text"}},
+	},
+	{
+		UUID:   "sd.plugin.i3.command",
+		Plugin: "i3",
+		Name:   "Run i3 Command",
+		Fields: []Field{{Key: "command", Label: "Command (exec is not allowed)", Type: "text"}},
+	},
+	{
+		UUID:   "sd.multiaction.run",
+		Plugin: "multiaction",
+		Name:   "Multi Action",
+		Fields: []Field{{Key: "steps", Label: "Steps", Type: "steps"}},
+	},
+	{
+		UUID:   "sd.multiaction.toggle",
+		Plugin: "multiaction",
+		Name:   "Toggle Multi Action",
+		Fields: []Field{
+			{Key: "steps", Label: "Steps (state 0)", Type: "steps"},
+			{Key: "toggleSteps", Label: "Steps (state 1)", Type: "steps"},
+		},
+		States: 2,
+	},
+}
+
+// All returns every known action definition.
+func All() []Definition {
+	return definitions
+}
+
+// KeyActions returns the definitions a key can be bound to.
+func KeyActions() []Definition {
+	var keys []Definition
+	for _, d := range definitions {
+		if !d.Dial {
+			keys = append(keys, d)
+		}
+	}
+	return keys
+}
+
+// DialActions returns the definitions a dial can be bound to.
+func DialActions() []Definition {
+	var dials []Definition
+	for _, d := range definitions {
+		if d.Dial {
+			dials = append(dials, d)
+		}
+	}
+	return dials
+}
+
+// Get returns the definition for an action UUID.
+func Get(uuid string) (Definition, bool) {
+	for _, d := range definitions {
+		if d.UUID == uuid {
+			return d, true
+		}
+	}
+	return Definition{}, false
+}
+
+// ReleaseSubject returns the subject a release action receives key
+// releases on.
+func ReleaseSubject(uuid string) string {
+	return uuid + ".release"
+}
+
+// DialSubject returns the subject a dial action receives rotations and
+// presses on.
+func DialSubject(uuid string) string {
+	return uuid + ".dial"
+}
+
+// StepActions returns the definitions a multi-action step can run.
+func StepActions() []Definition {
+	var steps []Definition
+	for _, d := range definitions {
+		if d.Plugin != "multiaction" && !d.Release && !d.Dial {
+			steps = append(steps, d)
+		}
+	}
+	return steps
+}
+
+// Steps returns the step list of settings stored under key.
+func Steps(settings *types.Settings, key string) *[]types.Step {
+	switch key {
+	case "steps":
+		return &settings.Steps
+	case "toggleSteps":
+		return &settings.ToggleSteps
+	}
+	return nil
+}
+
+// SettingValue returns a setting as a string suitable for a form input.
+func SettingValue(settings types.Settings, key string) string {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return ""
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return ""
+	}
+
+	switch v := m[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool, float64:
+		return fmt.Sprint(v)
+	default:
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+}
+
+// ParseSettings builds settings for a definition from "settings.<key>" form values.
+func ParseSettings(definition Definition, form url.Values) (types.Settings, error) {
+	m := make(map[string]any)
+
+	for _, field := range definition.Fields {
+		value := form.Get("settings." + field.Key)
+
+		switch field.Type {
+		case "number":
+			if value == "" {
+				continue
+			}
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return types.Settings{}, fmt.Errorf("%s must be a number", field.Label)
+			}
+			m[field.Key] = n
+		case "checkbox":
+			m[field.Key] = value != ""
+		case "steps":
+			steps, err := parseSteps(form, "settings."+field.Key+".")
+			if err != nil {
+				return types.Settings{}, err
+			}
+			m[field.Key] = steps
+		default:
+			m[field.Key] = value
+		}
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return types.Settings{}, err
+	}
+
+	var settings types.Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return types.Settings{}, fmt.Errorf("invalid settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+// parseSteps reads "<prefix><i>.uuid", "<prefix><i>.delay",
+// "<prefix><i>.stopOnError" and "<prefix><i>.settings.<key>" form values
+// for consecutive step indexes.
+func parseSteps(form url.Values, prefix string) ([]types.Step, error) {
+	steps := []types.Step{}
+
+	for i := 0; ; i++ {
+		stepPrefix := prefix + strconv.Itoa(i) + "."
+		if !form.Has(stepPrefix + "uuid") {
+			return steps, nil
+		}
+
+		step := types.Step{
+			UUID:        form.Get(stepPrefix + "uuid"),
+			StopOnError: form.Get(stepPrefix+"stopOnError") != "",
+		}
+
+		if delay := form.Get(stepPrefix + "delay"); delay != "" {
+			ms, err := strconv.Atoi(delay)
+			if err != nil || ms < 0 {
+				return nil, fmt.Errorf("delay of step %d must be a positive number of milliseconds", i+1)
+			}
+			step.Delay = ms
+		}
+
+		if definition, ok := Get(step.UUID); ok {
+			if definition.Plugin == "multiaction" {
+				return nil, fmt.Errorf("step %d cannot be a multi action", i+1)
+			}
+
+			sub := url.Values{}
+			for key, values := range form {
+				if rest, ok := strings.CutPrefix(key, stepPrefix+"settings."); ok {
+					sub["settings."+rest] = values
+				}
+			}
+
+			settings, err := ParseSettings(definition, sub)
+			if err != nil {
+				return nil, fmt.Errorf("step %d: %w", i+1, err)
+			}
+			step.Settings = settings
+		}
+
+		steps = append(steps, step)
+	}
+}
@@ -0,0 +1,176 @@
+package actions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sd/pkg/env"
+	"sd/pkg/types"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Headers of action messages.
+const (
+	ButtonKeyHeader = "Sd-Button-Key" // KV key of the pressed button or turned dial
+	StepHeader      = "Sd-Step"       // multi-action step, such as steps.2
+	DialHeader      = "Sd-Dial"       // dial input, see DialInput
+	TimeHeader      = "Sd-Time"
+	SignatureHeader = "Sd-Signature"
+)
+
+// SecretEnv names the shared secret action messages are signed with. Plugins
+// running sensitive actions reject unsigned messages while it is set.
+const SecretEnv = "SD_ACTION_SECRET"
+
+// maxAge bounds how old a signed message may be, to limit replays.
+const maxAge = 30 * time.Second
+
+// NewMsg returns a signed message with the data of a pressed button for
+// subject. step is empty unless the message runs a multi-action step.
+func NewMsg(subject string, buttonKey string, step string, data []byte) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if buttonKey != "" {
+		msg.Header.Set(ButtonKeyHeader, buttonKey)
+	}
+	if step != "" {
+		msg.Header.Set(StepHeader, step)
+	}
+
+	sign(msg)
+
+	return msg
+}
+
+// sign adds a signature to msg when SD_ACTION_SECRET is set.
+func sign(msg *nats.Msg) {
+	if secret := env.Get(SecretEnv, ""); secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		msg.Header.Set(TimeHeader, timestamp)
+		msg.Header.Set(SignatureHeader, signature(secret, msg, timestamp))
+	}
+}
+
+func signature(secret string, msg *nats.Msg, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for _, part := range []string{msg.Subject, msg.Header.Get(ButtonKeyHeader), msg.Header.Get(StepHeader), msg.Header.Get(DialHeader), timestamp} {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	mac.Write(msg.Data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a message when SD_ACTION_SECRET is set.
+func Verify(m *nats.Msg) error {
+	secret := env.Get(SecretEnv, "")
+	if secret == "" {
+		return nil
+	}
+
+	timestamp := m.Header.Get(TimeHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("message is not signed")
+	}
+
+	if age := time.Since(time.Unix(seconds, 0)); age > maxAge || age < -maxAge {
+		return fmt.Errorf("signature expired")
+	}
+
+	expected := signature(secret, m, timestamp)
+	if !hmac.Equal([]byte(expected), []byte(m.Header.Get(SignatureHeader))) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+// Publish sends the data of a pressed button to subject.
+func Publish(nc *nats.Conn, subject string, buttonKey string, data []byte) error {
+	return nc.PublishMsg(NewMsg(subject, buttonKey, "", data))
+}
+
+// ButtonKey returns the key of the button a message was sent for, or "" when
+// it did not come from a key.
+func ButtonKey(m *nats.Msg) string {
+	return m.Header.Get(ButtonKeyHeader)
+}
+
+// OwnKey returns the key of the button a message was sent for when the
+// action is bound to that button itself. It returns "" for the steps of
+// multi-actions and schedules, whose key belongs to the button or schedule
+// running them, so that steps never draw on it.
+func OwnKey(m *nats.Msg) string {
+	if m.Header.Get(StepHeader) != "" {
+		return ""
+	}
+	return ButtonKey(m)
+}
+
+// ForInstance reports whether a message was sent for a key or dial of an
+// instance. Messages without a key, such as those of other tools, are for
+// every instance.
+func ForInstance(m *nats.Msg, instanceID string) bool {
+	key := ButtonKey(m)
+	return key == "" || strings.HasPrefix(key, "instances."+instanceID+".")
+}
+
+// PublishRelease tells a release action that the key of button was let go.
+// Other actions ignore releases.
+func PublishRelease(nc *nats.Conn, buttonKey string, button types.Button) error {
+	definition, ok := Get(button.UUID)
+	if !ok || !definition.Release {
+		return nil
+	}
+
+	data, err := json.Marshal(button)
+	if err != nil {
+		return err
+	}
+
+	return Publish(nc, ReleaseSubject(button.UUID), buttonKey, data)
+}
+
+// Dial inputs a dial action receives.
+const (
+	DialRotate  = "rotate"
+	DialPress   = "press"
+	DialRelease = "release"
+)
+
+// PublishDial tells the action of a dial that it was rotated by ticks,
+// negative to the left, or pressed or released.
+func PublishDial(nc *nats.Conn, dialKey string, dial types.Button, input string, ticks int) error {
+	data, err := json.Marshal(dial)
+	if err != nil {
+		return err
+	}
+
+	value := input
+	if input == DialRotate {
+		value = fmt.Sprintf("%s:%d", input, ticks)
+	}
+
+	msg := nats.NewMsg(DialSubject(dial.UUID))
+	msg.Data = data
+	msg.Header.Set(ButtonKeyHeader, dialKey)
+	msg.Header.Set(DialHeader, value)
+	sign(msg)
+
+	return nc.PublishMsg(msg)
+}
+
+// DialInput returns the input of a dial message and, for rotations, the
+// number of ticks.
+func DialInput(m *nats.Msg) (string, int) {
+	input, value, _ := strings.Cut(m.Header.Get(DialHeader), ":")
+	ticks, _ := strconv.Atoi(value)
+	return input, ticks
+}
@@ -0,0 +1,46 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// Result is the answer of a plugin to an action sent as a request, which
+// multi-actions do to know whether a step succeeded.
+type Result struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Reply answers a request with the outcome of an action. Key presses are
+// published without a reply subject and get no answer.
+func Reply(m *nats.Msg, err error) {
+	if m.Reply == "" {
+		return
+	}
+
+	var result Result
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	data, _ := json.Marshal(result)
+	if err := m.Respond(data); err != nil {
+		log.Error().Err(err).Str("action", m.Subject).Msg("Failed to reply to action")
+	}
+}
+
+// ResultError returns the error carried by a reply.
+func ResultError(data []byte) error {
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("invalid reply: %w", err)
+	}
+	if result.Error != "" {
+		return errors.New(result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package bindings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sd/pkg/natsconn"
+	"sd/pkg/store"
+	"sd/pkg/types"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// UpdateFunc is called for every change of a button or dial key. put is
+// false when the key was deleted, button is then empty.
+type UpdateFunc func(key string, button types.Button, put bool)
+
+// Buttons returns the pattern of the button keys of an instance.
+func Buttons(instanceID string) string {
+	return fmt.Sprintf("instances.%s.devices.*.profiles.*.pages.*.buttons.*", instanceID)
+}
+
+// Dials returns the pattern of the dial keys of an instance.
+func Dials(instanceID string) string {
+	return fmt.Sprintf("instances.%s.devices.*.profiles.*.dials.*", instanceID)
+}
+
+// Watch follows the keys matching pattern until ctx is done, calling update
+// with every key as it is bound, changed or unbound. Plugins use it to know
+// which keys show their feedback.
+func Watch(ctx context.Context, pattern string, update UpdateFunc) {
+	_, kv := natsconn.GetNATSConn()
+
+	watcher, err := kv.Watch(pattern)
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating watcher")
+		return
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-watcher.Updates():
+			if entry == nil {
+				continue
+			}
+
+			var button types.Button
+			put := entry.Operation() == nats.KeyValuePut
+			if put {
+				if err := json.Unmarshal(entry.Value(), &button); err != nil {
+					continue
+				}
+			}
+			update(entry.Key(), button, put)
+		}
+	}
+}
+
+// Drawn remembers what was last drawn on each key, so that feedback which
+// did not change is not written again. Callers serialise access.
+type Drawn map[string]string
+
+// Feedback draws feedback on a key unless it is already drawn. A nil
+// feedback restores the configured image and title.
+func (d Drawn) Feedback(key string, fb *types.Feedback) error {
+	data, err := json.Marshal(fb)
+	if err != nil {
+		return err
+	}
+
+	current := string(data)
+	if drawn, ok := d[key]; ok && drawn == current {
+		return nil
+	}
+
+	if err := store.SetFeedback(key, fb); err != nil {
+		return err
+	}
+	d[key] = current
+
+	return nil
+}
+
+// State switches a key to one of its states unless it is already shown.
+func (d Drawn) State(key string, state string) error {
+	if drawn, ok := d[key]; ok && drawn == state {
+		return nil
+	}
+
+	if err := store.SetButtonState(key, state); err != nil {
+		return err
+	}
+	d[key] = state
+
+	return nil
+}
+
+// Forget makes the next draw of a key write it again.
+func (d Drawn) Forget(key string) {
+	delete(d, key)
+}
+
+// Clear forgets a key and, unless it was deleted, restores its configured
+// image and title.
+func (d Drawn) Clear(key string, put bool) error {
+	delete(d, key)
+	if !put {
+		return nil
+	}
+	return store.SetFeedback(key, nil)
+}
@@ -0,0 +1,165 @@
+// Package cron parses five-field cron expressions.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expression is a parsed cron expression matching times to the minute.
+type Expression struct {
+	minute, hour, day, month, weekday uint64 // bit sets of allowed values
+
+	// Like cron, when both the day of the month and the day of the week are
+	// restricted a time matches either of them.
+	anyDay, anyWeekday bool
+}
+
+var aliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// field describes the values of a field of an expression.
+type field struct {
+	name     string
+	min, max int
+	names    []string // names of the values from min
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	{name: "day of week", min: 0, max: 7, names: weekdayNames},
+}
+
+// Parse parses an expression of five fields: minute, hour, day of month,
+// month and day of week. Fields are *, values, ranges such as 1-5, steps such
+// as */15 or 8-18/2, or lists of them such as 0,30. Months and days of the
+// week may be named, such as jan or mon, and both 0 and 7 are Sunday. The
+// @hourly, @daily, @weekly, @monthly and @yearly aliases are accepted.
+func Parse(expression string) (Expression, error) {
+	expression = strings.TrimSpace(strings.ToLower(expression))
+	if alias, ok := aliases[expression]; ok {
+		expression = alias
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != len(fields) {
+		return Expression{}, fmt.Errorf("cron expression must have 5 fields, got %d", len(parts))
+	}
+
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return Expression{}, err
+		}
+		sets[i] = set
+	}
+
+	// Sunday is both 0 and 7.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return Expression{
+		minute:     sets[0],
+		hour:       sets[1],
+		day:        sets[2],
+		month:      sets[3],
+		weekday:    sets[4],
+		anyDay:     parts[2] == "*",
+		anyWeekday: parts[4] == "*",
+	}, nil
+}
+
+func parseValue(s string, f field) (int, error) {
+	for i, name := range f.names {
+		if s == name {
+			return f.min + i, nil
+		}
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("invalid %s %q, must be between %d and %d", f.name, s, f.min, f.max)
+	}
+	return n, nil
+}
+
+func parseField(s string, f field) (uint64, error) {
+	var set uint64
+
+	for _, item := range strings.Split(s, ",") {
+		spec, stepText, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid %s step %q", f.name, stepText)
+			}
+			step = n
+		}
+
+		low, high := f.min, f.max
+		switch from, to, isRange := strings.Cut(spec, "-"); {
+		case spec == "*":
+		case isRange:
+			var err error
+			if low, err = parseValue(from, f); err != nil {
+				return 0, err
+			}
+			if high, err = parseValue(to, f); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid %s range %q", f.name, spec)
+			}
+		default:
+			var err error
+			if low, err = parseValue(spec, f); err != nil {
+				return 0, err
+			}
+			// A value with a step, such as 5/15, runs to the end of the range.
+			if !hasStep {
+				high = low
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+
+	return set, nil
+}
+
+// Match reports whether t, to the minute, matches the expression.
+func (e Expression) Match(t time.Time) bool {
+	if e.minute&(1<<t.Minute()) == 0 || e.hour&(1<<t.Hour()) == 0 || e.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+
+	day := e.day&(1<<t.Day()) != 0
+	weekday := e.weekday&(1<<int(t.Weekday())) != 0
+	if e.anyDay || e.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
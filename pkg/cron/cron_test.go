@@ -0,0 +1,111 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expression string
+		time       string // Monday 2024-01-01 is the first day of the week
+		want       bool
+	}{
+		{"* * * * *", "2024-01-01 00:00", true},
+
+		// Values and lists.
+		{"30 9 * * *", "2024-01-01 09:30", true},
+		{"30 9 * * *", "2024-01-01 09:31", false},
+		{"0,30 * * * *", "2024-01-01 14:30", true},
+		{"0,30 * * * *", "2024-01-01 14:15", false},
+
+		// Ranges.
+		{"0 9-17 * * *", "2024-01-01 09:00", true},
+		{"0 9-17 * * *", "2024-01-01 17:00", true},
+		{"0 9-17 * * *", "2024-01-01 18:00", false},
+		{"0 0 * * 1-5", "2024-01-05 00:00", true},
+		{"0 0 * * 1-5", "2024-01-06 00:00", false},
+
+		// Steps.
+		{"*/15 * * * *", "2024-01-01 10:45", true},
+		{"*/15 * * * *", "2024-01-01 10:50", false},
+		{"0 8-18/2 * * *", "2024-01-01 14:00", true},
+		{"0 8-18/2 * * *", "2024-01-01 15:00", false},
+		{"5/20 * * * *", "2024-01-01 10:45", true},
+		{"5/20 * * * *", "2024-01-01 10:00", false},
+
+		// Names.
+		{"0 0 1 jan *", "2024-01-01 00:00", true},
+		{"0 0 1 jan *", "2024-02-01 00:00", false},
+		{"0 0 * * mon-fri", "2024-01-03 00:00", true},
+		{"0 0 * * mon-fri", "2024-01-07 00:00", false},
+		{"0 0 * * SAT,SUN", "2024-01-06 00:00", true},
+
+		// Sunday is both 0 and 7.
+		{"0 0 * * 0", "2024-01-07 00:00", true},
+		{"0 0 * * 7", "2024-01-07 00:00", true},
+		{"0 0 * * 7", "2024-01-06 00:00", false},
+		{"0 0 * * 5-7", "2024-01-07 00:00", true},
+
+		// With both the day of the month and the day of the week restricted,
+		// either matches.
+		{"0 0 13 * fri", "2024-01-13 00:00", true},
+		{"0 0 13 * fri", "2024-01-05 00:00", true},
+		{"0 0 13 * fri", "2024-01-06 00:00", false},
+		// With only one restricted, it must match.
+		{"0 0 13 * *", "2024-01-05 00:00", false},
+		{"0 0 * * fri", "2024-01-13 00:00", false},
+
+		// Aliases.
+		{"@hourly", "2024-01-01 10:00", true},
+		{"@hourly", "2024-01-01 10:01", false},
+		{"@daily", "2024-01-01 00:00", true},
+		{"@weekly", "2024-01-07 00:00", true},
+		{"@weekly", "2024-01-01 00:00", false},
+		{"@monthly", "2024-02-01 00:00", true},
+		{"@yearly", "2024-01-01 00:00", true},
+		{"@yearly", "2024-02-01 00:00", false},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.expression)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expression, err)
+			continue
+		}
+
+		at, err := time.Parse("2006-01-02 15:04", tt.time)
+		if err != nil {
+			t.Fatalf("bad test time %q: %v", tt.time, err)
+		}
+		if got := e.Match(at); got != tt.want {
+			t.Errorf("%q at %s (%s) = %v, want %v", tt.expression, tt.time, at.Weekday(), got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* * * foo *",
+		"17-9 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"1-x * * * *",
+		"@reboot",
+	}
+
+	for _, expression := range tests {
+		if _, err := Parse(expression); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expression)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package elgato
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	maxFileSize      = 10 << 20
+	maxArchiveSize   = 200 << 20
+	maxArchiveDepth  = 3
+	profileDirSuffix = ".sdprofile"
+	profileSuffix    = ".streamdeckprofile"
+)
+
+// profileFiles holds the files of one .sdProfile directory, keyed by their
+// path relative to that directory.
+type profileFiles struct {
+	name  string
+	files map[string][]byte
+}
+
+// rootManifest is the manifest.json at the root of a .sdProfile directory.
+type rootManifest struct {
+	Name  string `json:"Name"`
+	Pages struct {
+		Current string   `json:"Current"`
+		Pages   []string `json:"Pages"`
+	} `json:"Pages"`
+}
+
+// pageManifest is a manifest.json holding the keys of a page. Version 1
+// profiles list actions directly, version 2 groups them by controller.
+type pageManifest struct {
+	Actions     map[string]action `json:"Actions"`
+	Controllers []struct {
+		Type    string            `json:"Type"`
+		Actions map[string]action `json:"Actions"`
+	} `json:"Controllers"`
+}
+
+// keypadActions returns the key actions of a page, keyed by "column,row".
+func (m pageManifest) keypadActions() map[string]action {
+	if m.Actions != nil {
+		return m.Actions
+	}
+
+	for _, controller := range m.Controllers {
+		if controller.Type == "" || controller.Type == "Keypad" {
+			return controller.Actions
+		}
+	}
+
+	return nil
+}
+
+type action struct {
+	UUID     string                     `json:"UUID"`
+	Name     string                     `json:"Name"`
+	Settings map[string]json.RawMessage `json:"Settings"`
+	State    int                        `json:"State"`
+	States   []actionState              `json:"States"`
+	Actions  []struct {
+		Actions []action `json:"Actions"`
+	} `json:"Actions"`
+}
+
+type actionState struct {
+	Image          string       `json:"Image"`
+	Title          string       `json:"Title"`
+	ShowTitle      *bool        `json:"ShowTitle"`
+	TitleAlignment string       `json:"TitleAlignment"`
+	TitleColor     string       `json:"TitleColor"`
+	FontSize       flexibleSize `json:"FSize"`
+}
+
+// flexibleSize decodes a font size written either as a number or a string.
+type flexibleSize int
+
+func (s *flexibleSize) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(bytes.Trim(data, `"`), &n); err != nil {
+		return nil
+	}
+
+	value, err := n.Float64()
+	if err != nil {
+		return nil
+	}
+
+	*s = flexibleSize(value)
+	return nil
+}
+
+// setting returns a string setting of an action.
+func (a action) setting(key string) string {
+	var value string
+	if err := json.Unmarshal(a.Settings[key], &value); err != nil {
+		return ""
+	}
+	return value
+}
+
+// readArchive returns the profiles of a .streamDeckProfile or
+// .streamDeckProfilesBackup archive. Backups may hold profiles as
+// directories or as nested .streamDeckProfile archives.
+func readArchive(r io.ReaderAt, size int64, depth int, total *int64) ([]profileFiles, error) {
+	if depth > maxArchiveDepth {
+		return nil, fmt.Errorf("profile archives are nested too deeply")
+	}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not a Stream Deck profile archive: %w", err)
+	}
+
+	var profiles []profileFiles
+	byRoot := make(map[string]*profileFiles)
+
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		*total += int64(f.UncompressedSize64)
+		if *total > maxArchiveSize {
+			return nil, fmt.Errorf("profile archive is too large")
+		}
+
+		if strings.HasSuffix(strings.ToLower(f.Name), profileSuffix) {
+			data, err := readFile(f)
+			if err != nil {
+				return nil, err
+			}
+
+			nested, err := readArchive(bytes.NewReader(data), int64(len(data)), depth+1, total)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			profiles = append(profiles, nested...)
+			continue
+		}
+
+		root, rel, ok := splitProfilePath(f.Name)
+		if !ok {
+			continue
+		}
+
+		data, err := readFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		profile, ok := byRoot[root]
+		if !ok {
+			profile = &profileFiles{name: root, files: make(map[string][]byte)}
+			byRoot[root] = profile
+		}
+		profile.files[rel] = data
+	}
+
+	roots := make([]string, 0, len(byRoot))
+	for root := range byRoot {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+
+	for _, root := range roots {
+		profiles = append(profiles, *byRoot[root])
+	}
+
+	return profiles, nil
+}
+
+// splitProfilePath splits a file name at its top-most .sdProfile directory.
+func splitProfilePath(name string) (string, string, bool) {
+	segments := strings.Split(path.Clean(name), "/")
+	for i, segment := range segments[:len(segments)-1] {
+		if strings.HasSuffix(strings.ToLower(segment), profileDirSuffix) {
+			return strings.Join(segments[:i+1], "/"), strings.Join(segments[i+1:], "/"), true
+		}
+	}
+	return "", "", false
+}
+
+func readFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxFileSize {
+		return nil, fmt.Errorf("%s is too large", f.Name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFileSize {
+		return nil, fmt.Errorf("%s is too large", f.Name)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,467 @@
+package elgato
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sd/pkg/models"
+	"sd/pkg/multiaction"
+	"sd/pkg/navigation"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Elgato action UUIDs that have an equivalent here.
+const (
+	actionWebsite           = "com.elgato.streamdeck.system.website"
+	actionOpen              = "com.elgato.streamdeck.system.open"
+	actionHotkey            = "com.elgato.streamdeck.system.hotkey"
+	actionText              = "com.elgato.streamdeck.system.text"
+	actionMultiAction       = "com.elgato.streamdeck.multiactions.routine"
+	actionMultiActionToggle = "com.elgato.streamdeck.multiactions.routine2"
+	actionDelay             = "com.elgato.streamdeck.multiactions.delay"
+	actionNextPage          = "com.elgato.streamdeck.page.next"
+	actionPreviousPage      = "com.elgato.streamdeck.page.previous"
+	actionOpenChild         = "com.elgato.streamdeck.profile.openchild"
+	actionBackToParent      = "com.elgato.streamdeck.profile.backtoparent"
+)
+
+// IsArchive reports whether a filename looks like an Elgato profile export.
+func IsArchive(filename string) bool {
+	name := strings.ToLower(filename)
+	return strings.HasSuffix(name, profileSuffix) || strings.HasSuffix(name, ".streamdeckprofilesbackup")
+}
+
+// page is a page manifest of an Elgato profile. Folders are imported as
+// extra pages of the profile they belong to.
+type page struct {
+	id       string
+	dir      string
+	manifest pageManifest
+}
+
+func pageID(dir string) string {
+	return strings.TrimSuffix(strings.ToLower(path.Base(dir)), profileDirSuffix)
+}
+
+// pages returns the root manifest and the pages of a profile, in the order
+// of the root manifest followed by folders.
+func (p profileFiles) pages() (rootManifest, []page) {
+	var root rootManifest
+	if data, ok := p.files["manifest.json"]; ok {
+		if err := json.Unmarshal(data, &root); err != nil {
+			log.Warn().Err(err).Str("profile", p.name).Msg("Invalid profile manifest")
+		}
+	}
+
+	var pages []page
+	for name, data := range p.files {
+		if path.Base(name) != "manifest.json" {
+			continue
+		}
+
+		var manifest pageManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			log.Warn().Err(err).Str("file", name).Msg("Skipping invalid page manifest")
+			continue
+		}
+		if manifest.Actions == nil && manifest.Controllers == nil {
+			continue
+		}
+
+		dir := path.Dir(name)
+		if dir == "." {
+			pages = append(pages, page{id: pageID(p.name), manifest: manifest})
+			continue
+		}
+		pages = append(pages, page{id: pageID(dir), dir: dir, manifest: manifest})
+	}
+
+	order := make(map[string]int)
+	for i, id := range root.Pages.Pages {
+		order[strings.ToLower(id)] = i + 1
+	}
+
+	rank := func(pg page) int {
+		if pg.dir == "" {
+			return 0
+		}
+		if n, ok := order[pg.id]; ok {
+			return n
+		}
+		return len(order) + 1
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		if rank(pages[i]) != rank(pages[j]) {
+			return rank(pages[i]) < rank(pages[j])
+		}
+		return pages[i].dir < pages[j].dir
+	})
+
+	return root, pages
+}
+
+// image returns the image of an action state, looking in the locations used
+// by the different versions of the Elgato software.
+func (p profileFiles) image(pg page, coordinates string, index int, state actionState) []byte {
+	if strings.HasPrefix(state.Image, "data:") {
+		if _, encoded, ok := strings.Cut(state.Image, ","); ok {
+			if data, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+				return data
+			}
+		}
+	}
+
+	var candidates []string
+	if state.Image != "" {
+		candidates = append(candidates,
+			path.Join(pg.dir, state.Image),
+			path.Join(pg.dir, coordinates, "CustomImages", state.Image))
+	}
+	candidates = append(candidates, path.Join(pg.dir, coordinates, "CustomImages", fmt.Sprintf("state%d.png", index)))
+
+	for _, candidate := range candidates {
+		if data, ok := p.files[candidate]; ok {
+			return data
+		}
+	}
+
+	return nil
+}
+
+// keyID returns the button ID of a "column,row" key position.
+func keyID(coordinates string, model models.Model) (int, bool) {
+	col, row, ok := strings.Cut(coordinates, ",")
+	if !ok {
+		return 0, false
+	}
+
+	c, errCol := strconv.Atoi(strings.TrimSpace(col))
+	r, errRow := strconv.Atoi(strings.TrimSpace(row))
+	if errCol != nil || errRow != nil || c < 0 || r < 0 || c >= model.Cols || r >= model.Rows {
+		return 0, false
+	}
+
+	return r*model.Cols + c + 1, true
+}
+
+// quote quotes a string for sh.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func websiteURL(a action) string {
+	url := a.setting("path")
+	if url != "" && !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+	return url
+}
+
+// hotkeyCombinations returns the key combinations of a hotkey action.
+func hotkeyCombinations(a action) []string {
+	var hotkeys []hotkey
+	if err := json.Unmarshal(a.Settings["Hotkeys"], &hotkeys); err != nil {
+		return nil
+	}
+
+	var combinations []string
+	for _, h := range hotkeys {
+		if combination, ok := h.combination(); ok {
+			combinations = append(combinations, combination)
+		}
+	}
+
+	return combinations
+}
+
+func pastedText(a action) string {
+	text := a.setting("pastedText")
+
+	var enter bool
+	if err := json.Unmarshal(a.Settings["isSendingEnter"], &enter); err == nil && enter {
+		text += "\n"
+	}
+
+	return text
+}
+
+// steps converts one sequence of an Elgato multi-action. Elgato delays are
+// separate actions and become the delay of the following step.
+func (imp *importer) steps(pg page, a action, sequence int) []types.Step {
+	if sequence >= len(a.Actions) {
+		return nil
+	}
+
+	var steps []types.Step
+	delay := 0
+
+	for _, member := range a.Actions[sequence].Actions {
+		switch member.UUID {
+		case actionDelay:
+			var ms float64
+			if err := json.Unmarshal(member.Settings["delayInMS"], &ms); err == nil && ms > 0 {
+				delay += int(ms)
+			}
+			continue
+		case actionMultiAction, actionMultiActionToggle:
+			log.Warn().Msg("Skipping nested multi-action")
+			continue
+		}
+
+		button := types.Button{UUID: "none"}
+		imp.bind(&button, pg, member)
+		if button.UUID == "none" {
+			continue
+		}
+
+		steps = append(steps, types.Step{UUID: button.UUID, Settings: button.Settings, Delay: delay})
+		delay = 0
+	}
+
+	return steps
+}
+
+// importer converts the pages of one Elgato profile.
+type importer struct {
+	files   profileFiles
+	pages   []page
+	numbers map[string]int // page ID to 1-based page number
+	parents map[string]int // folder page ID to the page number opening it
+}
+
+func newImporter(files profileFiles, pages []page) *importer {
+	imp := &importer{
+		files:   files,
+		pages:   pages,
+		numbers: make(map[string]int),
+		parents: make(map[string]int),
+	}
+
+	for i, pg := range pages {
+		imp.numbers[pg.id] = i + 1
+	}
+
+	for i, pg := range pages {
+		for _, a := range pg.manifest.keypadActions() {
+			if a.UUID == actionOpenChild {
+				imp.parents[strings.ToLower(a.setting("ProfileUUID"))] = i + 1
+			}
+		}
+	}
+
+	return imp
+}
+
+// bind sets the action and settings of a button from an Elgato action.
+// Actions without an equivalent keep their title and image only.
+func (imp *importer) bind(button *types.Button, pg page, a action) {
+	switch a.UUID {
+	case actionWebsite:
+		button.UUID = "sd.plugin.browser.open_url"
+		button.Settings.URL = websiteURL(a)
+	case actionOpen:
+		button.UUID = "sd.plugin.command.exec"
+		button.Settings.Command = "xdg-open " + quote(a.setting("path"))
+	case actionHotkey:
+		switch combinations := hotkeyCombinations(a); len(combinations) {
+		case 0:
+		case 1:
+			button.UUID = "sd.plugin.keyboard.hotkey"
+			button.Settings.Keys = combinations[0]
+		default:
+			button.UUID = "sd.plugin.keyboard.sequence"
+			button.Settings.Sequence = strings.Join(combinations, "\n")
+		}
+	case actionText:
+		button.UUID = "sd.plugin.keyboard.type"
+		button.Settings.Text = pastedText(a)
+	case actionMultiAction:
+		if steps := imp.steps(pg, a, 0); len(steps) > 0 {
+			button.UUID = multiaction.Run
+			button.Settings.Steps = steps
+		}
+	case actionMultiActionToggle:
+		steps, toggleSteps := imp.steps(pg, a, 0), imp.steps(pg, a, 1)
+		if len(steps) > 0 || len(toggleSteps) > 0 {
+			button.UUID = multiaction.Toggle
+			button.Settings.Steps = steps
+			button.Settings.ToggleSteps = toggleSteps
+		}
+	case actionNextPage:
+		button.UUID = navigation.PageNext
+	case actionPreviousPage:
+		button.UUID = navigation.PagePrevious
+	case actionOpenChild:
+		if n, ok := imp.numbers[strings.ToLower(a.setting("ProfileUUID"))]; ok {
+			button.UUID = navigation.PageGoto
+			button.Settings.Page = n
+		}
+	case actionBackToParent:
+		if n, ok := imp.parents[pg.id]; ok {
+			button.UUID = navigation.PageGoto
+			button.Settings.Page = n
+		}
+	}
+
+	if button.UUID == "none" && a.UUID != "" {
+		log.Info().Str("uuid", a.UUID).Str("name", a.Name).Msg("No equivalent for Elgato action, importing title and image only")
+	}
+}
+
+// button converts an Elgato action into a button.
+func (imp *importer) button(id int, pg page, coordinates string, a action) types.Button {
+	button := types.Button{ID: strconv.Itoa(id), UUID: "none", State: "0"}
+
+	imp.bind(&button, pg, a)
+
+	for i, state := range a.States {
+		imageID := store.DefaultImageID()
+		if data := imp.files.image(pg, coordinates, i, state); data != nil {
+			id, err := store.PutImage(path.Base(state.Image), data)
+			if err != nil {
+				log.Warn().Err(err).Str("key", coordinates).Msg("Skipping image that could not be stored")
+			} else {
+				imageID = id
+			}
+		}
+		button.States = append(button.States, types.State{ID: strconv.Itoa(i), ImageID: imageID})
+	}
+
+	if len(button.States) == 0 {
+		button.States = []types.State{{ID: "0", ImageID: store.DefaultImageID()}}
+		return button
+	}
+
+	current := a.State
+	if current < 0 || current >= len(a.States) {
+		current = 0
+	}
+	button.State = strconv.Itoa(current)
+
+	state := a.States[current]
+	button.Title = state.Title
+	button.TitleStyle = types.TitleStyle{
+		FontSize:  int(state.FontSize),
+		Color:     state.TitleColor,
+		Alignment: state.TitleAlignment,
+		Hidden:    state.ShowTitle != nil && !*state.ShowTitle,
+	}
+
+	return button
+}
+
+// importProfile creates a profile from the files of one .sdProfile directory.
+func importProfile(instanceID string, device *types.Device, model models.Model, files profileFiles) (*types.Profile, error) {
+	root, pages := files.pages()
+	if len(pages) == 0 {
+		log.Warn().Str("profile", files.name).Msg("Skipping Elgato profile without pages")
+		return nil, nil
+	}
+
+	name := root.Name
+	if name == "" {
+		name = "Imported"
+	}
+
+	profile, err := store.CreateProfile(instanceID, device, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fail := func(err error) (*types.Profile, error) {
+		if cleanupErr := store.DeleteProfile(instanceID, device, profile.ID); cleanupErr != nil {
+			log.Warn().Err(cleanupErr).Str("profile", profile.ID).Msg("Failed to remove partially imported profile")
+		}
+		return nil, err
+	}
+
+	pageIDs := []string{profile.CurrentPage}
+	for range pages[1:] {
+		page, err := store.CreatePage(instanceID, device, profile.ID)
+		if err != nil {
+			return fail(err)
+		}
+		pageIDs = append(pageIDs, page.ID)
+	}
+
+	imp := newImporter(files, pages)
+
+	for i, pg := range pages {
+		for coordinates, a := range pg.manifest.keypadActions() {
+			id, ok := keyID(coordinates, model)
+			if !ok {
+				log.Warn().Str("key", coordinates).Msg("Skipping key that does not exist on this device")
+				continue
+			}
+
+			button := imp.button(id, pg, coordinates, a)
+			if _, err := store.UpdateButton(instanceID, device, profile.ID, pageIDs[i], &button); err != nil {
+				return fail(fmt.Errorf("failed to import key %s: %w", coordinates, err))
+			}
+		}
+	}
+
+	// CreatePage moves the profile to each new page, start on the current one.
+	profile = store.GetProfile(instanceID, device, profile.ID)
+	if profile == nil {
+		return nil, fmt.Errorf("imported profile not found")
+	}
+
+	profile.CurrentPage = pageIDs[0]
+	if n, ok := imp.numbers[strings.ToLower(root.Pages.Current)]; ok {
+		profile.CurrentPage = pageIDs[n-1]
+	}
+
+	if _, err := store.UpdateProfile(instanceID, device, profile); err != nil {
+		return fail(err)
+	}
+
+	log.Info().Str("profile", profile.ID).Str("name", profile.Name).Int("pages", len(pages)).Msg("Imported Elgato profile")
+
+	return profile, nil
+}
+
+// Import creates a profile on a device for every profile of an Elgato
+// .streamDeckProfile or .streamDeckProfilesBackup archive.
+func Import(instanceID string, device *types.Device, r io.ReaderAt, size int64) ([]*types.Profile, error) {
+	if instanceID == "" || device == nil {
+		return nil, fmt.Errorf("instanceID and device are required")
+	}
+
+	model, ok := models.ByType(device.Type)
+	if !ok {
+		return nil, fmt.Errorf("unsupported device type: %s", device.Type)
+	}
+
+	var total int64
+	archives, err := readArchive(r, size, 0, &total)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []*types.Profile
+	for _, files := range archives {
+		profile, err := importProfile(instanceID, device, model, files)
+		if err != nil {
+			return profiles, err
+		}
+		if profile != nil {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("archive has no Stream Deck profiles")
+	}
+
+	return profiles, nil
+}
@@ -0,0 +1,105 @@
+package elgato
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keysyms maps Windows virtual key codes, as written by the Elgato software,
+// to X keysym names, which the keyboard plugin accepts as key names.
+var keysyms = map[int]string{
+	0x08: "BackSpace",
+	0x09: "Tab",
+	0x0D: "Return",
+	0x13: "Pause",
+	0x14: "Caps_Lock",
+	0x1B: "Escape",
+	0x20: "space",
+	0x21: "Prior",
+	0x22: "Next",
+	0x23: "End",
+	0x24: "Home",
+	0x25: "Left",
+	0x26: "Up",
+	0x27: "Right",
+	0x28: "Down",
+	0x2C: "Print",
+	0x2D: "Insert",
+	0x2E: "Delete",
+	0x6A: "KP_Multiply",
+	0x6B: "KP_Add",
+	0x6D: "KP_Subtract",
+	0x6E: "KP_Decimal",
+	0x6F: "KP_Divide",
+	0x90: "Num_Lock",
+	0x91: "Scroll_Lock",
+	0xAD: "XF86AudioMute",
+	0xAE: "XF86AudioLowerVolume",
+	0xAF: "XF86AudioRaiseVolume",
+	0xB0: "XF86AudioNext",
+	0xB1: "XF86AudioPrev",
+	0xB2: "XF86AudioStop",
+	0xB3: "XF86AudioPlay",
+	0xBA: "semicolon",
+	0xBB: "equal",
+	0xBC: "comma",
+	0xBD: "minus",
+	0xBE: "period",
+	0xBF: "slash",
+	0xC0: "grave",
+	0xDB: "bracketleft",
+	0xDC: "backslash",
+	0xDD: "bracketright",
+	0xDE: "apostrophe",
+}
+
+// keysym returns the X keysym name of a Windows virtual key code.
+func keysym(code int) (string, bool) {
+	switch {
+	case code >= 0x30 && code <= 0x39:
+		return string(rune('0' + code - 0x30)), true
+	case code >= 0x41 && code <= 0x5A:
+		return string(rune('a' + code - 0x41)), true
+	case code >= 0x60 && code <= 0x69:
+		return fmt.Sprintf("KP_%d", code-0x60), true
+	case code >= 0x70 && code <= 0x87:
+		return fmt.Sprintf("F%d", code-0x70+1), true
+	}
+
+	name, ok := keysyms[code]
+	return name, ok
+}
+
+// hotkey is one key combination of an Elgato hotkey action.
+type hotkey struct {
+	KeyCmd    bool `json:"KeyCmd"`
+	KeyCtrl   bool `json:"KeyCtrl"`
+	KeyOption bool `json:"KeyOption"`
+	KeyShift  bool `json:"KeyShift"`
+	VKeyCode  int  `json:"VKeyCode"`
+}
+
+// combination returns the hotkey in keyboard plugin notation, such as
+// ctrl+shift+t.
+func (h hotkey) combination() (string, bool) {
+	key, ok := keysym(h.VKeyCode)
+	if !ok {
+		return "", false
+	}
+
+	var keys []string
+	if h.KeyCtrl {
+		keys = append(keys, "ctrl")
+	}
+	if h.KeyShift {
+		keys = append(keys, "shift")
+	}
+	if h.KeyOption {
+		keys = append(keys, "alt")
+	}
+	if h.KeyCmd {
+		keys = append(keys, "super")
+	}
+
+	return strings.Join(append(keys, key), "+"), true
+}
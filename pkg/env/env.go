@@ -2,6 +2,8 @@ package env
 
 import (
 	"os"
+	"regexp"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
@@ -24,3 +26,28 @@ func Get(key string, defaultValue string) string {
 	}
 	return value
 }
+
+// ButtonPrefix starts the names of the environment variables that button
+// settings may read, such as SD_BUTTON_API_TOKEN. Other variables, such as
+// the credentials of the server, are never exposed to buttons.
+const ButtonPrefix = "SD_BUTTON_"
+
+// Button returns an environment variable that button settings may read, or
+// "" when its name does not start with ButtonPrefix.
+func Button(name string) string {
+	if !strings.HasPrefix(name, ButtonPrefix) {
+		return ""
+	}
+	return os.Getenv(name)
+}
+
+// buttonVariable matches ${SD_BUTTON_NAME} and $SD_BUTTON_NAME.
+var buttonVariable = regexp.MustCompile(`\$\{` + ButtonPrefix + `[A-Za-z0-9_]+\}|\$` + ButtonPrefix + `[A-Za-z0-9_]+`)
+
+// ExpandButton replaces ${SD_BUTTON_NAME} and $SD_BUTTON_NAME in s with the
+// value of the variable. Any other $, such as in a password, is kept as is.
+func ExpandButton(s string) string {
+	return buttonVariable.ReplaceAllStringFunc(s, func(match string) string {
+		return Button(strings.Trim(match, "${}"))
+	})
+}
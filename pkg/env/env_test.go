@@ -0,0 +1,36 @@
+package env
+
+import "testing"
+
+func TestExpandButton(t *testing.T) {
+	t.Setenv("SD_BUTTON_TOKEN", "abc")
+	t.Setenv("SECRET", "server")
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Bearer ${SD_BUTTON_TOKEN}", "Bearer abc"},
+		{"Bearer $SD_BUTTON_TOKEN", "Bearer abc"},
+		{"${SD_BUTTON_TOKEN}x", "abcx"},
+		{"$SD_BUTTON_UNSET", ""},
+
+		// Other variables are never read.
+		{"${SECRET}", "${SECRET}"},
+		{"$SECRET", "$SECRET"},
+
+		// Literal dollars are kept.
+		{"pa$$word", "pa$$word"},
+		{"$2y$10$abcdefghijklmnopqrstuv", "$2y$10$abcdefghijklmnopqrstuv"},
+		{"cost: $5", "cost: $5"},
+		{"trailing $", "trailing $"},
+		{"${}", "${}"},
+		{"${SD_BUTTON_TOKEN", "${SD_BUTTON_TOKEN"},
+	}
+
+	for _, tt := range tests {
+		if got := ExpandButton(tt.in); got != tt.want {
+			t.Errorf("ExpandButton(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
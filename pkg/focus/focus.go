@@ -0,0 +1,99 @@
+package focus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sd/pkg/i3"
+	"sd/pkg/natsconn"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const retryDelay = 30 * time.Second
+
+// Window is the focused application window.
+type Window struct {
+	Class string `json:"class"`
+	Title string `json:"title"`
+}
+
+// Subject returns the NATS subject the focused window of an instance is
+// published to.
+func Subject(instanceID string) string {
+	return fmt.Sprintf("instances.%s.focus", instanceID)
+}
+
+// Watch publishes the focused window to the focus subject of an instance
+// until ctx is done. i3 and sway are watched through their IPC socket, other
+// window managers through _NET_ACTIVE_WINDOW.
+func Watch(ctx context.Context, instanceID string) {
+	nc, _ := natsconn.GetNATSConn()
+
+	var last Window
+	publish := func(window Window) {
+		if window == last {
+			return
+		}
+		last = window
+
+		data, err := json.Marshal(window)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal focused window")
+			return
+		}
+
+		if err := nc.Publish(Subject(instanceID), data); err != nil {
+			log.Error().Err(err).Msg("Failed to publish focused window")
+		}
+	}
+
+	for {
+		err := watchI3(ctx, publish)
+		if err != nil {
+			log.Debug().Err(err).Msg("i3 IPC unavailable, watching X11")
+			err = watchX11UntilI3(ctx, publish)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			// i3 became available, watch it again.
+			continue
+		}
+
+		log.Warn().Err(err).Msg("Cannot watch the focused window")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// watchX11UntilI3 watches X11 until ctx is done or the i3 IPC socket becomes
+// available, so that i3 or sway started after the server are used again.
+func watchX11UntilI3(ctx context.Context, onChange func(Window)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+
+			if conn, err := i3.Dial(); err == nil {
+				conn.Close()
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return watchX11(ctx, onChange)
+}
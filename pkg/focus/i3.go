@@ -0,0 +1,78 @@
+package focus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sd/pkg/i3"
+)
+
+func nodeWindow(n i3.Node) Window {
+	class := n.WindowProperties.Class
+	if class == "" {
+		class = n.AppID
+	}
+
+	title := n.WindowProperties.Title
+	if title == "" {
+		title = n.Name
+	}
+
+	return Window{Class: class, Title: title}
+}
+
+// watchI3 calls onChange with the focused window and again whenever the
+// focus or the title of the focused window changes.
+func watchI3(ctx context.Context, onChange func(Window)) error {
+	conn, err := i3.Dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	if tree, err := conn.Tree(); err == nil {
+		if node, ok := tree.FocusedNode(); ok {
+			onChange(nodeWindow(node))
+		}
+	}
+
+	if err := conn.Subscribe("window"); err != nil {
+		return err
+	}
+
+	for {
+		msgType, payload, err := conn.Receive()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read from i3: %w", err)
+		}
+
+		if msgType != i3.EventWindow {
+			continue
+		}
+
+		var event struct {
+			Change    string  `json:"change"`
+			Container i3.Node `json:"container"`
+		}
+		if err := json.Unmarshal(payload, &event); err != nil {
+			continue
+		}
+
+		switch event.Change {
+		case "focus":
+			onChange(nodeWindow(event.Container))
+		case "title":
+			if event.Container.Focused {
+				onChange(nodeWindow(event.Container))
+			}
+		}
+	}
+}
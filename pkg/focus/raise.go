@@ -0,0 +1,156 @@
+package focus
+
+import (
+	"fmt"
+	"regexp"
+	"sd/pkg/i3"
+
+	"github.com/robotn/xgb/xproto"
+	"github.com/robotn/xgbutil"
+	"github.com/robotn/xgbutil/ewmh"
+	"github.com/robotn/xgbutil/icccm"
+)
+
+// Matcher matches windows by class and title. Class and Title are regular
+// expressions, an empty expression matches anything.
+type Matcher struct {
+	class *regexp.Regexp
+	title *regexp.Regexp
+}
+
+// NewMatcher compiles the class and title expressions of a matcher.
+func NewMatcher(class string, title string) (Matcher, error) {
+	var m Matcher
+	var err error
+
+	if class != "" {
+		if m.class, err = regexp.Compile(class); err != nil {
+			return Matcher{}, fmt.Errorf("invalid class expression: %w", err)
+		}
+	}
+	if title != "" {
+		if m.title, err = regexp.Compile(title); err != nil {
+			return Matcher{}, fmt.Errorf("invalid title expression: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+// Match reports whether a window matches.
+func (m Matcher) Match(window Window) bool {
+	return (m.class == nil || m.class.MatchString(window.Class)) &&
+		(m.title == nil || m.title.MatchString(window.Title))
+}
+
+// Raise focuses the first window matching m, switching to its workspace,
+// and reports whether one was found. i3 and sway are asked through their IPC
+// socket, other window managers through _NET_ACTIVE_WINDOW.
+func Raise(m Matcher) (bool, error) {
+	found, err := raiseI3(m)
+	if err == nil {
+		return found, nil
+	}
+
+	return raiseX11(m)
+}
+
+func raiseI3(m Matcher) (bool, error) {
+	conn, err := i3.Dial()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	tree, err := conn.Tree()
+	if err != nil {
+		return false, err
+	}
+
+	for _, node := range tree.Windows() {
+		if !m.Match(nodeWindow(node)) {
+			continue
+		}
+		if err := conn.RunCommand(fmt.Sprintf("[con_id=%d] focus", node.ID)); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func raiseX11(m Matcher) (bool, error) {
+	xu, err := xgbutil.NewConn()
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to X: %w", err)
+	}
+	defer xu.Conn().Close()
+
+	windows, err := MatchX11(xu, m)
+	if err != nil || len(windows) == 0 {
+		return false, err
+	}
+
+	if err := ActivateX11(xu, windows[0]); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MatchX11 returns the client windows matching m, in the order of
+// _NET_CLIENT_LIST.
+func MatchX11(xu *xgbutil.XUtil, m Matcher) ([]xproto.Window, error) {
+	clients, err := ewmh.ClientListGet(xu)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list windows: %w", err)
+	}
+
+	var windows []xproto.Window
+	for _, win := range clients {
+		if m.Match(x11Window(xu, win)) {
+			windows = append(windows, win)
+		}
+	}
+
+	return windows, nil
+}
+
+// ActivateX11 switches to the desktop of a window and focuses it through
+// _NET_ACTIVE_WINDOW.
+func ActivateX11(xu *xgbutil.XUtil, win xproto.Window) error {
+	if desktop, err := ewmh.WmDesktopGet(xu, win); err == nil && desktop != 0xFFFFFFFF {
+		if err := ewmh.CurrentDesktopReq(xu, int(desktop)); err != nil {
+			return err
+		}
+	}
+	if err := ewmh.ActiveWindowReq(xu, win); err != nil {
+		return err
+	}
+
+	return Flush(xu)
+}
+
+// Flush makes a round trip so that the requests sent to X are handled
+// before the connection closes.
+func Flush(xu *xgbutil.XUtil) error {
+	_, err := xproto.GetInputFocus(xu.Conn()).Reply()
+	return err
+}
+
+// x11Window returns the class and title of a window.
+func x11Window(xu *xgbutil.XUtil, win xproto.Window) Window {
+	var window Window
+
+	if class, err := icccm.WmClassGet(xu, win); err == nil {
+		window.Class = class.Class
+	}
+
+	title, err := ewmh.WmNameGet(xu, win)
+	if err != nil || title == "" {
+		title, _ = icccm.WmNameGet(xu, win)
+	}
+	window.Title = title
+
+	return window
+}
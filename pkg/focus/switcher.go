@@ -0,0 +1,134 @@
+package focus
+
+import (
+	"encoding/json"
+	"regexp"
+	"sd/pkg/natsconn"
+	"sd/pkg/store"
+	"sd/pkg/types"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// switchState remembers the profile a device showed before a rule switched it.
+type switchState struct {
+	previous string
+	applied  string
+}
+
+type switcher struct {
+	instanceID  string
+	switched    map[string]switchState
+	expressions map[string]*regexp.Regexp
+}
+
+// SubscribeSwitcher applies the focus rules of every device of an instance
+// to the windows published on its focus subject. A device returns to its
+// previous profile when no rule matches anymore, unless the profile was
+// changed by hand in the meantime.
+func SubscribeSwitcher(instanceID string) error {
+	nc, _ := natsconn.GetNATSConn()
+
+	s := &switcher{
+		instanceID:  instanceID,
+		switched:    make(map[string]switchState),
+		expressions: make(map[string]*regexp.Regexp),
+	}
+
+	_, err := nc.Subscribe(Subject(instanceID), func(m *nats.Msg) {
+		var window Window
+		if err := json.Unmarshal(m.Data, &window); err != nil {
+			log.Error().Err(err).Msg("Error unmarshaling focused window")
+			return
+		}
+
+		s.apply(window)
+	})
+
+	return err
+}
+
+// matches reports whether an expression matches a value. Empty expressions
+// match anything.
+func (s *switcher) matches(expression string, value string) bool {
+	if expression == "" {
+		return true
+	}
+
+	re, ok := s.expressions[expression]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(expression); err != nil {
+			log.Warn().Err(err).Str("expression", expression).Msg("Invalid focus rule expression")
+			return false
+		}
+		s.expressions[expression] = re
+	}
+
+	return re.MatchString(value)
+}
+
+// match returns the profile of the first rule matching a window.
+func (s *switcher) match(rules []types.FocusRule, window Window) (string, bool) {
+	for _, rule := range rules {
+		if s.matches(rule.Class, window.Class) && s.matches(rule.Title, window.Title) {
+			return rule.ProfileID, true
+		}
+	}
+	return "", false
+}
+
+func (s *switcher) setProfile(device types.Device, profileID string) bool {
+	if store.GetProfile(s.instanceID, &device, profileID) == nil {
+		log.Warn().Str("device", device.ID).Str("profile", profileID).Msg("Focus rule profile not found")
+		return false
+	}
+
+	device.CurrentProfile = profileID
+	if _, err := store.UpdateDevice(s.instanceID, &device); err != nil {
+		log.Error().Err(err).Str("device", device.ID).Msg("Failed to switch profile")
+		return false
+	}
+
+	log.Info().Str("device", device.ID).Str("profile", profileID).Msg("Switched profile")
+	return true
+}
+
+func (s *switcher) apply(window Window) {
+	for _, device := range store.GetDevices(s.instanceID) {
+		rules, err := store.GetFocusRules(s.instanceID, device.ID)
+		if err != nil {
+			log.Error().Err(err).Str("device", device.ID).Msg("Failed to get focus rules")
+			continue
+		}
+
+		state, switched := s.switched[device.ID]
+		if switched && device.CurrentProfile != state.applied {
+			// Changed by hand since, keep it.
+			delete(s.switched, device.ID)
+			switched = false
+		}
+
+		if profileID, ok := s.match(rules, window); ok {
+			if profileID == device.CurrentProfile {
+				continue
+			}
+
+			previous := device.CurrentProfile
+			if switched {
+				previous = state.previous
+			}
+
+			if s.setProfile(device, profileID) {
+				s.switched[device.ID] = switchState{previous: previous, applied: profileID}
+			}
+			continue
+		}
+
+		if switched {
+			delete(s.switched, device.ID)
+			s.setProfile(device, state.previous)
+		}
+	}
+}
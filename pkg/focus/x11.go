@@ -0,0 +1,72 @@
+package focus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/robotn/xgb/xproto"
+	"github.com/robotn/xgbutil"
+	"github.com/robotn/xgbutil/ewmh"
+	"github.com/robotn/xgbutil/xevent"
+	"github.com/robotn/xgbutil/xprop"
+	"github.com/robotn/xgbutil/xwindow"
+)
+
+// activeWindow returns the class and title of the window named by
+// _NET_ACTIVE_WINDOW.
+func activeWindow(xu *xgbutil.XUtil) (Window, error) {
+	win, err := ewmh.ActiveWindowGet(xu)
+	if err != nil {
+		return Window{}, err
+	}
+	if win == 0 {
+		return Window{}, nil
+	}
+
+	return x11Window(xu, win), nil
+}
+
+// watchX11 calls onChange with the active window and again whenever
+// _NET_ACTIVE_WINDOW changes on the root window. It works with any EWMH
+// compliant window manager.
+func watchX11(ctx context.Context, onChange func(Window)) error {
+	xu, err := xgbutil.NewConn()
+	if err != nil {
+		return fmt.Errorf("failed to connect to X: %w", err)
+	}
+
+	activeAtom, err := xprop.Atm(xu, "_NET_ACTIVE_WINDOW")
+	if err != nil {
+		xu.Conn().Close()
+		return fmt.Errorf("failed to look up _NET_ACTIVE_WINDOW: %w", err)
+	}
+
+	root := xu.RootWin()
+	if err := xwindow.New(xu, root).Listen(xproto.EventMaskPropertyChange); err != nil {
+		xu.Conn().Close()
+		return fmt.Errorf("failed to listen on the root window: %w", err)
+	}
+
+	if window, err := activeWindow(xu); err == nil {
+		onChange(window)
+	}
+
+	xevent.PropertyNotifyFun(func(xu *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
+		if ev.Atom != activeAtom {
+			return
+		}
+		if window, err := activeWindow(xu); err == nil {
+			onChange(window)
+		}
+	}).Connect(xu, root)
+
+	go func() {
+		<-ctx.Done()
+		xevent.Quit(xu)
+		xu.Conn().Close()
+	}()
+
+	xevent.Main(xu)
+
+	return nil
+}
@@ -0,0 +1,222 @@
+// Package hass is a minimal client of the Home Assistant WebSocket API.
+package hass
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sd/pkg/wsrpc"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// EventStateChanged is the event Home Assistant fires when an entity
+// changes state.
+const EventStateChanged = "state_changed"
+
+const (
+	commandTimeout = 5 * time.Second
+	eventBuffer    = 256
+)
+
+// ErrClosed is returned by commands on a closed connection.
+var ErrClosed = errors.New("home assistant connection closed")
+
+// State is the state of an entity, such as a light or a sensor.
+type State struct {
+	EntityID   string         `json:"entity_id"`
+	State      string         `json:"state"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// Unit returns the unit of measurement of a sensor, or "".
+func (s State) Unit() string {
+	unit, _ := s.Attributes["unit_of_measurement"].(string)
+	return unit
+}
+
+// Event is an event fired by Home Assistant.
+type Event struct {
+	Type string          `json:"event_type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// StateChange is the data of a state_changed event. NewState is nil when the
+// entity was removed.
+type StateChange struct {
+	EntityID string `json:"entity_id"`
+	NewState *State `json:"new_state"`
+}
+
+type message struct {
+	ID      int             `json:"id"`
+	Type    string          `json:"type"`
+	Success bool            `json:"success"`
+	Result  json.RawMessage `json:"result"`
+	Event   *Event          `json:"event"`
+	Message string          `json:"message"`
+	Error   *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Client is a connection to the Home Assistant WebSocket API.
+type Client struct {
+	conn *wsrpc.Conn[Event]
+}
+
+// Dial connects to the WebSocket API at url, such as
+// ws://localhost:8123/api/websocket, and authenticates with a long-lived
+// access token.
+func Dial(url string, token string) (*Client, error) {
+	config, err := websocket.NewConfig(url, "http://localhost/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid Home Assistant URL: %w", err)
+	}
+	config.Dialer = &net.Dialer{Timeout: commandTimeout}
+
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Home Assistant: %w", err)
+	}
+
+	if err := authenticate(ws, token); err != nil {
+		ws.Close()
+		return nil, err
+	}
+
+	return &Client{conn: wsrpc.New(ws, dispatch, ErrClosed, commandTimeout, eventBuffer)}, nil
+}
+
+func authenticate(ws *websocket.Conn, token string) error {
+	ws.SetDeadline(time.Now().Add(commandTimeout))
+	defer ws.SetDeadline(time.Time{})
+
+	var msg message
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		return fmt.Errorf("failed to read auth_required: %w", err)
+	}
+	if msg.Type != "auth_required" {
+		return fmt.Errorf("expected auth_required, got %s", msg.Type)
+	}
+
+	if err := websocket.JSON.Send(ws, map[string]string{"type": "auth", "access_token": token}); err != nil {
+		return fmt.Errorf("failed to authenticate: %w", err)
+	}
+
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		return fmt.Errorf("failed to read auth result: %w", err)
+	}
+	switch msg.Type {
+	case "auth_ok":
+		return nil
+	case "auth_invalid":
+		return fmt.Errorf("authentication failed: %s", msg.Message)
+	default:
+		return fmt.Errorf("expected auth_ok, got %s", msg.Type)
+	}
+}
+
+// dispatch sorts the results and events of Home Assistant.
+func dispatch(data []byte) (string, *Event) {
+	var msg message
+	if json.Unmarshal(data, &msg) != nil {
+		return "", nil
+	}
+
+	switch msg.Type {
+	case "event":
+		return "", msg.Event
+	case "result":
+		return strconv.Itoa(msg.ID), nil
+	}
+
+	return "", nil
+}
+
+// Command sends a command of a type, such as get_states, with its fields
+// and decodes its result into result unless result is nil.
+func (c *Client) Command(commandType string, fields map[string]any, result any) error {
+	id := int(c.conn.NextID())
+
+	cmd := map[string]any{"id": id, "type": commandType}
+	for k, v := range fields {
+		cmd[k] = v
+	}
+
+	reply, err := c.conn.Call(commandType, strconv.Itoa(id), cmd)
+	if err != nil {
+		return err
+	}
+
+	var msg message
+	if err := json.Unmarshal(reply, &msg); err != nil {
+		return err
+	}
+
+	if !msg.Success {
+		if msg.Error != nil {
+			return fmt.Errorf("%s failed (%s): %s", commandType, msg.Error.Code, msg.Error.Message)
+		}
+		return fmt.Errorf("%s failed", commandType)
+	}
+	if result != nil && len(msg.Result) > 0 {
+		return json.Unmarshal(msg.Result, result)
+	}
+	return nil
+}
+
+// States returns the state of every entity.
+func (c *Client) States() ([]State, error) {
+	var states []State
+	if err := c.Command("get_states", nil, &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// CallService calls a service, such as light.toggle, on an entity unless
+// entityID is empty, with the service data data.
+func (c *Client) CallService(service string, entityID string, data map[string]any) error {
+	domain, name, ok := strings.Cut(service, ".")
+	if !ok || domain == "" || name == "" {
+		return fmt.Errorf("invalid service %q, expected domain.service", service)
+	}
+
+	fields := map[string]any{"domain": domain, "service": name}
+	if len(data) > 0 {
+		fields["service_data"] = data
+	}
+	if entityID != "" {
+		fields["target"] = map[string]string{"entity_id": entityID}
+	}
+
+	return c.Command("call_service", fields, nil)
+}
+
+// Subscribe subscribes to the events of a type, such as state_changed,
+// which are then sent on Events.
+func (c *Client) Subscribe(eventType string) error {
+	return c.Command("subscribe_events", map[string]any{"event_type": eventType}, nil)
+}
+
+// Events returns the events of the subscriptions. It is closed with the
+// connection.
+func (c *Client) Events() <-chan Event {
+	return c.conn.Events()
+}
+
+// Done is closed when the connection is lost.
+func (c *Client) Done() <-chan struct{} {
+	return c.conn.Done()
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
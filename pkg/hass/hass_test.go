@@ -0,0 +1,192 @@
+package hass
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const testToken = "long-lived-token"
+
+// fakeHA is a Home Assistant WebSocket API with a kitchen light, firing a
+// state_changed event for every service called.
+type fakeHA struct {
+	conns chan *websocket.Conn
+	calls chan map[string]any
+}
+
+func (f *fakeHA) serve(ws *websocket.Conn) {
+	defer ws.Close()
+
+	if websocket.JSON.Send(ws, map[string]any{"type": "auth_required"}) != nil {
+		return
+	}
+	var auth map[string]any
+	if websocket.JSON.Receive(ws, &auth) != nil {
+		return
+	}
+	if auth["type"] != "auth" || auth["access_token"] != testToken {
+		websocket.JSON.Send(ws, map[string]any{"type": "auth_invalid", "message": "Invalid access token"})
+		return
+	}
+	if websocket.JSON.Send(ws, map[string]any{"type": "auth_ok"}) != nil {
+		return
+	}
+	f.conns <- ws
+
+	light := map[string]any{"entity_id": "light.kitchen", "state": "off", "attributes": map[string]any{}}
+	for {
+		var cmd map[string]any
+		if websocket.JSON.Receive(ws, &cmd) != nil {
+			return
+		}
+
+		result := map[string]any{"id": cmd["id"], "type": "result", "success": true}
+		switch cmd["type"] {
+		case "get_states":
+			result["result"] = []any{
+				light,
+				map[string]any{"entity_id": "sensor.outside", "state": "21.5", "attributes": map[string]any{"unit_of_measurement": "°C"}},
+			}
+		case "call_service":
+			f.calls <- cmd
+			if cmd["domain"] == "nope" {
+				result["success"] = false
+				result["error"] = map[string]any{"code": "not_found", "message": "Service not found."}
+				break
+			}
+			light = map[string]any{"entity_id": "light.kitchen", "state": "on", "attributes": map[string]any{}}
+			event := map[string]any{"type": "event", "event": map[string]any{
+				"event_type": EventStateChanged,
+				"data":       map[string]any{"entity_id": "light.kitchen", "new_state": light},
+			}}
+			if websocket.JSON.Send(ws, event) != nil {
+				return
+			}
+		}
+		if websocket.JSON.Send(ws, result) != nil {
+			return
+		}
+	}
+}
+
+func start(t *testing.T) (*fakeHA, string) {
+	t.Helper()
+
+	f := &fakeHA{conns: make(chan *websocket.Conn, 4), calls: make(chan map[string]any, 4)}
+	server := httptest.NewServer(websocket.Handler(f.serve))
+	t.Cleanup(server.Close)
+
+	return f, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func dial(t *testing.T, url string) *Client {
+	t.Helper()
+
+	client, err := Dial(url, testToken)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestDialAuthenticates(t *testing.T) {
+	_, url := start(t)
+
+	dial(t, url)
+
+	_, err := Dial(url, "wrong")
+	if err == nil || !strings.Contains(err.Error(), "Invalid access token") {
+		t.Errorf("Dial with a wrong token = %v, want an authentication error", err)
+	}
+}
+
+func TestStates(t *testing.T) {
+	_, url := start(t)
+	client := dial(t, url)
+
+	states, err := client.States()
+	if err != nil {
+		t.Fatalf("States: %v", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("got %d states, want 2", len(states))
+	}
+	if s := states[1]; s.EntityID != "sensor.outside" || s.State != "21.5" || s.Unit() != "°C" {
+		t.Errorf("sensor state = %+v, want 21.5 °C", s)
+	}
+}
+
+func TestCallService(t *testing.T) {
+	f, url := start(t)
+	client := dial(t, url)
+
+	if err := client.CallService("light.turn_on", "light.kitchen", map[string]any{"brightness": 128}); err != nil {
+		t.Fatalf("CallService: %v", err)
+	}
+
+	call := <-f.calls
+	target, _ := call["target"].(map[string]any)
+	data, _ := call["service_data"].(map[string]any)
+	if call["domain"] != "light" || call["service"] != "turn_on" || target["entity_id"] != "light.kitchen" || data["brightness"] != 128.0 {
+		t.Errorf("call_service = %v, want light.turn_on on light.kitchen with brightness 128", call)
+	}
+
+	err := client.CallService("nope.missing", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "Service not found.") {
+		t.Errorf("unknown service = %v, want the error of Home Assistant", err)
+	}
+	<-f.calls
+
+	if err := client.CallService("toggle", "light.kitchen", nil); err == nil {
+		t.Error("service without a domain was called")
+	}
+}
+
+func TestStateChanges(t *testing.T) {
+	_, url := start(t)
+	client := dial(t, url)
+
+	if err := client.Subscribe(EventStateChanged); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := client.CallService("homeassistant.toggle", "light.kitchen", nil); err != nil {
+		t.Fatalf("CallService: %v", err)
+	}
+
+	select {
+	case event := <-client.Events():
+		if event.Type != EventStateChanged || !strings.Contains(string(event.Data), `"state":"on"`) {
+			t.Errorf("event = %s %s, want the kitchen light on", event.Type, event.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no state change received")
+	}
+}
+
+func TestConnectionLost(t *testing.T) {
+	f, url := start(t)
+	client := dial(t, url)
+
+	(<-f.conns).Close()
+
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done not closed after the connection was lost")
+	}
+	if _, err := client.States(); !errors.Is(err, ErrClosed) {
+		t.Errorf("command on a lost connection = %v, want ErrClosed", err)
+	}
+
+	client = dial(t, url)
+	if _, err := client.States(); err != nil {
+		t.Errorf("command after reconnecting: %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package i3
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// IPC message and event types, see https://i3wm.org/docs/ipc.html. Sway
+// speaks the same protocol.
+const (
+	MsgRunCommand    = 0
+	MsgGetWorkspaces = 1
+	MsgSubscribe     = 2
+	MsgGetTree       = 4
+
+	EventWorkspace = 0x80000000
+	EventWindow    = 0x80000003
+)
+
+const (
+	magic          = "i3-ipc"
+	headerLength   = len(magic) + 8
+	maxPayloadSize = 64 << 20
+)
+
+// Node is a container of the layout tree.
+type Node struct {
+	ID               int64  `json:"id"`
+	Focused          bool   `json:"focused"`
+	Name             string `json:"name"`
+	AppID            string `json:"app_id"`
+	Window           int64  `json:"window"` // X11 window ID, 0 for containers and Wayland windows
+	WindowProperties struct {
+		Class string `json:"class"`
+		Title string `json:"title"`
+	} `json:"window_properties"`
+	Nodes         []Node `json:"nodes"`
+	FloatingNodes []Node `json:"floating_nodes"`
+}
+
+// FocusedNode returns the focused container of a tree.
+func (n Node) FocusedNode() (Node, bool) {
+	if n.Focused {
+		return n, true
+	}
+
+	for _, children := range [][]Node{n.Nodes, n.FloatingNodes} {
+		for _, child := range children {
+			if node, ok := child.FocusedNode(); ok {
+				return node, true
+			}
+		}
+	}
+
+	return Node{}, false
+}
+
+// Windows returns the application windows of a tree.
+func (n Node) Windows() []Node {
+	if n.Window != 0 || n.AppID != "" {
+		return []Node{n}
+	}
+
+	var windows []Node
+	for _, children := range [][]Node{n.Nodes, n.FloatingNodes} {
+		for _, child := range children {
+			windows = append(windows, child.Windows()...)
+		}
+	}
+
+	return windows
+}
+
+// Workspace is a workspace as returned by GET_WORKSPACES.
+type Workspace struct {
+	Num     int    `json:"num"`
+	Name    string `json:"name"`
+	Visible bool   `json:"visible"`
+	Focused bool   `json:"focused"`
+	Urgent  bool   `json:"urgent"`
+	Output  string `json:"output"`
+}
+
+// SocketPath returns the IPC socket of the running i3 or sway.
+func SocketPath() (string, error) {
+	for _, name := range []string{"I3SOCK", "SWAYSOCK"} {
+		if path := os.Getenv(name); path != "" {
+			return path, nil
+		}
+	}
+
+	out, err := exec.Command("i3", "--get-socketpath").Output()
+	if err != nil {
+		return "", fmt.Errorf("i3 is not running: %w", err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Conn is a connection to the IPC socket. A connection that subscribed to
+// events receives them interleaved with replies, so commands are best sent
+// on a connection of their own.
+type Conn struct {
+	conn net.Conn
+}
+
+// Dial connects to the IPC socket of the running i3 or sway.
+func Dial() (*Conn, error) {
+	path, err := SocketPath()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to i3: %w", err)
+	}
+
+	return &Conn{conn: conn}, nil
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Send writes a message.
+func (c *Conn) Send(msgType uint32, payload []byte) error {
+	header := make([]byte, headerLength)
+	copy(header, magic)
+	binary.LittleEndian.PutUint32(header[len(magic):], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[len(magic)+4:], msgType)
+
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return fmt.Errorf("failed to write to i3: %w", err)
+	}
+
+	return nil
+}
+
+// Receive reads the next reply or event.
+func (c *Conn) Receive() (uint32, []byte, error) {
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return 0, nil, err
+	}
+
+	if string(header[:len(magic)]) != magic {
+		return 0, nil, fmt.Errorf("invalid i3 IPC message")
+	}
+
+	length := binary.LittleEndian.Uint32(header[len(magic):])
+	msgType := binary.LittleEndian.Uint32(header[len(magic)+4:])
+
+	if length > maxPayloadSize {
+		return 0, nil, fmt.Errorf("i3 IPC message is too large")
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.conn, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return msgType, payload, nil
+}
+
+// request sends a message and decodes its reply into v, skipping events.
+func (c *Conn) request(msgType uint32, payload []byte, v any) error {
+	if err := c.Send(msgType, payload); err != nil {
+		return err
+	}
+
+	for {
+		replyType, reply, err := c.Receive()
+		if err != nil {
+			return fmt.Errorf("failed to read from i3: %w", err)
+		}
+		if replyType == msgType {
+			return json.Unmarshal(reply, v)
+		}
+	}
+}
+
+// Tree returns the layout tree.
+func (c *Conn) Tree() (Node, error) {
+	var tree Node
+	err := c.request(MsgGetTree, nil, &tree)
+	return tree, err
+}
+
+// Workspaces returns the workspaces in the order i3 lists them.
+func (c *Conn) Workspaces() ([]Workspace, error) {
+	var workspaces []Workspace
+	err := c.request(MsgGetWorkspaces, nil, &workspaces)
+	return workspaces, err
+}
+
+// RunCommand runs an i3 command, such as `workspace 2`.
+func (c *Conn) RunCommand(command string) error {
+	var results []struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+
+	if err := c.request(MsgRunCommand, []byte(command), &results); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if !result.Success {
+			return fmt.Errorf("i3 command failed: %s", result.Error)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe subscribes to events, such as "window" or "workspace".
+func (c *Conn) Subscribe(events ...string) error {
+	payload, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := c.request(MsgSubscribe, payload, &result); err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("failed to subscribe to %s", strings.Join(events, ", "))
+	}
+
+	return nil
+}
+
+// QuoteArgument quotes a command argument such as a workspace name.
+func QuoteArgument(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"fmt"
+	"sd/pkg/util"
+
+	"github.com/karalabe/hid"
+)
+
+// VendorIDElgato is the USB vendor ID of every Stream Deck.
+const VendorIDElgato = 0x0fd9
+
+// Device types stored in types.Device.Type.
+const (
+	TypeXL      = "xl"
+	TypePlus    = "plus"
+	TypePedal   = "pedal"
+	TypeUnknown = "unknown"
+)
+
+// brightnessReportLength is the length of the feature report setting the
+// brightness of the models with key displays.
+const brightnessReportLength = 32
+
+// Touchscreen describes the touch strip of a model.
+type Touchscreen struct {
+	Width    int
+	Height   int
+	Segments int // One segment per dial
+}
+
+// Model describes the hardware of a Stream Deck model.
+type Model struct {
+	Type      string
+	Name      string
+	ProductID uint16
+
+	Rows int
+	Cols int
+
+	// Key images. Models without key displays still have a KeySize for the
+	// previews shown in the web UI.
+	HasKeyDisplay bool
+	KeySize       int
+	ImageFormat   util.ImageFormat
+	Transform     util.Transform
+
+	ImageReportLength int
+	InputReportLength int
+
+	Dials       int
+	Touchscreen *Touchscreen
+}
+
+var models = []Model{
+	{
+		Type:              TypeXL,
+		Name:              "Stream Deck XL",
+		ProductID:         0x006c,
+		Rows:              4,
+		Cols:              8,
+		HasKeyDisplay:     true,
+		KeySize:           96,
+		ImageFormat:       util.FormatJPEG,
+		Transform:         util.TransformRotate180,
+		ImageReportLength: 1024,
+		InputReportLength: 512,
+	},
+	{
+		Type:              TypePlus,
+		Name:              "Stream Deck +",
+		ProductID:         0x0084,
+		Rows:              2,
+		Cols:              4,
+		HasKeyDisplay:     true,
+		KeySize:           120,
+		ImageFormat:       util.FormatJPEG,
+		Transform:         util.TransformNone,
+		ImageReportLength: 1024,
+		InputReportLength: 512,
+		Dials:             4,
+		Touchscreen:       &Touchscreen{Width: 800, Height: 100, Segments: 4},
+	},
+	{
+		Type:              TypePedal,
+		Name:              "Stream Deck Pedal",
+		ProductID:         0x0086,
+		Rows:              1,
+		Cols:              3,
+		KeySize:           96,
+		InputReportLength: 512,
+	},
+}
+
+// All returns every supported model.
+func All() []Model {
+	return models
+}
+
+// ByProductID returns the model with a USB product ID.
+func ByProductID(productID uint16) (Model, bool) {
+	for _, m := range models {
+		if m.ProductID == productID {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// ByType returns the model for a device type.
+func ByType(deviceType string) (Model, bool) {
+	for _, m := range models {
+		if m.Type == deviceType {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// DeviceType returns the device type of a USB product ID.
+func DeviceType(productID uint16) string {
+	if m, ok := ByProductID(productID); ok {
+		return m.Type
+	}
+	return TypeUnknown
+}
+
+// Keys returns the number of keys on the model.
+func (m Model) Keys() int {
+	return m.Rows * m.Cols
+}
+
+// KeyImageSpec returns the spec key images are rendered to for the web UI
+// and the store. The device transform is applied when writing to the device.
+func (m Model) KeyImageSpec() util.ImageSpec {
+	return util.ImageSpec{Width: m.KeySize, Height: m.KeySize, Format: util.FormatJPEG}
+}
+
+// DeviceImage converts a rendered key image to the format and orientation the
+// device expects.
+func (m Model) DeviceImage(buffer []byte) ([]byte, error) {
+	if m.Transform == util.TransformNone && (m.ImageFormat == util.FormatJPEG || m.ImageFormat == "") {
+		return buffer, nil
+	}
+
+	return util.RenderImage(buffer, util.ImageSpec{Format: m.ImageFormat, Transform: m.Transform})
+}
+
+// WriteKeyImage converts a rendered key image for the device and writes it to a key.
+func (m Model) WriteKeyImage(device *hid.Device, keyID int, buffer []byte) error {
+	content, err := m.DeviceImage(buffer)
+	if err != nil {
+		return err
+	}
+
+	return util.WriteKeyImage(device, keyID, content, m.ImageReportLength)
+}
+
+// SetBrightness sets the brightness of the displays of a device in percent.
+func (m Model) SetBrightness(device *hid.Device, percent int) error {
+	if !m.HasKeyDisplay {
+		return fmt.Errorf("%s has no displays", m.Name)
+	}
+
+	report := make([]byte, brightnessReportLength)
+	report[0], report[1], report[2] = 0x03, 0x08, byte(percent)
+
+	if _, err := device.SendFeatureReport(report); err != nil {
+		return fmt.Errorf("failed to set brightness: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package multiaction
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sd/pkg/actions"
+	"sd/pkg/natsconn"
+	"sd/pkg/navigation"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// Multi-actions are handled by the device driver instead of a plugin.
+const (
+	Run    = "sd.multiaction.run"
+	Toggle = "sd.multiaction.toggle"
+)
+
+const stepTimeout = time.Minute
+
+// IsMultiAction reports whether an action UUID is a multi-action.
+func IsMultiAction(uuid string) bool {
+	return uuid == Run || uuid == Toggle
+}
+
+// Handle runs the steps of a multi-action button pressed at a location. A
+// toggle multi-action flips the button between states 0 and 1 and runs
+// Steps from state 0 and ToggleSteps from state 1. Steps run in the
+// background so that delays do not block the device.
+func Handle(instanceID string, device *types.Device, location navigation.Location, button types.Button) error {
+	key := fmt.Sprintf("instances.%s.devices.%s.profiles.%s.pages.%s.buttons.%s",
+		instanceID, device.ID, location.ProfileID, location.PageID, button.ID)
+
+	field, steps := "steps", button.Settings.Steps
+
+	if button.UUID == Toggle {
+		if button.State == "1" {
+			field, steps = "toggleSteps", button.Settings.ToggleSteps
+			button.State = "0"
+		} else {
+			button.State = "1"
+		}
+
+		if _, err := store.UpdateButton(instanceID, device, location.ProfileID, location.PageID, &button); err != nil {
+			return fmt.Errorf("failed to toggle button: %w", err)
+		}
+	}
+
+	go func() {
+		if err := RunSteps(instanceID, device, key, field, steps); err != nil {
+			log.Error().Err(err).Str("button", button.ID).Msg("Multi action stopped")
+		}
+	}()
+
+	return nil
+}
+
+// RunSteps executes the steps stored in field of the button, or schedule, at
+// key in order. A failing step is logged and skipped unless it is marked
+// StopOnError.
+func RunSteps(instanceID string, device *types.Device, key string, field string, steps []types.Step) error {
+	for i, step := range steps {
+		if step.Delay > 0 {
+			time.Sleep(time.Duration(step.Delay) * time.Millisecond)
+		}
+
+		if step.UUID == "" || step.UUID == actions.None {
+			continue
+		}
+
+		if err := runStep(instanceID, device, key, field+"."+strconv.Itoa(i), step); err != nil {
+			if step.StopOnError {
+				return fmt.Errorf("step %d (%s): %w", i+1, step.UUID, err)
+			}
+			log.Warn().Err(err).Int("step", i+1).Str("action", step.UUID).Msg("Multi action step failed")
+		}
+	}
+
+	return nil
+}
+
+// runStep runs a step. Plugins are told which stored step they run so that
+// they can check it against the stored button.
+func runStep(instanceID string, device *types.Device, key string, ref string, step types.Step) error {
+	button := types.Button{UUID: step.UUID, Settings: step.Settings}
+
+	if navigation.IsNavigation(step.UUID) {
+		// An earlier step may have changed the page.
+		current := store.GetDevice(instanceID, device.ID)
+		if current == nil {
+			return fmt.Errorf("device not found: %s", device.ID)
+		}
+		return navigation.Handle(instanceID, current, button)
+	}
+
+	if IsMultiAction(step.UUID) {
+		return fmt.Errorf("multi actions cannot be nested")
+	}
+
+	data, err := json.Marshal(button)
+	if err != nil {
+		return err
+	}
+
+	nc, _ := natsconn.GetNATSConn()
+
+	reply, err := nc.RequestMsg(actions.NewMsg(step.UUID, key, ref, data), stepTimeout)
+	if errors.Is(err, nats.ErrNoResponders) {
+		return fmt.Errorf("no plugin handles %s", step.UUID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return actions.ResultError(reply.Data)
+}
+
+// ErrOtherInstance marks messages for buttons of another instance, which
+// its own plugins answer.
+var ErrOtherInstance = errors.New("button of another instance")
+
+// StoredSettings returns the settings stored for the button or pedal
+// switch, or the multi-action or schedule step, a message was sent for. The
+// payload is never trusted: only settings saved on a button, switch or
+// schedule of this instance are used.
+func StoredSettings(instanceID string, m *nats.Msg) (types.Settings, error) {
+	if err := actions.Verify(m); err != nil {
+		return types.Settings{}, err
+	}
+
+	key := actions.ButtonKey(m)
+	if scheduleInstance, deviceID, ok := store.ParseSchedulesKey(key); ok {
+		if scheduleInstance != instanceID {
+			return types.Settings{}, ErrOtherInstance
+		}
+		return scheduleSettings(instanceID, deviceID, m)
+	}
+
+	segments := strings.Split(key, ".")
+	_, _, isSwitch := store.ParseSwitchKey(key)
+	isButton := len(segments) == 10 && segments[0] == "instances" && segments[8] == "buttons"
+	if !isButton && !isSwitch {
+		return types.Settings{}, fmt.Errorf("not sent for a stored button")
+	}
+	if segments[1] != instanceID {
+		return types.Settings{}, ErrOtherInstance
+	}
+
+	button, err := store.GetButton(key)
+	if err != nil {
+		return types.Settings{}, fmt.Errorf("button not found: %w", err)
+	}
+
+	ref := m.Header.Get(actions.StepHeader)
+	if ref == "" {
+		if button.UUID != m.Subject {
+			return types.Settings{}, fmt.Errorf("button is not bound to %s", m.Subject)
+		}
+		return button.Settings, nil
+	}
+
+	field, index, _ := strings.Cut(ref, ".")
+	if !hasSteps(button.UUID, field) {
+		return types.Settings{}, fmt.Errorf("button has no %s", field)
+	}
+
+	steps := actions.Steps(&button.Settings, field)
+	i, err := strconv.Atoi(index)
+	if steps == nil || err != nil || i < 0 || i >= len(*steps) {
+		return types.Settings{}, fmt.Errorf("unknown step: %s", ref)
+	}
+
+	step := (*steps)[i]
+	if step.UUID != m.Subject {
+		return types.Settings{}, fmt.Errorf("step %s is not bound to %s", ref, m.Subject)
+	}
+
+	return step.Settings, nil
+}
+
+// scheduleSettings returns the settings of the step of a schedule a message
+// was sent for. Steps of a schedule are referred to as <schedule ID>.<index>.
+func scheduleSettings(instanceID string, deviceID string, m *nats.Msg) (types.Settings, error) {
+	ref := m.Header.Get(actions.StepHeader)
+	scheduleID, index, _ := strings.Cut(ref, ".")
+
+	schedules, err := store.GetSchedules(instanceID, deviceID)
+	if err != nil {
+		return types.Settings{}, err
+	}
+
+	for _, schedule := range schedules {
+		if schedule.ID != scheduleID {
+			continue
+		}
+
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= len(schedule.Steps) {
+			return types.Settings{}, fmt.Errorf("unknown step: %s", ref)
+		}
+
+		step := schedule.Steps[i]
+		if step.UUID != m.Subject {
+			return types.Settings{}, fmt.Errorf("step %s is not bound to %s", ref, m.Subject)
+		}
+		return step.Settings, nil
+	}
+
+	return types.Settings{}, fmt.Errorf("schedule not found: %s", scheduleID)
+}
+
+// hasSteps reports whether the action of a button runs the steps stored in
+// field, such as a multi-action or a countdown running steps on completion.
+func hasSteps(uuid string, field string) bool {
+	definition, ok := actions.Get(uuid)
+	if !ok {
+		return false
+	}
+	for _, f := range definition.Fields {
+		if f.Key == field && f.Type == "steps" {
+			return true
+		}
+	}
+	return false
+}
@@ -11,9 +11,11 @@ import (
 )
 
 var (
-	nc   *nats.Conn
-	kv   nats.KeyValue
-	once sync.Once
+	nc         *nats.Conn
+	kv         nats.KeyValue
+	once       sync.Once
+	objects    nats.ObjectStore
+	objectOnce sync.Once
 )
 
 // GetNATSConn returns a singleton NATS connection.
@@ -87,3 +89,41 @@ func GetNATSConn() (*nats.Conn, nats.KeyValue) {
 
 	return nc, kv
 }
+
+// GetObjectStore returns a singleton Object Store used for images.
+func GetObjectStore() nats.ObjectStore {
+	objectOnce.Do(func() {
+		nc, _ := GetNATSConn()
+
+		natsObjectBucket := env.Get("NATS_OBJECT_BUCKET", "sd-images")
+
+		log.Info().Str("NATS_OBJECT_BUCKET", natsObjectBucket).Msg("NATS_OBJECT_BUCKET")
+
+		js, err := nc.JetStream()
+
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error enabling JetStream")
+			os.Exit(1)
+		}
+
+		// Check if the Object Store bucket already exists
+		objects, err = js.ObjectStore(natsObjectBucket)
+
+		if err == nats.ErrStreamNotFound {
+			// Create the bucket if it doesn't exist
+			objects, err = js.CreateObjectStore(&nats.ObjectStoreConfig{
+				Bucket: natsObjectBucket,
+			})
+
+			if err != nil {
+				log.Fatal().Err(err).Str("bucket", natsObjectBucket).Msg("Error creating Object Store bucket")
+				os.Exit(1)
+			}
+		} else if err != nil {
+			log.Fatal().Err(err).Msg("Error accessing Object Store bucket")
+			os.Exit(1)
+		}
+	})
+
+	return objects
+}
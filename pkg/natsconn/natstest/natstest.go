@@ -0,0 +1,49 @@
+// Package natstest runs an embedded NATS server with JetStream for the tests
+// of packages using natsconn.
+package natstest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// Run starts a NATS server with JetStream storing in a temporary directory,
+// points NATS_URL at it and runs the tests of a package. Call it from
+// TestMain, before anything connects to NATS.
+func Run(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "sd-nats-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "natstest:", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  dir,
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "natstest:", err)
+		return 1
+	}
+
+	go s.Start()
+	defer s.Shutdown()
+
+	if !s.ReadyForConnections(10 * time.Second) {
+		fmt.Fprintln(os.Stderr, "natstest: NATS server not ready")
+		return 1
+	}
+
+	os.Setenv("NATS_URL", s.ClientURL())
+
+	return m.Run()
+}
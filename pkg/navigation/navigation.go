@@ -0,0 +1,239 @@
+package navigation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sd/pkg/models"
+	"sd/pkg/natsconn"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// Navigation and device actions are handled by the device driver instead of
+// a plugin.
+const (
+	PageNext     = "sd.navigation.page.next"
+	PagePrevious = "sd.navigation.page.previous"
+	PageGoto     = "sd.navigation.page.goto"
+	Profile      = "sd.navigation.profile"
+	Brightness   = "sd.device.brightness"
+)
+
+// IsNavigation reports whether an action UUID is a navigation or device
+// action.
+func IsNavigation(uuid string) bool {
+	return strings.HasPrefix(uuid, "sd.navigation.") || strings.HasPrefix(uuid, "sd.device.")
+}
+
+// Location is a page of a profile on a device.
+type Location struct {
+	ProfileID string
+	PageID    string
+}
+
+// Current returns the current profile and page of a device.
+func Current(instanceID string, deviceID string) Location {
+	device := store.GetDevice(instanceID, deviceID)
+	if device == nil || device.CurrentProfile == "" {
+		return Location{}
+	}
+
+	profile := store.GetProfile(instanceID, device, device.CurrentProfile)
+	if profile == nil {
+		return Location{ProfileID: device.CurrentProfile}
+	}
+
+	return Location{ProfileID: profile.ID, PageID: profile.CurrentPage}
+}
+
+// BufferKey returns the key of the rendered image of a key at a location.
+func BufferKey(instanceID string, deviceID string, location Location, keyID int) string {
+	return fmt.Sprintf("instances.%s.devices.%s.profiles.%s.pages.%s.buttons.%d.buffer",
+		instanceID, deviceID, location.ProfileID, location.PageID, keyID)
+}
+
+// ParseBufferKey returns the location and key number of a
+// instances.<i>.devices.<d>.profiles.<p>.pages.<pg>.buttons.<n>.buffer key.
+func ParseBufferKey(key string) (Location, int, bool) {
+	segments := strings.Split(key, ".")
+	if len(segments) != 11 || segments[4] != "profiles" || segments[6] != "pages" || segments[8] != "buttons" || segments[10] != "buffer" {
+		return Location{}, 0, false
+	}
+
+	id, err := strconv.Atoi(segments[9])
+	if err != nil {
+		return Location{}, 0, false
+	}
+
+	return Location{ProfileID: segments[5], PageID: segments[7]}, id, true
+}
+
+// Follow calls onChange with the current location of a device, and again
+// whenever the current profile or page of the device changes.
+func Follow(ctx context.Context, instanceID string, deviceID string, onChange func(Location)) {
+	_, kv := natsconn.GetNATSConn()
+
+	deviceKey := fmt.Sprintf("instances.%s.devices.%s", instanceID, deviceID)
+
+	watcher, err := kv.WatchFiltered([]string{deviceKey, deviceKey + ".profiles.*"}, nats.UpdatesOnly())
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating watcher")
+		return
+	}
+	defer watcher.Stop()
+
+	location := Current(instanceID, deviceID)
+	onChange(location)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update := <-watcher.Updates():
+			if update == nil {
+				continue
+			}
+
+			current := Current(instanceID, deviceID)
+			if current == location {
+				continue
+			}
+
+			log.Info().Str("profile", current.ProfileID).Str("page", current.PageID).Msg("Current page changed")
+
+			location = current
+			onChange(location)
+		}
+	}
+}
+
+// FollowBrightness calls apply with the brightness of a device, and again
+// whenever it changes. Devices without a brightness are left as they are.
+func FollowBrightness(ctx context.Context, instanceID string, deviceID string, apply func(percent int)) {
+	_, kv := natsconn.GetNATSConn()
+
+	watcher, err := kv.Watch(fmt.Sprintf("instances.%s.devices.%s", instanceID, deviceID))
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating watcher")
+		return
+	}
+	defer watcher.Stop()
+
+	brightness := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update := <-watcher.Updates():
+			if update == nil || update.Operation() != nats.KeyValuePut {
+				continue
+			}
+
+			var device types.Device
+			if err := json.Unmarshal(update.Value(), &device); err != nil {
+				log.Error().Err(err).Str("key", update.Key()).Msg("Error unmarshaling device")
+				continue
+			}
+			if device.Brightness == 0 || device.Brightness == brightness {
+				continue
+			}
+
+			brightness = device.Brightness
+			apply(brightness)
+		}
+	}
+}
+
+// Handle performs a navigation action by updating the current page of the
+// profile, or the current profile or brightness of the device, drivers
+// follow the change.
+func Handle(instanceID string, device *types.Device, button types.Button) error {
+	switch button.UUID {
+	case Profile:
+		return switchProfile(instanceID, device, button.Settings.Profile)
+	case Brightness:
+		return setBrightness(instanceID, device, button.Settings.Brightness)
+	}
+
+	profile := store.GetProfile(instanceID, device, device.CurrentProfile)
+	if profile == nil {
+		return fmt.Errorf("current profile not found")
+	}
+
+	if len(profile.Pages) == 0 {
+		return nil
+	}
+
+	current := 0
+	for i, page := range profile.Pages {
+		if page.ID == profile.CurrentPage {
+			current = i
+		}
+	}
+
+	next := current
+	switch button.UUID {
+	case PageNext:
+		next = (current + 1) % len(profile.Pages)
+	case PagePrevious:
+		next = (current - 1 + len(profile.Pages)) % len(profile.Pages)
+	case PageGoto:
+		if button.Settings.Page < 1 || button.Settings.Page > len(profile.Pages) {
+			return fmt.Errorf("page %d does not exist", button.Settings.Page)
+		}
+		next = button.Settings.Page - 1
+	default:
+		return fmt.Errorf("unknown navigation action: %s", button.UUID)
+	}
+
+	if next == current {
+		return nil
+	}
+
+	profile.CurrentPage = profile.Pages[next].ID
+
+	_, err := store.UpdateProfile(instanceID, device, profile)
+	return err
+}
+
+// setBrightness stores the brightness of a device in percent.
+func setBrightness(instanceID string, device *types.Device, percent int) error {
+	if percent < 1 || percent > 100 {
+		return fmt.Errorf("brightness must be between 1 and 100 percent, got %d", percent)
+	}
+	if model, ok := models.ByType(device.Type); ok && !model.HasKeyDisplay {
+		return fmt.Errorf("%s has no displays", model.Name)
+	}
+	if device.Brightness == percent {
+		return nil
+	}
+
+	device.Brightness = percent
+	_, err := store.UpdateDevice(instanceID, device)
+	return err
+}
+
+// switchProfile makes the profile with the given ID or name, ignoring case,
+// the current profile of the device.
+func switchProfile(instanceID string, device *types.Device, name string) error {
+	for _, profile := range store.GetProfiles(instanceID, device) {
+		if profile.ID != name && !strings.EqualFold(profile.Name, name) {
+			continue
+		}
+		if device.CurrentProfile == profile.ID {
+			return nil
+		}
+
+		device.CurrentProfile = profile.ID
+		_, err := store.UpdateDevice(instanceID, device)
+		return err
+	}
+
+	return fmt.Errorf("profile %q not found", name)
+}
@@ -0,0 +1,239 @@
+// Package obs is a minimal obs-websocket v5 client.
+package obs
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sd/pkg/wsrpc"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Message opcodes of the obs-websocket v5 protocol.
+const (
+	OpHello           = 0
+	OpIdentify        = 1
+	OpIdentified      = 2
+	OpEvent           = 5
+	OpRequest         = 6
+	OpRequestResponse = 7
+)
+
+// Event subscription flags of Identify.
+const (
+	SubscribeScenes     = 1 << 2
+	SubscribeInputs     = 1 << 3
+	SubscribeOutputs    = 1 << 6
+	SubscribeSceneItems = 1 << 7
+)
+
+const (
+	protocol       = "obswebsocket.json"
+	rpcVersion     = 1
+	requestTimeout = 5 * time.Second
+	eventBuffer    = 256
+)
+
+// ErrClosed is returned by requests on a closed connection.
+var ErrClosed = errors.New("obs connection closed")
+
+type message struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+}
+
+type hello struct {
+	Authentication *struct {
+		Challenge string `json:"challenge"`
+		Salt      string `json:"salt"`
+	} `json:"authentication"`
+}
+
+type identify struct {
+	RPCVersion         int    `json:"rpcVersion"`
+	Authentication     string `json:"authentication,omitempty"`
+	EventSubscriptions int    `json:"eventSubscriptions"`
+}
+
+type request struct {
+	RequestType string `json:"requestType"`
+	RequestID   string `json:"requestId"`
+	RequestData any    `json:"requestData,omitempty"`
+}
+
+type response struct {
+	RequestID     string `json:"requestId"`
+	RequestStatus struct {
+		Result  bool   `json:"result"`
+		Code    int    `json:"code"`
+		Comment string `json:"comment"`
+	} `json:"requestStatus"`
+	ResponseData json.RawMessage `json:"responseData"`
+}
+
+// Event is an event sent by OBS, such as CurrentProgramSceneChanged.
+type Event struct {
+	Type string          `json:"eventType"`
+	Data json.RawMessage `json:"eventData"`
+}
+
+// Client is a connection to obs-websocket.
+type Client struct {
+	conn *wsrpc.Conn[Event]
+}
+
+// Auth returns the authentication string of the v5 handshake for a
+// password, salt and challenge.
+func Auth(password string, salt string, challenge string) string {
+	secret := sha256.Sum256([]byte(password + salt))
+	encoded := base64.StdEncoding.EncodeToString(secret[:])
+	auth := sha256.Sum256([]byte(encoded + challenge))
+	return base64.StdEncoding.EncodeToString(auth[:])
+}
+
+// Dial connects to obs-websocket at url, such as ws://localhost:4455, and
+// identifies with password, subscribing to the events of subscriptions.
+func Dial(url string, password string, subscriptions int) (*Client, error) {
+	config, err := websocket.NewConfig(url, "http://localhost/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid obs-websocket URL: %w", err)
+	}
+	config.Protocol = []string{protocol}
+	config.Dialer = &net.Dialer{Timeout: requestTimeout}
+
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to obs-websocket: %w", err)
+	}
+
+	if err := handshake(ws, password, subscriptions); err != nil {
+		ws.Close()
+		return nil, err
+	}
+
+	return &Client{conn: wsrpc.New(ws, dispatch, ErrClosed, requestTimeout, eventBuffer)}, nil
+}
+
+func handshake(ws *websocket.Conn, password string, subscriptions int) error {
+	ws.SetDeadline(time.Now().Add(requestTimeout))
+	defer ws.SetDeadline(time.Time{})
+
+	var msg message
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		return fmt.Errorf("failed to read hello: %w", err)
+	}
+	if msg.Op != OpHello {
+		return fmt.Errorf("expected hello, got op %d", msg.Op)
+	}
+
+	var h hello
+	if err := json.Unmarshal(msg.D, &h); err != nil {
+		return fmt.Errorf("invalid hello: %w", err)
+	}
+
+	id := identify{RPCVersion: rpcVersion, EventSubscriptions: subscriptions}
+	if h.Authentication != nil {
+		if password == "" {
+			return fmt.Errorf("obs-websocket requires a password")
+		}
+		id.Authentication = Auth(password, h.Authentication.Salt, h.Authentication.Challenge)
+	}
+
+	if err := send(ws, OpIdentify, id); err != nil {
+		return fmt.Errorf("failed to identify: %w", err)
+	}
+
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	if msg.Op != OpIdentified {
+		return fmt.Errorf("expected identified, got op %d", msg.Op)
+	}
+
+	return nil
+}
+
+func send(ws *websocket.Conn, op int, data any) error {
+	d, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return websocket.JSON.Send(ws, message{Op: op, D: d})
+}
+
+// dispatch sorts the responses and events of obs-websocket.
+func dispatch(data []byte) (string, *Event) {
+	var msg message
+	if json.Unmarshal(data, &msg) != nil {
+		return "", nil
+	}
+
+	switch msg.Op {
+	case OpEvent:
+		var event Event
+		if json.Unmarshal(msg.D, &event) == nil {
+			return "", &event
+		}
+	case OpRequestResponse:
+		var resp response
+		if json.Unmarshal(msg.D, &resp) == nil {
+			return resp.RequestID, nil
+		}
+	}
+
+	return "", nil
+}
+
+// Request sends a request, such as SetCurrentProgramScene, and decodes its
+// response data into result unless result is nil.
+func (c *Client) Request(requestType string, data any, result any) error {
+	id := strconv.FormatUint(c.conn.NextID(), 10)
+
+	d, err := json.Marshal(request{RequestType: requestType, RequestID: id, RequestData: data})
+	if err != nil {
+		return err
+	}
+
+	reply, err := c.conn.Call(requestType, id, message{Op: OpRequest, D: d})
+	if err != nil {
+		return err
+	}
+
+	var msg message
+	var resp response
+	if err := json.Unmarshal(reply, &msg); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(msg.D, &resp); err != nil {
+		return err
+	}
+
+	if !resp.RequestStatus.Result {
+		return fmt.Errorf("%s failed (%d): %s", requestType, resp.RequestStatus.Code, resp.RequestStatus.Comment)
+	}
+	if result != nil && len(resp.ResponseData) > 0 {
+		return json.Unmarshal(resp.ResponseData, result)
+	}
+	return nil
+}
+
+// Events returns the events sent by OBS. It is closed with the connection.
+func (c *Client) Events() <-chan Event {
+	return c.conn.Events()
+}
+
+// Done is closed when the connection is lost.
+func (c *Client) Done() <-chan struct{} {
+	return c.conn.Done()
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
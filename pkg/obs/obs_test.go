@@ -0,0 +1,196 @@
+package obs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// The example of the obs-websocket v5 protocol documentation, with the
+// authentication string it gives.
+const (
+	testPassword  = "supersecretpassword"
+	testSalt      = "lM1GncleQOaCu9lT1yeUZhFYnqhsLLP1G5lAGo3ixaI="
+	testChallenge = "+IxH4CnCiqpX1rM9scsNynZzbOe4KhDeYcTNS3PDaeY="
+	testAuth      = "1Ct943GAT+6YQUUX47Ia/ncufilbe6+oD6lY+5kaCu4="
+)
+
+// fakeOBS is an obs-websocket server answering GetVersion, failing Fail and
+// firing an event for TriggerEvent. With auth set, clients must identify
+// with it.
+type fakeOBS struct {
+	auth  string
+	conns chan *websocket.Conn
+}
+
+func (f *fakeOBS) serve(ws *websocket.Conn) {
+	defer ws.Close()
+
+	var h hello
+	if f.auth != "" {
+		h.Authentication = &struct {
+			Challenge string `json:"challenge"`
+			Salt      string `json:"salt"`
+		}{Challenge: testChallenge, Salt: testSalt}
+	}
+	if send(ws, OpHello, h) != nil {
+		return
+	}
+
+	var msg message
+	var id identify
+	if websocket.JSON.Receive(ws, &msg) != nil || msg.Op != OpIdentify || json.Unmarshal(msg.D, &id) != nil {
+		return
+	}
+	if f.auth != "" && id.Authentication != f.auth {
+		return
+	}
+	if send(ws, OpIdentified, map[string]int{"negotiatedRpcVersion": rpcVersion}) != nil {
+		return
+	}
+	f.conns <- ws
+
+	for {
+		if websocket.JSON.Receive(ws, &msg) != nil {
+			return
+		}
+		var req request
+		if msg.Op != OpRequest || json.Unmarshal(msg.D, &req) != nil {
+			continue
+		}
+
+		resp := response{RequestID: req.RequestID}
+		resp.RequestStatus.Result = true
+		resp.RequestStatus.Code = 100
+		switch req.RequestType {
+		case "GetVersion":
+			resp.ResponseData = json.RawMessage(`{"obsVersion":"30.1.2"}`)
+		case "Fail":
+			resp.RequestStatus.Result = false
+			resp.RequestStatus.Code = 600
+			resp.RequestStatus.Comment = "no such scene"
+		case "TriggerEvent":
+			event := Event{Type: "CurrentProgramSceneChanged", Data: json.RawMessage(`{"sceneName":"Live"}`)}
+			if send(ws, OpEvent, event) != nil {
+				return
+			}
+		}
+		if send(ws, OpRequestResponse, resp) != nil {
+			return
+		}
+	}
+}
+
+func start(t *testing.T, auth string) (*fakeOBS, string) {
+	t.Helper()
+
+	f := &fakeOBS{auth: auth, conns: make(chan *websocket.Conn, 4)}
+	server := httptest.NewServer(websocket.Handler(f.serve))
+	t.Cleanup(server.Close)
+
+	return f, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func dial(t *testing.T, url string, password string) *Client {
+	t.Helper()
+
+	client, err := Dial(url, password, SubscribeScenes)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestDialIdentifies(t *testing.T) {
+	_, url := start(t, "")
+	dial(t, url, "")
+}
+
+func TestAuth(t *testing.T) {
+	if got := Auth(testPassword, testSalt, testChallenge); got != testAuth {
+		t.Errorf("Auth = %q, want %q", got, testAuth)
+	}
+}
+
+func TestDialAuthenticates(t *testing.T) {
+	_, url := start(t, testAuth)
+
+	dial(t, url, testPassword)
+
+	if _, err := Dial(url, "wrong", SubscribeScenes); err == nil {
+		t.Error("Dial with a wrong password succeeded")
+	}
+	if _, err := Dial(url, "", SubscribeScenes); err == nil || !strings.Contains(err.Error(), "requires a password") {
+		t.Errorf("Dial without a password = %v, want a missing password error", err)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	_, url := start(t, "")
+	client := dial(t, url, "")
+
+	var version struct {
+		OBSVersion string `json:"obsVersion"`
+	}
+	if err := client.Request("GetVersion", nil, &version); err != nil {
+		t.Fatalf("GetVersion: %v", err)
+	}
+	if version.OBSVersion != "30.1.2" {
+		t.Errorf("obsVersion = %q, want 30.1.2", version.OBSVersion)
+	}
+
+	err := client.Request("Fail", map[string]any{"sceneName": "Missing"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "no such scene") {
+		t.Errorf("Fail = %v, want the comment of the failed request", err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	_, url := start(t, "")
+	client := dial(t, url, "")
+
+	if err := client.Request("TriggerEvent", nil, nil); err != nil {
+		t.Fatalf("TriggerEvent: %v", err)
+	}
+
+	select {
+	case event := <-client.Events():
+		if event.Type != "CurrentProgramSceneChanged" || !strings.Contains(string(event.Data), "Live") {
+			t.Errorf("event = %s %s, want CurrentProgramSceneChanged to Live", event.Type, event.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	f, url := start(t, "")
+	client := dial(t, url, "")
+
+	// OBS quits.
+	(<-f.conns).Close()
+
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done not closed after the connection was lost")
+	}
+	if _, ok := <-client.Events(); ok {
+		t.Error("Events not closed after the connection was lost")
+	}
+	if err := client.Request("GetVersion", nil, nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("request on a lost connection = %v, want ErrClosed", err)
+	}
+
+	client = dial(t, url, "")
+	if err := client.Request("GetVersion", nil, nil); err != nil {
+		t.Errorf("request after reconnecting: %v", err)
+	}
+}
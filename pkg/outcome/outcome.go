@@ -0,0 +1,51 @@
+// Package outcome shows the outcome of an action on its key, for actions
+// with an Output setting such as commands and webhooks.
+package outcome
+
+import (
+	"sd/pkg/store"
+	"sd/pkg/types"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Outputs of the Output setting.
+	Title = "title"
+	State = "state"
+
+	// States shown by Output "state".
+	Success = "0"
+	Failure = "1"
+
+	titleLength = 24
+)
+
+// Show draws the outcome of an action on its key as output selects: the
+// title returned by summary, or the Success or Failure state. Nothing is
+// drawn without a key or an output.
+func Show(buttonKey string, output string, err error, summary func() string) {
+	if buttonKey == "" {
+		return
+	}
+
+	var drawErr error
+	switch output {
+	case Title:
+		title := summary()
+		if runes := []rune(title); len(runes) > titleLength {
+			title = string(runes[:titleLength-3]) + "..."
+		}
+		drawErr = store.SetFeedback(buttonKey, &types.Feedback{Title: title})
+	case State:
+		state := Success
+		if err != nil {
+			state = Failure
+		}
+		drawErr = store.SetButtonState(buttonKey, state)
+	}
+
+	if drawErr != nil {
+		log.Error().Err(drawErr).Str("key", buttonKey).Msg("Failed to show outcome")
+	}
+}
@@ -0,0 +1,168 @@
+package audio
+
+import (
+	"encoding/json"
+	"fmt"
+	"sd/pkg/actions"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	actionVolumeUp   types.ActionType = "volume_up"
+	actionVolumeDown types.ActionType = "volume_down"
+	actionVolumeSet  types.ActionType = "volume_set"
+	actionMute       types.ActionType = "mute"
+	actionDefault    types.ActionType = "default"
+	actionDial       types.ActionType = "dial"
+)
+
+const (
+	defaultKeyStep  = 5
+	defaultDialStep = 2
+)
+
+func subject(t types.ActionType) string {
+	return "sd.plugin.audio." + string(t)
+}
+
+// target returns the target of the settings, sinks by default.
+func target(settings types.Settings) string {
+	if settings.AudioTarget == "" {
+		return targetSink
+	}
+	return settings.AudioTarget
+}
+
+// step returns the volume step of the settings in percent.
+func step(settings types.Settings, fallback int) int {
+	if settings.VolumeStep <= 0 {
+		return fallback
+	}
+	return settings.VolumeStep
+}
+
+// validate checks the settings of an action.
+func validate(t types.ActionType, settings types.Settings) error {
+	switch target(settings) {
+	case targetSink, targetSource:
+	case targetApp:
+		if settings.AudioDevice == "" {
+			return fmt.Errorf("application name is empty")
+		}
+		if t == actionDefault {
+			return fmt.Errorf("applications cannot be the default device")
+		}
+	default:
+		return fmt.Errorf("unknown audio target: %s", settings.AudioTarget)
+	}
+
+	switch t {
+	case actionVolumeUp, actionVolumeDown, actionMute, actionDial:
+	case actionVolumeSet:
+		if settings.Volume < 0 || settings.Volume > 150 {
+			return fmt.Errorf("volume must be between 0 and 150%%")
+		}
+	case actionDefault:
+		if settings.AudioDevice == "" {
+			return fmt.Errorf("device is empty")
+		}
+	default:
+		return fmt.Errorf("unknown audio action: %s", t)
+	}
+
+	return nil
+}
+
+// execute performs a key action with the settings of a button.
+func execute(t types.ActionType, settings types.Settings) error {
+	if err := validate(t, settings); err != nil {
+		return err
+	}
+
+	switch t {
+	case actionVolumeUp:
+		return changeVolume(target(settings), settings.AudioDevice, step(settings, defaultKeyStep))
+	case actionVolumeDown:
+		return changeVolume(target(settings), settings.AudioDevice, -step(settings, defaultKeyStep))
+	case actionVolumeSet:
+		return setVolume(target(settings), settings.AudioDevice, settings.Volume)
+	case actionMute:
+		return toggleMute(target(settings), settings.AudioDevice)
+	case actionDefault:
+		return setDefault(target(settings), settings.AudioDevice)
+	}
+
+	return fmt.Errorf("%s is bound to dials", t)
+}
+
+// turn changes the volume of a dial by its step per tick, and toggles mute
+// when the dial is pressed.
+func turn(settings types.Settings, input string, ticks int) error {
+	if err := validate(actionDial, settings); err != nil {
+		return err
+	}
+
+	switch input {
+	case actions.DialRotate:
+		return changeVolume(target(settings), settings.AudioDevice, ticks*step(settings, defaultDialStep))
+	case actions.DialPress:
+		return toggleMute(target(settings), settings.AudioDevice)
+	}
+
+	return nil
+}
+
+// SubscribeActions sets up the NATS subscriptions for the audio actions of
+// an instance.
+func SubscribeActions(instanceID string, feedback *feedback) {
+	nc, _ := natsconn.GetNATSConn()
+
+	for _, t := range []types.ActionType{actionVolumeUp, actionVolumeDown, actionVolumeSet, actionMute, actionDefault} {
+		t := t
+		if _, err := nc.Subscribe(subject(t), func(m *nats.Msg) {
+			if !actions.ForInstance(m, instanceID) {
+				return
+			}
+
+			var button types.Button
+			if err := json.Unmarshal(m.Data, &button); err != nil {
+				log.Error().Err(err).Msg("Failed to unmarshal button")
+				actions.Reply(m, err)
+				return
+			}
+
+			err := execute(t, button.Settings)
+			if err != nil {
+				log.Error().Err(err).Str("action", string(t)).Msg("Audio action failed")
+			}
+			feedback.trigger()
+			actions.Reply(m, err)
+		}); err != nil {
+			log.Error().Err(err).Str("action", string(t)).Msg("Failed to subscribe")
+		}
+	}
+
+	if _, err := nc.Subscribe(actions.DialSubject(subject(actionDial)), func(m *nats.Msg) {
+		if !actions.ForInstance(m, instanceID) {
+			return
+		}
+
+		var dial types.Button
+		if err := json.Unmarshal(m.Data, &dial); err != nil {
+			log.Error().Err(err).Msg("Failed to unmarshal dial")
+			return
+		}
+
+		input, ticks := actions.DialInput(m)
+		if err := turn(dial.Settings, input, ticks); err != nil {
+			log.Error().Err(err).Msg("Audio dial failed")
+		}
+		feedback.trigger()
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to subscribe to audio dials")
+	}
+}
@@ -0,0 +1,260 @@
+package audio
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"sd/pkg/bindings"
+	"sd/pkg/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	retryDelay = 30 * time.Second
+
+	// settleDelay groups the bursts of events pactl reports for one change.
+	settleDelay = 100 * time.Millisecond
+)
+
+// binding is a key or dial showing the state of a sink, source or stream.
+type binding struct {
+	action   types.ActionType
+	settings types.Settings
+}
+
+// snapshot is the state of the audio server.
+type snapshot struct {
+	nodes    map[string][]node // by target
+	defaults map[string]string // default sink and source names
+}
+
+// feedback draws the volume, mute and default state of audio devices on the
+// keys and dials bound to them.
+type feedback struct {
+	instanceID string
+	refresh    chan struct{}
+
+	mu       sync.Mutex
+	bindings map[string]binding
+	drawn    bindings.Drawn
+	state    *snapshot
+}
+
+func newFeedback(instanceID string) *feedback {
+	return &feedback{
+		instanceID: instanceID,
+		refresh:    make(chan struct{}, 1),
+		bindings:   make(map[string]binding),
+		drawn:      make(bindings.Drawn),
+	}
+}
+
+// trigger asks for the state of the audio server to be read again.
+func (f *feedback) trigger() {
+	select {
+	case f.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// nodes returns the nodes a binding refers to in the current state.
+func (f *feedback) nodes(b binding) []node {
+	t := target(b.settings)
+
+	name := b.settings.AudioDevice
+	if name == "" {
+		name = f.state.defaults[t]
+	}
+
+	return filter(t, f.state.nodes[t], name)
+}
+
+// draw updates a key or dial if its state changed. Callers hold f.mu.
+func (f *feedback) draw(key string) {
+	b, ok := f.bindings[key]
+	if !ok || f.state == nil {
+		return
+	}
+
+	nodes := f.nodes(b)
+
+	switch b.action {
+	case actionMute, actionDefault:
+		state := "0"
+		if len(nodes) > 0 {
+			if b.action == actionMute && nodes[0].Mute {
+				state = "1"
+			}
+			if b.action == actionDefault && nodes[0].Name == f.state.defaults[target(b.settings)] {
+				state = "1"
+			}
+		}
+
+		if err := f.drawn.State(key, state); err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to draw audio state")
+		}
+
+	default:
+		var fb *types.Feedback
+		if len(nodes) > 0 {
+			volume := nodes[0].percent()
+			fb = &types.Feedback{Title: fmt.Sprintf("%d%%", volume)}
+			if nodes[0].Mute {
+				fb.Title = "Muted"
+			}
+			if b.action == actionDial {
+				fb.Level = &volume
+			}
+		}
+
+		if err := f.drawn.Feedback(key, fb); err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to draw audio volume")
+		}
+	}
+}
+
+// read returns the current state of the audio server.
+func read() (*snapshot, error) {
+	s := &snapshot{
+		nodes:    make(map[string][]node),
+		defaults: make(map[string]string),
+	}
+
+	for _, t := range []string{targetSink, targetSource, targetApp} {
+		nodes, err := list(t)
+		if err != nil {
+			return nil, err
+		}
+		s.nodes[t] = nodes
+	}
+
+	for _, t := range []string{targetSink, targetSource} {
+		name, err := defaultName(t)
+		if err != nil {
+			return nil, err
+		}
+		s.defaults[t] = name
+	}
+
+	return s, nil
+}
+
+// run redraws the bound keys and dials whenever a refresh is triggered.
+func (f *feedback) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.refresh:
+		}
+
+		time.Sleep(settleDelay)
+
+		f.mu.Lock()
+		if len(f.bindings) == 0 {
+			f.mu.Unlock()
+			continue
+		}
+		f.mu.Unlock()
+
+		state, err := read()
+		if err != nil {
+			log.Debug().Err(err).Msg("Cannot read the audio state")
+			continue
+		}
+
+		f.mu.Lock()
+		f.state = state
+		for key := range f.bindings {
+			f.draw(key)
+		}
+		f.mu.Unlock()
+	}
+}
+
+// update tracks a button or dial key, drawing it when it is bound to an
+// audio action and clearing the feedback of keys that no longer are.
+func (f *feedback) update(key string, button types.Button, put bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	action, ok := strings.CutPrefix(button.UUID, "sd.plugin.audio.")
+	if !ok {
+		if previous, ok := f.bindings[key]; ok {
+			delete(f.bindings, key)
+			if previous.action == actionMute || previous.action == actionDefault {
+				f.drawn.Forget(key)
+			} else if err := f.drawn.Clear(key, put); err != nil {
+				log.Error().Err(err).Str("key", key).Msg("Failed to clear audio feedback")
+			}
+		}
+		return
+	}
+
+	b := binding{action: types.ActionType(action), settings: button.Settings}
+	if previous, ok := f.bindings[key]; !ok || previous.action != b.action ||
+		previous.settings.AudioTarget != b.settings.AudioTarget || previous.settings.AudioDevice != b.settings.AudioDevice {
+		f.drawn.Forget(key)
+	}
+	f.bindings[key] = b
+
+	if f.state == nil {
+		f.trigger()
+		return
+	}
+	f.draw(key)
+}
+
+// watchServer triggers a refresh whenever pactl reports a change of a sink,
+// source, stream or of the defaults.
+func (f *feedback) watchServer(ctx context.Context) {
+	for {
+		if err := f.followServer(ctx); err != nil {
+			log.Warn().Err(err).Msg("Cannot watch the audio server")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (f *feedback) followServer(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "pactl", "subscribe")
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start pactl subscribe: %w", err)
+	}
+
+	f.trigger()
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		// Such as: Event 'change' on sink-input #42
+		line := scanner.Text()
+		if strings.Contains(line, " on sink") || strings.Contains(line, " on source") || strings.Contains(line, " on server") {
+			f.trigger()
+		}
+	}
+
+	return cmd.Wait()
+}
+
+// start follows the audio server and the bindings of the instance.
+func (f *feedback) start(ctx context.Context) {
+	go f.run(ctx)
+	go f.watchServer(ctx)
+	go bindings.Watch(ctx, bindings.Buttons(f.instanceID), f.update)
+	go bindings.Watch(ctx, bindings.Dials(f.instanceID), f.update)
+}
@@ -0,0 +1,263 @@
+package audio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const pactlTimeout = 5 * time.Second
+
+// Targets of an audio action.
+const (
+	targetSink   = "sink"
+	targetSource = "source"
+	targetApp    = "app"
+)
+
+// node is a sink, source or application stream as listed by pactl.
+type node struct {
+	Index       int    `json:"index"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Mute        bool   `json:"mute"`
+	Volume      map[string]struct {
+		Value int `json:"value"`
+	} `json:"volume"`
+	Properties map[string]string `json:"properties"`
+}
+
+// percent returns the average volume of the channels of a node.
+func (n node) percent() int {
+	if len(n.Volume) == 0 {
+		return 0
+	}
+
+	total := 0
+	for _, channel := range n.Volume {
+		total += channel.Value
+	}
+
+	return int(math.Round(float64(total) / float64(len(n.Volume)) * 100 / 65536))
+}
+
+// id returns the argument pactl identifies a node of a target by.
+func (n node) id(target string) string {
+	if target == targetApp {
+		return strconv.Itoa(n.Index)
+	}
+	return n.Name
+}
+
+func pactl(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pactlTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "pactl", args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("pactl %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("pactl %s: %w", args[0], err)
+	}
+
+	return out, nil
+}
+
+// listCommand returns the pactl list type of a target.
+func listCommand(target string) string {
+	switch target {
+	case targetSource:
+		return "sources"
+	case targetApp:
+		return "sink-inputs"
+	default:
+		return "sinks"
+	}
+}
+
+// setCommand returns the pactl command setting a property, such as
+// "volume" or "mute", of a target.
+func setCommand(target string, property string) string {
+	switch target {
+	case targetSource:
+		return "set-source-" + property
+	case targetApp:
+		return "set-sink-input-" + property
+	default:
+		return "set-sink-" + property
+	}
+}
+
+func list(target string) ([]node, error) {
+	out, err := pactl("--format=json", "list", listCommand(target))
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []node
+	if err := json.Unmarshal(out, &nodes); err != nil {
+		return nil, fmt.Errorf("failed to parse pactl output: %w", err)
+	}
+
+	return nodes, nil
+}
+
+// defaultName returns the name of the default sink or source.
+func defaultName(target string) (string, error) {
+	command := "get-default-sink"
+	if target == targetSource {
+		command = "get-default-source"
+	}
+
+	out, err := pactl(command)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// matches reports whether a node is the device or application named by
+// name: sinks and sources by name or description, streams by application
+// name or binary.
+func matches(target string, n node, name string) bool {
+	if target == targetApp {
+		for _, property := range []string{"application.name", "application.process.binary"} {
+			if strings.EqualFold(n.Properties[property], name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return n.Name == name || strings.EqualFold(n.Description, name)
+}
+
+// find returns the nodes of a target named by name, or the default sink or
+// source when name is empty. Every stream of an application is returned.
+func find(target string, name string) ([]node, error) {
+	if name == "" {
+		if target == targetApp {
+			return nil, fmt.Errorf("application name is empty")
+		}
+
+		var err error
+		if name, err = defaultName(target); err != nil {
+			return nil, err
+		}
+	}
+
+	nodes, err := list(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return filter(target, nodes, name), nil
+}
+
+// filter returns the nodes of a target named by name.
+func filter(target string, nodes []node, name string) []node {
+	var found []node
+	for _, n := range nodes {
+		if matches(target, n, name) {
+			found = append(found, n)
+		}
+	}
+	return found
+}
+
+// changeVolume adds delta percent to the volume of the nodes of a target,
+// keeping it between 0 and 100%.
+func changeVolume(target string, name string, delta int) error {
+	nodes, err := find(target, name)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("%s not found: %s", target, name)
+	}
+
+	for _, n := range nodes {
+		volume := min(max(n.percent()+delta, 0), 100)
+		if _, err := pactl(setCommand(target, "volume"), n.id(target), fmt.Sprintf("%d%%", volume)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setVolume sets the volume of the nodes of a target in percent.
+func setVolume(target string, name string, volume int) error {
+	if volume < 0 || volume > 150 {
+		return fmt.Errorf("volume must be between 0 and 150%%")
+	}
+
+	nodes, err := find(target, name)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("%s not found: %s", target, name)
+	}
+
+	for _, n := range nodes {
+		if _, err := pactl(setCommand(target, "volume"), n.id(target), fmt.Sprintf("%d%%", volume)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// toggleMute mutes the nodes of a target, or unmutes them when the first
+// one is muted.
+func toggleMute(target string, name string) error {
+	nodes, err := find(target, name)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("%s not found: %s", target, name)
+	}
+
+	mute := "1"
+	if nodes[0].Mute {
+		mute = "0"
+	}
+
+	for _, n := range nodes {
+		if _, err := pactl(setCommand(target, "mute"), n.id(target), mute); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setDefault makes a sink or source the default.
+func setDefault(target string, name string) error {
+	if target == targetApp {
+		return fmt.Errorf("applications cannot be the default device")
+	}
+	if name == "" {
+		return fmt.Errorf("device is empty")
+	}
+
+	nodes, err := find(target, name)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("%s not found: %s", target, name)
+	}
+
+	_, err = pactl("set-default-"+target, nodes[0].Name)
+	return err
+}
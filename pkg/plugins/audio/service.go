@@ -0,0 +1,53 @@
+package audio
+
+import (
+	"context"
+	"encoding/json"
+	"sd/pkg/types"
+
+	"github.com/rs/zerolog/log"
+)
+
+// AudioPlugin controls PulseAudio and PipeWire sinks, sources and
+// application streams through pactl, and shows their state on the keys and
+// dials bound to them.
+type AudioPlugin struct {
+	InstanceID string
+}
+
+// Name returns the name of the plugin.
+func (p *AudioPlugin) Name() string {
+	return "audio"
+}
+
+// Init sets up the NATS subscriptions and the audio feedback.
+func (p *AudioPlugin) Init() {
+	f := newFeedback(p.InstanceID)
+	SubscribeActions(p.InstanceID, f)
+	f.start(context.Background())
+
+	log.Info().Msg("Audio plugin initialized")
+}
+
+// GetActionTypes implements actions.Plugin.
+func (p *AudioPlugin) GetActionTypes() []types.ActionType {
+	return []types.ActionType{actionVolumeUp, actionVolumeDown, actionVolumeSet, actionMute, actionDefault, actionDial}
+}
+
+// ValidateConfig implements actions.Plugin.
+func (p *AudioPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return validate(actionType, settings)
+}
+
+// ExecuteAction implements actions.Plugin.
+func (p *AudioPlugin) ExecuteAction(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return execute(actionType, settings)
+}
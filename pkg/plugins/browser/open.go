@@ -0,0 +1,200 @@
+package browser
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sd/pkg/focus"
+	"sd/pkg/types"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/browser"
+	"github.com/rs/zerolog/log"
+)
+
+type family int
+
+const (
+	familyOther family = iota
+	familyFirefox
+	familyChromium
+)
+
+// browserFamily guesses the command line flags a browser binary takes from
+// its name.
+func browserFamily(binary string) family {
+	name := strings.ToLower(filepath.Base(binary))
+
+	for _, n := range []string{"firefox", "librewolf", "waterfox", "floorp", "zen"} {
+		if strings.Contains(name, n) {
+			return familyFirefox
+		}
+	}
+	for _, n := range []string{"chrom", "brave", "vivaldi", "edge", "opera"} {
+		if strings.Contains(name, n) {
+			return familyChromium
+		}
+	}
+
+	return familyOther
+}
+
+// defaultBrowser returns the binary of the default browser as set by
+// xdg-settings, whose desktop file is usually named after it.
+func defaultBrowser() (string, error) {
+	out, err := exec.Command("xdg-settings", "get", "default-web-browser").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the default browser, set a browser: %w", err)
+	}
+
+	name := strings.TrimSuffix(strings.TrimSpace(string(out)), ".desktop")
+	binary, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("default browser %q not found, set a browser", name)
+	}
+
+	return binary, nil
+}
+
+// browserArgs returns the arguments opening a URL with the profile and
+// window settings of a button. The URL follows --, so that it is never
+// taken for a flag.
+func browserArgs(binary string, settings types.Settings, target string) ([]string, error) {
+	var args []string
+
+	switch browserFamily(binary) {
+	case familyFirefox:
+		if settings.BrowserProfile != "" {
+			args = append(args, "-P", settings.BrowserProfile)
+		}
+		switch {
+		case settings.Private:
+			args = append(args, "--private-window")
+		case settings.NewWindow:
+			args = append(args, "--new-window")
+		}
+	case familyChromium:
+		if settings.BrowserProfile != "" {
+			args = append(args, "--profile-directory="+settings.BrowserProfile)
+		}
+		if settings.Private {
+			args = append(args, "--incognito")
+		}
+		if settings.NewWindow {
+			args = append(args, "--new-window")
+		}
+	default:
+		if settings.BrowserProfile != "" || settings.Private || settings.NewWindow {
+			return nil, fmt.Errorf("unknown browser %s, profile and window options need Firefox or Chromium", binary)
+		}
+	}
+
+	return append(args, "--", target), nil
+}
+
+// windowMatcher matches the windows of the browser of a button whose title
+// matches its window expression. Only windows of the browser match, the
+// title alone could match any application.
+func windowMatcher(binary string, settings types.Settings) (focus.Matcher, error) {
+	if binary == "" {
+		return focus.Matcher{}, fmt.Errorf("focusing an existing window needs a browser")
+	}
+
+	class := "(?i)" + regexp.QuoteMeta(strings.TrimSuffix(filepath.Base(binary), "-stable"))
+	return focus.NewMatcher(class, settings.Window)
+}
+
+// checkBrowser checks that a browser setting names a Firefox or Chromium
+// based browser, as any program would otherwise be run with the URL.
+func checkBrowser(name string) error {
+	if name != "" && browserFamily(name) == familyOther {
+		return fmt.Errorf("unknown browser %s, use a Firefox or Chromium based browser", name)
+	}
+	return nil
+}
+
+// checkURL checks that an expanded URL is a web page or a local file.
+func checkURL(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "file":
+		return nil
+	}
+	return fmt.Errorf("URL must start with http://, https:// or file://")
+}
+
+// needsBinary reports whether the settings need a browser binary rather
+// than the default URL handler.
+func needsBinary(settings types.Settings) bool {
+	return settings.Browser != "" || settings.BrowserProfile != "" || settings.Private || settings.NewWindow
+}
+
+// open raises a window matching the settings if any, and opens the URL
+// otherwise.
+func open(settings types.Settings) error {
+	target, err := expandURL(settings.URL)
+	if err != nil {
+		return fmt.Errorf("failed to expand URL: %w", err)
+	}
+	if target == "" {
+		return fmt.Errorf("URL is empty")
+	}
+	if err := checkURL(target); err != nil {
+		return err
+	}
+	if err := checkBrowser(settings.Browser); err != nil {
+		return err
+	}
+
+	var binary string
+	switch {
+	case settings.Browser != "":
+		if binary, err = exec.LookPath(settings.Browser); err != nil {
+			return fmt.Errorf("browser %s not found", settings.Browser)
+		}
+	case needsBinary(settings) || settings.Window != "":
+		if binary, err = defaultBrowser(); err != nil {
+			return err
+		}
+	}
+
+	if settings.Window != "" {
+		m, err := windowMatcher(binary, settings)
+		if err != nil {
+			return err
+		}
+
+		raised, err := focus.Raise(m)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to look for an existing browser window")
+		}
+		if raised {
+			return nil
+		}
+	}
+
+	if binary == "" {
+		return browser.OpenURL(target)
+	}
+
+	args, err := browserArgs(binary, settings, target)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(binary, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", binary, err)
+	}
+	go cmd.Wait()
+
+	return nil
+}
@@ -2,19 +2,20 @@ package browser
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sd/pkg/actions"
+	"sd/pkg/focus"
+	"sd/pkg/multiaction"
 	"sd/pkg/natsconn"
 	"sd/pkg/types"
 
 	"github.com/nats-io/nats.go"
-	"github.com/pkg/browser"
 	"github.com/rs/zerolog/log"
 )
 
-type BrowserPlugin struct{}
-
-type OpenURLConfig struct {
-	URL string `json:"url"`
+type BrowserPlugin struct {
+	InstanceID string
 }
 
 func (b *BrowserPlugin) Name() string {
@@ -33,28 +34,55 @@ func (b *BrowserPlugin) GetActionTypes() []types.ActionType {
 }
 
 func (b *BrowserPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
-	var cfg OpenURLConfig
-	if err := json.Unmarshal(config, &cfg); err != nil {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
 		return err
 	}
-	if cfg.URL == "" {
+	return validate(settings)
+}
+
+// validate checks the settings of an open_url action.
+func validate(settings types.Settings) error {
+	if settings.URL == "" {
 		return fmt.Errorf("URL cannot be empty")
 	}
-	return nil
+	if err := checkBrowser(settings.Browser); err != nil {
+		return err
+	}
+	_, err := focus.NewMatcher("", settings.Window)
+	return err
 }
 
 func (b *BrowserPlugin) openSubscriber() {
 	nc, _ := natsconn.GetNATSConn()
 	nc.Subscribe("sd.plugin.browser.open_url", func(msg *nats.Msg) {
-		log.Info().Interface("msg", msg.Data).Msg("Browser plugin received message")
-		var action types.ActionInstance
-		if err := json.Unmarshal(msg.Data, &action); err != nil {
-			log.Error().Err(err).Msg("Failed to unmarshal action")
+		log.Info().Str("button", actions.ButtonKey(msg)).Msg("Browser plugin received message")
+
+		if !actions.ForInstance(msg, b.InstanceID) {
 			return
 		}
 
-		if url, ok := action.Settings.(map[string]interface{})["url"].(string); ok {
-			browser.OpenURL(url)
+		// The browser and URL are run as a command, only use stored buttons.
+		settings, err := multiaction.StoredSettings(b.InstanceID, msg)
+		if errors.Is(err, multiaction.ErrOtherInstance) {
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Refusing to open URL")
+			actions.Reply(msg, err)
+			return
+		}
+
+		if err := validate(settings); err != nil {
+			actions.Reply(msg, err)
+			return
+		}
+
+		if err := open(settings); err != nil {
+			log.Error().Err(err).Str("url", settings.URL).Msg("Failed to open URL")
+			actions.Reply(msg, err)
+			return
 		}
+		actions.Reply(msg, nil)
 	})
 }
@@ -0,0 +1,18 @@
+package browser
+
+import (
+	"net/url"
+	"sd/pkg/util"
+)
+
+// expandURL replaces the {date}, {time}, {clipboard} and {selection}
+// variables of a URL template. Values are query escaped, except at the start
+// of the template so that a copied or selected URL can be opened as is.
+func expandURL(template string) (string, error) {
+	return util.ExpandVariables(template, func(value string, offset int) string {
+		if offset == 0 {
+			return value
+		}
+		return url.QueryEscape(value)
+	})
+}
@@ -0,0 +1,186 @@
+package clipboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sd/pkg/actions"
+	"sd/pkg/env"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+	"sd/pkg/util"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	actionCopy    types.ActionType = "copy"
+	actionPaste   types.ActionType = "paste"
+	actionType    types.ActionType = "type"
+	actionHistory types.ActionType = "history"
+)
+
+var actionTypes = []types.ActionType{actionCopy, actionPaste, actionType, actionHistory}
+
+const (
+	defaultPasteKeys = "ctrl+v"
+
+	// pasteDelay lets the clipboard owner take over the new text before it
+	// is pasted.
+	pasteDelay = 100 * time.Millisecond
+
+	keyboardTimeout = 30 * time.Second
+)
+
+func subject(t types.ActionType) string {
+	return "sd.plugin.clipboard." + string(t)
+}
+
+// validate checks the settings of an action.
+func validate(t types.ActionType, settings types.Settings) error {
+	switch t {
+	case actionCopy, actionPaste, actionType:
+		if settings.Text == "" {
+			return fmt.Errorf("text is empty")
+		}
+	case actionHistory:
+		if settings.Slot < 1 || settings.Slot > historySize {
+			return fmt.Errorf("entry must be between 1 and %d", historySize)
+		}
+	default:
+		return fmt.Errorf("unknown clipboard action: %s", t)
+	}
+
+	return nil
+}
+
+// expand replaces the variables of a snippet: those of util.ExpandVariables,
+// {previous} for the clipboard entry before the current one and {env:NAME}
+// for the environment variables buttons may read.
+func (p *ClipboardPlugin) expand(text string) (string, error) {
+	return util.ExpandVariablesWith(text, func(name string) (string, bool) {
+		if name == "previous" {
+			p.poll()
+			previous, _ := p.history.get(2)
+			return previous, true
+		}
+		if key, ok := strings.CutPrefix(name, "env:"); ok {
+			return env.Button(key), true
+		}
+		return "", false
+	}, func(value string, offset int) string {
+		return value
+	})
+}
+
+// keyboard runs an action of the keyboard plugin, which types text and
+// presses keys with the backend it picked for the session. The message
+// carries the key that was pressed, so that only the keyboard plugin of its
+// instance types the text.
+func keyboard(buttonKey string, action string, settings types.Settings) error {
+	if buttonKey == "" {
+		return fmt.Errorf("clipboard actions only type from keys")
+	}
+
+	uuid := "sd.plugin.keyboard." + action
+
+	data, err := json.Marshal(types.Button{UUID: uuid, Settings: settings})
+	if err != nil {
+		return err
+	}
+
+	nc, _ := natsconn.GetNATSConn()
+
+	reply, err := nc.RequestMsg(actions.NewMsg(uuid, buttonKey, "", data), keyboardTimeout)
+	if errors.Is(err, nats.ErrNoResponders) {
+		return fmt.Errorf("the keyboard plugin is not running")
+	}
+	if err != nil {
+		return err
+	}
+
+	return actions.ResultError(reply.Data)
+}
+
+// copy puts text on the clipboard and, when keys are set, pastes it with the
+// keyboard of the instance of buttonKey.
+func (p *ClipboardPlugin) copy(buttonKey string, text string, keys string) error {
+	if err := util.WriteClipboard(text); err != nil {
+		return fmt.Errorf("failed to write clipboard: %w", err)
+	}
+	if p.history.add(text) {
+		p.feedback.drawAll()
+	}
+
+	if keys == "" {
+		return nil
+	}
+
+	time.Sleep(pasteDelay)
+	return keyboard(buttonKey, "hotkey", types.Settings{Keys: keys})
+}
+
+// execute runs an action of the key at buttonKey.
+func (p *ClipboardPlugin) execute(t types.ActionType, settings types.Settings, buttonKey string) error {
+	if err := validate(t, settings); err != nil {
+		return err
+	}
+
+	if t == actionHistory {
+		text, ok := p.history.get(settings.Slot)
+		if !ok {
+			return fmt.Errorf("clipboard history entry %d is empty", settings.Slot)
+		}
+		return p.copy(buttonKey, text, settings.Keys)
+	}
+
+	text, err := p.expand(settings.Text)
+	if err != nil {
+		return err
+	}
+
+	switch t {
+	case actionCopy:
+		return p.copy(buttonKey, text, "")
+	case actionPaste:
+		keys := settings.Keys
+		if keys == "" {
+			keys = defaultPasteKeys
+		}
+		return p.copy(buttonKey, text, keys)
+	}
+
+	return keyboard(buttonKey, "type", types.Settings{Text: text})
+}
+
+// subscribeActions sets up the NATS subscriptions of the plugin.
+func (p *ClipboardPlugin) subscribeActions() {
+	nc, _ := natsconn.GetNATSConn()
+
+	for _, t := range actionTypes {
+		t := t
+		if _, err := nc.Subscribe(subject(t), func(m *nats.Msg) {
+			if !actions.ForInstance(m, p.InstanceID) {
+				return
+			}
+
+			var button types.Button
+			if err := json.Unmarshal(m.Data, &button); err != nil {
+				log.Error().Err(err).Msg("Failed to unmarshal button")
+				actions.Reply(m, err)
+				return
+			}
+
+			err := p.execute(t, button.Settings, actions.ButtonKey(m))
+			if err != nil {
+				log.Error().Err(err).Str("action", string(t)).Msg("Clipboard action failed")
+			}
+			actions.Reply(m, err)
+		}); err != nil {
+			log.Error().Err(err).Str("action", string(t)).Msg("Failed to subscribe")
+		}
+	}
+}
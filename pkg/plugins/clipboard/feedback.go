@@ -0,0 +1,84 @@
+package clipboard
+
+import (
+	"sd/pkg/bindings"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// feedback shows the clipboard history across the keys bound to
+// sd.plugin.clipboard.history with previews on, one entry per key.
+type feedback struct {
+	instanceID string
+	history    *history
+
+	mu       sync.Mutex
+	bindings map[string]int // history slot by button key
+	drawn    bindings.Drawn
+}
+
+func newFeedback(instanceID string, h *history) *feedback {
+	return &feedback{
+		instanceID: instanceID,
+		history:    h,
+		bindings:   make(map[string]int),
+		drawn:      make(bindings.Drawn),
+	}
+}
+
+// draw shows the history entry of a slot on a key, or its configured title
+// while the slot is empty. The caller holds f.mu.
+func (f *feedback) draw(key string, slot int) {
+	text, ok := f.history.get(slot)
+
+	var fb *types.Feedback
+	if ok {
+		fb = &types.Feedback{Title: preview(text)}
+	}
+
+	if err := f.drawn.Feedback(key, fb); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to draw clipboard entry")
+	}
+}
+
+// drawAll redraws the history keys after the history changed.
+func (f *feedback) drawAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, slot := range f.bindings {
+		f.draw(key, slot)
+	}
+}
+
+// update follows a button key being bound, changed or unbound.
+func (f *feedback) update(key string, button types.Button, put bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Previews are saved with the key feedback, so only keys that opt in
+	// show their entry.
+	if button.UUID == subject(actionHistory) && button.Settings.Preview && validate(actionHistory, button.Settings) == nil {
+		if f.bindings[key] != button.Settings.Slot {
+			f.drawn.Forget(key)
+		}
+		f.bindings[key] = button.Settings.Slot
+		f.draw(key, button.Settings.Slot)
+		return
+	}
+
+	if _, bound := f.bindings[key]; !bound {
+		// A preview saved before the key opted out is removed too.
+		if button.UUID != subject(actionHistory) || store.GetFeedback(key) == nil {
+			return
+		}
+	}
+	delete(f.bindings, key)
+
+	if err := f.drawn.Clear(key, put); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to clear clipboard entry")
+	}
+}
@@ -0,0 +1,85 @@
+package clipboard
+
+import (
+	"strings"
+	"sync"
+)
+
+const (
+	// historySize is the number of clipboard entries kept.
+	historySize = 32
+
+	// Previews of entries are wrapped to previewLines of previewWidth runes.
+	previewWidth = 9
+	previewLines = 3
+)
+
+// history is the ring of recent clipboard texts, newest first. It is kept in
+// memory only since the clipboard often holds passwords. Entries only reach
+// the store as previews of keys that opt in to them.
+type history struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+// add puts text at the front of the history, moving it there when it was
+// already in it. Texts are trimmed like the clipboard is read. It reports
+// whether the history changed.
+func (h *history) add(text string) bool {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.entries) > 0 && h.entries[0] == text {
+		return false
+	}
+
+	for i, entry := range h.entries {
+		if entry == text {
+			h.entries = append(h.entries[:i], h.entries[i+1:]...)
+			break
+		}
+	}
+
+	h.entries = append([]string{text}, h.entries...)
+	if len(h.entries) > historySize {
+		h.entries = h.entries[:historySize]
+	}
+
+	return true
+}
+
+// get returns the entry of a 1-based slot.
+func (h *history) get(slot int) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if slot < 1 || slot > len(h.entries) {
+		return "", false
+	}
+	return h.entries[slot-1], true
+}
+
+// preview returns text wrapped to fit a key, on one line per previewWidth
+// runes with whitespace collapsed.
+func preview(text string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+
+	var lines []string
+	for len(runes) > 0 && len(lines) < previewLines {
+		n := min(len(runes), previewWidth)
+		lines = append(lines, strings.TrimSpace(string(runes[:n])))
+		runes = runes[n:]
+	}
+
+	if len(runes) > 0 {
+		last := []rune(lines[len(lines)-1])
+		lines[len(lines)-1] = string(last[:min(len(last), previewWidth-2)]) + ".."
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,105 @@
+package clipboard
+
+import (
+	"context"
+	"encoding/json"
+	"sd/pkg/bindings"
+	"sd/pkg/types"
+	"sd/pkg/util"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// pollInterval is how often the clipboard is read to follow its history.
+const pollInterval = time.Second
+
+// ClipboardPlugin copies and pastes text snippets, types templated text and
+// keeps a history of the clipboard shown across a page of keys.
+type ClipboardPlugin struct {
+	InstanceID string
+
+	history  *history
+	feedback *feedback
+
+	pollMu  sync.Mutex
+	failing bool // reading the clipboard failed at the last poll
+}
+
+// Name returns the name of the plugin.
+func (p *ClipboardPlugin) Name() string {
+	return "clipboard"
+}
+
+// Init sets up the NATS subscriptions and starts following the clipboard.
+func (p *ClipboardPlugin) Init() {
+	p.history = &history{}
+	p.feedback = newFeedback(p.InstanceID, p.history)
+
+	p.subscribeActions()
+	go bindings.Watch(context.Background(), bindings.Buttons(p.InstanceID), p.feedback.update)
+	go p.follow(context.Background())
+
+	log.Info().Msg("Clipboard plugin initialized")
+}
+
+// poll adds the text of the clipboard to the history.
+func (p *ClipboardPlugin) poll() {
+	p.pollMu.Lock()
+	defer p.pollMu.Unlock()
+
+	text, err := util.ReadClipboard(false)
+	if err != nil {
+		if !p.failing {
+			log.Warn().Err(err).Msg("Cannot read clipboard")
+		}
+		p.failing = true
+		return
+	}
+	p.failing = false
+
+	if p.history.add(text) {
+		p.feedback.drawAll()
+	}
+}
+
+// follow polls the clipboard until ctx is done.
+func (p *ClipboardPlugin) follow(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		p.poll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetActionTypes implements actions.Plugin.
+func (p *ClipboardPlugin) GetActionTypes() []types.ActionType {
+	return actionTypes
+}
+
+// ValidateConfig implements actions.Plugin.
+func (p *ClipboardPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return validate(actionType, settings)
+}
+
+// ExecuteAction implements actions.Plugin. Without a key, text is copied
+// but never typed or pasted.
+func (p *ClipboardPlugin) ExecuteAction(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return p.execute(actionType, settings, "")
+}
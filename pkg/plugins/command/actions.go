@@ -1,86 +1,240 @@
 package command
 
 import (
-	"encoding/json"
-	"os/exec"
+	"context"
+	"errors"
+	"fmt"
+	"sd/pkg/actions"
 	"sd/pkg/env"
+	"sd/pkg/multiaction"
 	"sd/pkg/natsconn"
+	"sd/pkg/outcome"
+	"sd/pkg/store"
 	"sd/pkg/types"
 	"sd/pkg/util"
-	"syscall"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/rs/zerolog/log"
 )
 
-type Settings struct {
-	Command string `json:"command"`
+const (
+	actionExec   = "sd.plugin.command.exec"
+	actionToggle = "sd.plugin.command.toggle"
+)
+
+var (
+	processesMu sync.Mutex
+	processes   = make(map[string]context.CancelFunc) // toggled processes by processKey
+)
+
+// processKey returns the key a toggled process is tracked by: the key of its
+// button, or of the step running it.
+func processKey(m *nats.Msg) string {
+	if step := m.Header.Get(actions.StepHeader); step != "" {
+		return actions.ButtonKey(m) + "." + step
+	}
+	return actions.ButtonKey(m)
 }
 
-// Subscribe sets up the NATS subscription for this plugin.
-func OpenSubscriber() {
-	root, err := util.GetProjectRoot()
+// run runs a command and shows its outcome on buttonKey, if any. Without a
+// timeout or an outcome to show it is left running on its own once started.
+func run(buttonKey string, settings types.Settings) error {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if settings.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(settings.Timeout)*time.Second)
+	}
 
+	cmd, err := newCommand(ctx, settings)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get project root")
-		return
+		cancel()
+		return err
 	}
 
-	env.LoadEnv(root + "/pkg/plugins/.env")
-
-	nc, _ := natsconn.GetNATSConn()
+	output := &limitedBuffer{}
+	if settings.Output == outcome.Title {
+		cmd.Stdout = output
+	}
 
-	if _, err := nc.Subscribe("sd.plugin.command.exec", func(m *nats.Msg) {
-		var actionInstance types.ActionInstance
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return fmt.Errorf("cannot start command: %w", err)
+	}
 
-		// Parse the incoming message
-		if err := json.Unmarshal(m.Data, &actionInstance); err != nil {
-			log.Error().Err(err).Msg("Error unmarshaling JSON")
-			return
+	wait := func() error {
+		defer cancel()
+		err := cmd.Wait()
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("command timed out after %ds", settings.Timeout)
 		}
+		return err
+	}
 
-		// Convert actionInstance.Settings to Settings
-		settingsMap, ok := actionInstance.Settings.(map[string]any)
+	if settings.Timeout == 0 && settings.Output != outcome.Title && settings.Output != outcome.State {
+		go wait()
+		return nil
+	}
 
-		if !ok {
-			log.Error().Msg("Settings is not a valid object")
-			return
-		}
+	err = wait()
+	outcome.Show(buttonKey, settings.Output, err, func() string { return summary(output.String(), err) })
 
-		settingsBytes, err := json.Marshal(settingsMap)
+	return err
+}
 
-		if err != nil {
-			log.Error().Err(err).Msg("Error marshaling settings to JSON")
-			return
-		}
+// toggle starts the process tracked by key, or stops it when it is still
+// running. The button at buttonKey, if any, shows state 1 while the process
+// runs.
+func toggle(key string, buttonKey string, settings types.Settings) error {
+	processesMu.Lock()
+	defer processesMu.Unlock()
 
-		var settings Settings
+	if stop, ok := processes[key]; ok {
+		stop()
+		return nil
+	}
 
-		if err := json.Unmarshal(settingsBytes, &settings); err != nil {
-			log.Error().Err(err).Msg("Error unmarshaling settings to Settings")
-			return
-		}
+	ctx, cancel := context.WithCancel(context.Background())
 
-		// Validate URL
-		if settings.Command == "" {
-			log.Error().Msg("Command is empty")
-			return
-		}
+	cmd, err := newCommand(ctx, settings)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return fmt.Errorf("cannot start command: %w", err)
+	}
+
+	processes[key] = cancel
+	setState(buttonKey, stateRunning)
 
-		cmd := exec.Command("sh", "-c", settings.Command)
+	go func() {
+		err := cmd.Wait()
+		cancel()
 
-		// Detach the process
-		cmd.SysProcAttr = &syscall.SysProcAttr{
-			Setsid: true, // Create a new session
+		processesMu.Lock()
+		delete(processes, key)
+		processesMu.Unlock()
+
+		log.Info().Err(err).Str("command", settings.Command).Msg("Toggled process exited")
+		setState(buttonKey, stateStopped)
+	}()
+
+	return nil
+}
+
+func setState(buttonKey string, state string) {
+	if buttonKey == "" {
+		return
+	}
+	if err := store.SetButtonState(buttonKey, state); err != nil {
+		log.Error().Err(err).Str("key", buttonKey).Msg("Failed to update process state")
+	}
+}
+
+// resetToggles shows every toggled process as stopped, as processes started
+// before a restart are no longer tracked.
+func resetToggles() {
+	_, kv := natsconn.GetNATSConn()
+
+	lister, err := kv.ListKeys()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list keys")
+		return
+	}
+
+	for key := range lister.Keys() {
+		segments := strings.Split(key, ".")
+		if len(segments) != 10 || segments[8] != "buttons" {
+			continue
 		}
 
-		// Start the command
-		if err := cmd.Start(); err != nil {
-			log.Error().Err(err).Msg("Cannot start command")
-			return
+		button, err := store.GetButton(key)
+		if err != nil || button.UUID != actionToggle || button.State == stateStopped {
+			continue
 		}
 
-	}); err != nil {
-		log.Fatal().Err(err).Msg("Failed to subscribe to sd.plugin.command.exec")
+		setState(key, stateStopped)
+	}
+}
+
+// running reports whether the toggled process tracked by key is running.
+func running(key string) bool {
+	processesMu.Lock()
+	defer processesMu.Unlock()
+	_, ok := processes[key]
+	return ok
+}
+
+// OpenSubscriber sets up the NATS subscriptions for this plugin.
+func OpenSubscriber(instanceID string) {
+	root, err := util.GetProjectRoot()
+
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get project root")
+		return
+	}
+
+	env.LoadEnv(root + "/pkg/plugins/.env")
+
+	resetToggles()
+
+	nc, _ := natsconn.GetNATSConn()
+
+	for _, subject := range []string{actionExec, actionToggle} {
+		if _, err := nc.Subscribe(subject, func(m *nats.Msg) {
+			settings, err := multiaction.StoredSettings(instanceID, m)
+			if errors.Is(err, multiaction.ErrOtherInstance) {
+				return
+			}
+
+			key := actions.ButtonKey(m)
+			entry := auditEntry{
+				Action:    m.Subject,
+				ButtonKey: key,
+				Step:      m.Header.Get(actions.StepHeader),
+				Command:   settings.Command,
+				WorkDir:   settings.WorkDir,
+				Outcome:   "started",
+			}
+
+			stopping := m.Subject == actionToggle && running(processKey(m))
+			if stopping {
+				entry.Outcome = "stopped"
+			} else if err == nil {
+				err = checkAllowlist(instanceID, settings.Command)
+			}
+
+			if err != nil {
+				entry.Outcome = "rejected"
+				entry.Error = err.Error()
+				audit(entry)
+				actions.Reply(m, err)
+				return
+			}
+
+			audit(entry)
+
+			// Commands may run for a while, do not hold up other presses.
+			go func() {
+				var err error
+				if m.Subject == actionToggle {
+					err = toggle(processKey(m), actions.OwnKey(m), settings)
+				} else {
+					err = run(actions.OwnKey(m), settings)
+				}
+				if err != nil {
+					entry.Outcome = "failed"
+					entry.Error = err.Error()
+					audit(entry)
+				}
+				actions.Reply(m, err)
+			}()
+		}); err != nil {
+			log.Fatal().Err(err).Msgf("Failed to subscribe to %s", subject)
+		}
 	}
 }
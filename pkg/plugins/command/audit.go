@@ -0,0 +1,95 @@
+package command
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sd/pkg/actions"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// auditEntry is one line of the audit log.
+type auditEntry struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	ButtonKey string    `json:"buttonKey,omitempty"`
+	Step      string    `json:"step,omitempty"`
+	Command   string    `json:"command,omitempty"`
+	WorkDir   string    `json:"workDir,omitempty"`
+	Outcome   string    `json:"outcome"` // started, stopped, rejected or failed
+	Error     string    `json:"error,omitempty"`
+}
+
+var auditMu sync.Mutex
+
+// auditPath returns the audit log, next to the instance ID.
+func auditPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config/sd", "command-audit.log"), nil
+}
+
+// audit appends an entry to the audit log, one JSON object per line.
+func audit(entry auditEntry) {
+	entry.Time = time.Now()
+
+	event := log.Info()
+	if entry.Outcome == "rejected" || entry.Outcome == "failed" {
+		event = log.Warn()
+	}
+	event.Str("action", entry.Action).Str("key", entry.ButtonKey).Str("command", entry.Command).
+		Str("outcome", entry.Outcome).Str("error", entry.Error).Msg("Command audit")
+
+	path, err := auditPath()
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot locate the command audit log")
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Error().Err(err).Msg("Failed to create the command audit log directory")
+		return
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to open the command audit log")
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		log.Error().Err(err).Msg("Failed to write the command audit log")
+	}
+}
+
+// Audit records a command another plugin runs for an action message, such as
+// the command of a window launch action, in the same audit log. The outcome
+// is started, rejected or failed.
+func Audit(m *nats.Msg, command string, outcome string, err error) {
+	entry := auditEntry{
+		Action:    m.Subject,
+		ButtonKey: actions.ButtonKey(m),
+		Step:      m.Header.Get(actions.StepHeader),
+		Command:   command,
+		Outcome:   outcome,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	audit(entry)
+}
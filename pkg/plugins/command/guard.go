@@ -0,0 +1,18 @@
+package command
+
+import (
+	"fmt"
+	"sd/pkg/store"
+)
+
+// checkAllowlist rejects commands missing from the allowlist of the instance.
+func checkAllowlist(instanceID string, command string) error {
+	allowed, err := store.CommandAllowed(instanceID, command)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("command is not in the allowlist")
+	}
+	return nil
+}
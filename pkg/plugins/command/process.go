@@ -0,0 +1,155 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sd/pkg/env"
+	"sd/pkg/types"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// States shown by toggled processes.
+	stateStopped = "0"
+	stateRunning = "1"
+
+	stopDelay   = 3 * time.Second
+	outputLimit = 4096
+)
+
+// terminals are tried in order when SD_TERMINAL and TERMINAL are unset,
+// with the arguments that make them run a command.
+var terminals = []struct {
+	name string
+	args []string
+}{
+	{"ghostty", []string{"-e"}},
+	{"kitty", nil},
+	{"alacritty", []string{"-e"}},
+	{"foot", nil},
+	{"wezterm", []string{"start", "--"}},
+	{"gnome-terminal", []string{"--"}},
+	{"konsole", []string{"-e"}},
+	{"xterm", []string{"-e"}},
+}
+
+// terminalCommand returns the command line running args in a terminal.
+func terminalCommand(args []string) ([]string, error) {
+	name := env.Get("SD_TERMINAL", os.Getenv("TERMINAL"))
+	if name != "" {
+		for _, t := range terminals {
+			if filepath.Base(name) == t.name {
+				return append(append([]string{name}, t.args...), args...), nil
+			}
+		}
+		return append([]string{name, "-e"}, args...), nil
+	}
+
+	for _, t := range terminals {
+		if path, err := exec.LookPath(t.name); err == nil {
+			return append(append([]string{path}, t.args...), args...), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no terminal emulator found, set SD_TERMINAL")
+}
+
+// parseEnv parses KEY=value lines.
+func parseEnv(s string) ([]string, error) {
+	var vars []string
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, _, ok := strings.Cut(line, "=")
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("environment line %d is not KEY=value", i+1)
+		}
+		vars = append(vars, line)
+	}
+	return vars, nil
+}
+
+// expandHome replaces a leading ~ with the home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
+
+// newCommand builds the command of a button. Commands run in their own
+// session so they outlive the server and can be stopped as a group.
+func newCommand(ctx context.Context, settings types.Settings) (*exec.Cmd, error) {
+	if settings.Command == "" {
+		return nil, fmt.Errorf("command is empty")
+	}
+
+	vars, err := parseEnv(settings.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"sh", "-c", settings.Command}
+	if settings.Terminal {
+		if args, err = terminalCommand(args); err != nil {
+			return nil, err
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = expandHome(settings.WorkDir)
+	cmd.Env = append(os.Environ(), vars...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = stopDelay
+
+	return cmd, nil
+}
+
+// limitedBuffer keeps the last outputLimit bytes written to it.
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.Buffer.Write(p)
+	if over := b.Len() - outputLimit; over > 0 {
+		b.Next(over)
+	}
+	return n, nil
+}
+
+// summary returns the title shown for the outcome of a command: the last
+// line of its output, or its exit status when it printed nothing.
+func summary(output string, err error) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	title := strings.TrimSpace(lines[len(lines)-1])
+
+	if title == "" {
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			title = "OK"
+		case errors.As(err, &exitErr):
+			title = fmt.Sprintf("exit %d", exitErr.ExitCode())
+		default:
+			title = "failed"
+		}
+	}
+
+	return title
+}
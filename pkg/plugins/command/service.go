@@ -1,26 +1,41 @@
 package command
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"sd/pkg/types"
 )
 
 // CommandPlugin represents the command plugin.
-type CommandPlugin struct{}
+type CommandPlugin struct {
+	InstanceID string
+}
 
-// ExecuteAction implements actions.Plugin.
+// ExecuteAction implements actions.Plugin. Commands only run for buttons
+// stored on this instance, never from a free-form configuration.
 func (c *CommandPlugin) ExecuteAction(actionType types.ActionType, config json.RawMessage) error {
-	panic("unimplemented")
+	return fmt.Errorf("commands only run from stored buttons")
 }
 
 // GetActionTypes implements actions.Plugin.
 func (c *CommandPlugin) GetActionTypes() []types.ActionType {
-	panic("unimplemented")
+	return []types.ActionType{"exec", "toggle"}
 }
 
 // ValidateConfig implements actions.Plugin.
 func (c *CommandPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
-	panic("unimplemented")
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+
+	if settings.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+
+	_, err := newCommand(context.Background(), settings)
+	return err
 }
 
 // Name returns the name of the plugin.
@@ -30,5 +45,5 @@ func (c *CommandPlugin) Name() string {
 
 // Subscribe sets up the NATS subscription for this plugin.
 func (c *CommandPlugin) Init() {
-	OpenSubscriber()
+	OpenSubscriber(c.InstanceID)
 }
@@ -0,0 +1,117 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sd/pkg/actions"
+	"sd/pkg/hass"
+	"sd/pkg/multiaction"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	actionToggle  types.ActionType = "toggle"
+	actionService types.ActionType = "service"
+	actionState   types.ActionType = "state"
+)
+
+var actionTypes = []types.ActionType{actionToggle, actionService, actionState}
+
+func subject(t types.ActionType) string {
+	return "sd.plugin.homeassistant." + string(t)
+}
+
+// serviceData decodes the service data of the settings.
+func serviceData(settings types.Settings) (map[string]any, error) {
+	if strings.TrimSpace(settings.ServiceData) == "" {
+		return nil, nil
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal([]byte(settings.ServiceData), &data); err != nil {
+		return nil, fmt.Errorf("service data is not a JSON object: %w", err)
+	}
+	return data, nil
+}
+
+// validate checks the settings of an action.
+func validate(t types.ActionType, settings types.Settings) error {
+	switch t {
+	case actionToggle, actionState:
+		if settings.Entity == "" {
+			return fmt.Errorf("entity is empty")
+		}
+	case actionService:
+		domain, name, ok := strings.Cut(settings.Service, ".")
+		if !ok || domain == "" || name == "" {
+			return fmt.Errorf("service must be domain.service, such as light.turn_on")
+		}
+		if _, err := serviceData(settings); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown Home Assistant action: %s", t)
+	}
+
+	return nil
+}
+
+// execute performs an action with the settings of a button.
+func execute(c *hass.Client, t types.ActionType, settings types.Settings) error {
+	if err := validate(t, settings); err != nil {
+		return err
+	}
+
+	switch t {
+	case actionToggle:
+		return c.CallService("homeassistant.toggle", settings.Entity, nil)
+	case actionService:
+		data, _ := serviceData(settings)
+		return c.CallService(settings.Service, settings.Entity, data)
+	case actionState:
+		// The state is shown by the feedback, pressing the key polls it.
+		return c.CallService("homeassistant.update_entity", settings.Entity, nil)
+	}
+
+	return nil
+}
+
+// subscribeActions sets up the NATS subscriptions for the Home Assistant
+// actions of an instance.
+func subscribeActions(instanceID string, s *session) {
+	nc, _ := natsconn.GetNATSConn()
+
+	for _, t := range actionTypes {
+		t := t
+		if _, err := nc.Subscribe(subject(t), func(m *nats.Msg) {
+			// Services may unlock doors or open garages, so only actions
+			// saved on a button or schedule of this instance are run.
+			settings, err := multiaction.StoredSettings(instanceID, m)
+			if errors.Is(err, multiaction.ErrOtherInstance) {
+				return
+			}
+			if err != nil {
+				log.Error().Err(err).Str("action", string(t)).Msg("Home Assistant action rejected")
+				actions.Reply(m, err)
+				return
+			}
+
+			c, err := s.get()
+			if err == nil {
+				err = execute(c, t, settings)
+			}
+			if err != nil {
+				log.Error().Err(err).Str("action", string(t)).Msg("Home Assistant action failed")
+			}
+			actions.Reply(m, err)
+		}); err != nil {
+			log.Error().Err(err).Str("action", string(t)).Msg("Failed to subscribe")
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"sd/pkg/bindings"
+	"sd/pkg/hass"
+	"sd/pkg/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const reconnectDelay = 10 * time.Second
+
+// binding is a key showing the state of an entity.
+type binding struct {
+	action types.ActionType
+	entity string
+}
+
+// feedback draws the state of Home Assistant entities on the keys bound to
+// them, switching toggle keys between their states and showing the state of
+// other entities, such as a temperature, as their title.
+type feedback struct {
+	instanceID string
+	session    *session
+
+	mu        sync.Mutex
+	connected bool
+	bindings  map[string]binding
+	drawn     bindings.Drawn
+	states    map[string]hass.State // by entity
+}
+
+func newFeedback(instanceID string, s *session) *feedback {
+	return &feedback{
+		instanceID: instanceID,
+		session:    s,
+		bindings:   make(map[string]binding),
+		drawn:      make(bindings.Drawn),
+		states:     make(map[string]hass.State),
+	}
+}
+
+// active reports whether an entity state counts as on for toggle keys.
+func active(state string) bool {
+	switch state {
+	case "on", "open", "playing", "home", "unlocked":
+		return true
+	}
+	return false
+}
+
+// title returns the state of an entity as shown on a key, such as 21.5 °C.
+func title(state hass.State) string {
+	if unit := state.Unit(); unit != "" {
+		return state.State + " " + unit
+	}
+	return state.State
+}
+
+// draw updates a key if its state changed. Callers hold f.mu.
+func (f *feedback) draw(key string) {
+	b, ok := f.bindings[key]
+	if !ok {
+		return
+	}
+
+	state, known := f.states[b.entity]
+	known = known && f.connected
+
+	switch b.action {
+	case actionToggle:
+		if !known {
+			return
+		}
+
+		s := "0"
+		if active(state.State) {
+			s = "1"
+		}
+		if err := f.drawn.State(key, s); err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to draw entity state")
+		}
+
+	case actionState:
+		var fb *types.Feedback
+		if known {
+			fb = &types.Feedback{Title: title(state)}
+		}
+		if err := f.drawn.Feedback(key, fb); err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to draw entity state")
+		}
+	}
+}
+
+// drawEntity updates the keys bound to an entity. Callers hold f.mu.
+func (f *feedback) drawEntity(entity string) {
+	for key, b := range f.bindings {
+		if b.entity == entity {
+			f.draw(key)
+		}
+	}
+}
+
+func (f *feedback) drawAll() {
+	for key := range f.bindings {
+		f.draw(key)
+	}
+}
+
+// follow reads the state of every entity, then applies state changes until
+// the connection is lost.
+func (f *feedback) follow(ctx context.Context, client *hass.Client) error {
+	if err := client.Subscribe(hass.EventStateChanged); err != nil {
+		return err
+	}
+	states, err := client.States()
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.connected = true
+	f.states = make(map[string]hass.State, len(states))
+	for _, state := range states {
+		f.states[state.EntityID] = state
+	}
+	f.drawAll()
+	f.mu.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-client.Events():
+			if !ok {
+				return hass.ErrClosed
+			}
+			if event.Type != hass.EventStateChanged {
+				continue
+			}
+
+			var change hass.StateChange
+			if err := json.Unmarshal(event.Data, &change); err != nil {
+				continue
+			}
+
+			f.mu.Lock()
+			if change.NewState == nil {
+				delete(f.states, change.EntityID)
+			} else {
+				f.states[change.EntityID] = *change.NewState
+			}
+			f.drawEntity(change.EntityID)
+			f.mu.Unlock()
+		}
+	}
+}
+
+// run connects to Home Assistant, follows the state of its entities and
+// clears the state keys while it is unreachable.
+func (f *feedback) run(ctx context.Context) {
+	for {
+		client, err := f.session.get()
+		if err == nil {
+			log.Info().Msg("Connected to Home Assistant")
+			err = f.follow(ctx, client)
+		}
+		if err != nil {
+			log.Debug().Err(err).Msg("Cannot follow Home Assistant")
+		}
+
+		f.mu.Lock()
+		f.connected = false
+		f.drawAll()
+		f.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.session.connected:
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// update tracks a button key, drawing it when it is bound to an entity and
+// clearing the feedback of keys that no longer are.
+func (f *feedback) update(key string, button types.Button, put bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	action, ok := strings.CutPrefix(button.UUID, "sd.plugin.homeassistant.")
+	t := types.ActionType(action)
+	if !ok || (t != actionToggle && t != actionState) {
+		if previous, ok := f.bindings[key]; ok {
+			delete(f.bindings, key)
+			if previous.action != actionState {
+				f.drawn.Forget(key)
+			} else if err := f.drawn.Clear(key, put); err != nil {
+				log.Error().Err(err).Str("key", key).Msg("Failed to clear entity state")
+			}
+		}
+		return
+	}
+
+	b := binding{action: t, entity: button.Settings.Entity}
+	if previous, ok := f.bindings[key]; !ok || previous != b {
+		f.drawn.Forget(key)
+	}
+	f.bindings[key] = b
+
+	f.draw(key)
+}
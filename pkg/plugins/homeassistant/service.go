@@ -0,0 +1,56 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sd/pkg/bindings"
+	"sd/pkg/types"
+
+	"github.com/rs/zerolog/log"
+)
+
+// HomeAssistantPlugin toggles entities and calls services over the Home
+// Assistant WebSocket API, and shows the state of entities on the keys
+// bound to them.
+type HomeAssistantPlugin struct {
+	InstanceID string
+
+	session *session
+}
+
+// Name returns the name of the plugin.
+func (p *HomeAssistantPlugin) Name() string {
+	return "homeassistant"
+}
+
+// Init sets up the NATS subscriptions and the entity feedback.
+func (p *HomeAssistantPlugin) Init() {
+	p.session = newSession()
+	subscribeActions(p.InstanceID, p.session)
+
+	f := newFeedback(p.InstanceID, p.session)
+	go bindings.Watch(context.Background(), bindings.Buttons(p.InstanceID), f.update)
+	go f.run(context.Background())
+
+	log.Info().Msg("Home Assistant plugin initialized")
+}
+
+// GetActionTypes implements actions.Plugin.
+func (p *HomeAssistantPlugin) GetActionTypes() []types.ActionType {
+	return actionTypes
+}
+
+// ValidateConfig implements actions.Plugin.
+func (p *HomeAssistantPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return validate(actionType, settings)
+}
+
+// ExecuteAction implements actions.Plugin.
+func (p *HomeAssistantPlugin) ExecuteAction(actionType types.ActionType, config json.RawMessage) error {
+	return fmt.Errorf("Home Assistant actions only run from stored buttons")
+}
@@ -0,0 +1,73 @@
+package homeassistant
+
+import (
+	"sd/pkg/env"
+	"sd/pkg/hass"
+	"sync"
+)
+
+// Environment variables locating Home Assistant. Point SD_HASS_URL at a
+// local fake server to try the plugin without Home Assistant.
+const (
+	urlEnv     = "SD_HASS_URL"
+	tokenEnv   = "SD_HASS_TOKEN" // long-lived access token
+	defaultURL = "ws://localhost:8123/api/websocket"
+)
+
+// session shares one Home Assistant connection between the actions and the
+// feedback, connecting on demand.
+type session struct {
+	mu        sync.Mutex
+	client    *hass.Client
+	connected chan struct{}
+}
+
+func newSession() *session {
+	return &session{connected: make(chan struct{}, 1)}
+}
+
+// current returns the connection unless it was lost. Callers hold s.mu.
+func (s *session) current() *hass.Client {
+	if s.client != nil {
+		select {
+		case <-s.client.Done():
+			s.client = nil
+		default:
+		}
+	}
+	return s.client
+}
+
+// get returns the current connection, connecting when there is none. The
+// lock is not held while dialling, so that a slow connection attempt does
+// not hold up actions.
+func (s *session) get() (*hass.Client, error) {
+	s.mu.Lock()
+	client := s.current()
+	s.mu.Unlock()
+	if client != nil {
+		return client, nil
+	}
+
+	client, err := hass.Dial(env.Get(urlEnv, defaultURL), env.Get(tokenEnv, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current := s.current(); current != nil {
+		// Another caller connected meanwhile.
+		client.Close()
+		return current, nil
+	}
+	s.client = client
+
+	select {
+	case s.connected <- struct{}{}:
+	default:
+	}
+
+	return client, nil
+}
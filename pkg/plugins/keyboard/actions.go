@@ -2,30 +2,193 @@ package keyboard
 
 import (
 	"encoding/json"
+	"fmt"
+	"sd/pkg/actions"
 	"sd/pkg/natsconn"
+	"sd/pkg/types"
+	"sync"
+	"time"
 
-	"github.com/go-vgo/robotgo"
 	"github.com/nats-io/nats.go"
 	"github.com/rs/zerolog/log"
 )
 
-var msg struct {
-	Text string `json:"text"`
-}
+const (
+	actionType     types.ActionType = "type"
+	actionHotkey   types.ActionType = "hotkey"
+	actionSequence types.ActionType = "sequence"
+	actionHold     types.ActionType = "hold"
+	actionMedia    types.ActionType = "media"
+)
 
-// Subscribe sets up the NATS subscription for this plugin.
-func SubscribeActionType(pluginNamespace string) {
-	nc, _ := natsconn.GetNATSConn()
+// maxHold releases held keys whose release never arrived, for instance
+// because the deck was unplugged while a key was down.
+const maxHold = time.Minute
 
-	nc.Subscribe(pluginNamespace+".type", func(m *nats.Msg) {
+var (
+	heldMu sync.Mutex
+	held   = make(map[string]*time.Timer) // keys setting to its safety timer
+)
 
-		// Parse the incoming message.
-		if err := json.Unmarshal(m.Data, &msg); err != nil {
-			log.Error().Err(err).Msg("Error unmarshaling JSON")
-			return
+// validate checks the settings of an action and returns its keys.
+func validate(t types.ActionType, settings types.Settings) (chord, []sequenceStep, error) {
+	switch t {
+	case actionType:
+		if settings.Text == "" {
+			return nil, nil, fmt.Errorf("text is empty")
 		}
+		return nil, nil, nil
+	case actionHotkey, actionHold:
+		c, err := parseChord(settings.Keys)
+		return c, nil, err
+	case actionSequence:
+		steps, err := parseSequence(settings.Sequence)
+		return nil, steps, err
+	case actionMedia:
+		k, err := parseMediaKey(settings.Keys)
+		return chord{k}, nil, err
+	}
+
+	return nil, nil, fmt.Errorf("unknown keyboard action: %s", t)
+}
+
+// execute performs a keyboard action with the settings of a button.
+func execute(t types.ActionType, settings types.Settings) error {
+	c, steps, err := validate(t, settings)
+	if err != nil {
+		return err
+	}
+
+	b := current()
+
+	switch t {
+	case actionType:
+		return b.typeText(settings.Text)
+	case actionSequence:
+		return playSequence(b, steps)
+	case actionHold:
+		return hold(b, settings.Keys, c)
+	default:
+		return tap(b, c)
+	}
+}
+
+// hold presses a chord until its release arrives.
+func hold(b backend, name string, c chord) error {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+
+	if timer, ok := held[name]; ok {
+		timer.Reset(maxHold)
+		return nil
+	}
 
-		// Perform the desired action.
-		robotgo.TypeStr(msg.Text)
+	if err := press(b, c); err != nil {
+		return err
+	}
+
+	held[name] = time.AfterFunc(maxHold, func() {
+		log.Warn().Str("keys", name).Msg("Releasing keys held too long")
+		if err := releaseHeld(b, name); err != nil {
+			log.Error().Err(err).Msg("Failed to release keys")
+		}
 	})
+
+	return nil
+}
+
+// releaseHeld releases a chord pressed by hold.
+func releaseHeld(b backend, name string) error {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+
+	timer, ok := held[name]
+	if !ok {
+		return nil
+	}
+	timer.Stop()
+	delete(held, name)
+
+	c, err := parseChord(name)
+	if err != nil {
+		return err
+	}
+	return release(b, c)
+}
+
+// handle runs handler with the settings of the button in m and replies
+// with the result. Messages for the keys of other instances are left to
+// their own keyboard plugin, and unsigned messages are rejected while
+// SD_ACTION_SECRET is set.
+func handle(instanceID string, m *nats.Msg, handler func(types.Settings) error) {
+	if !actions.ForInstance(m, instanceID) {
+		return
+	}
+	if err := actions.Verify(m); err != nil {
+		log.Warn().Err(err).Str("action", m.Subject).Msg("Rejected keyboard action")
+		actions.Reply(m, err)
+		return
+	}
+
+	var button types.Button
+	if err := json.Unmarshal(m.Data, &button); err != nil {
+		log.Error().Err(err).Msg("Error unmarshaling JSON")
+		actions.Reply(m, err)
+		return
+	}
+
+	err := handler(button.Settings)
+	if err != nil {
+		log.Error().Err(err).Str("action", m.Subject).Msg("Keyboard action failed")
+	}
+	actions.Reply(m, err)
+}
+
+// subscribeHold handles hold presses and releases in the order they were
+// sent, so that a short tap never releases before it pressed.
+func subscribeHold(nc *nats.Conn, instanceID string, subject string) error {
+	release := actions.ReleaseSubject(subject)
+	ch := make(chan *nats.Msg, 64)
+
+	for _, s := range []string{subject, release} {
+		if _, err := nc.ChanSubscribe(s, ch); err != nil {
+			return err
+		}
+	}
+
+	go func() {
+		for m := range ch {
+			if m.Subject == release {
+				handle(instanceID, m, func(settings types.Settings) error {
+					return releaseHeld(current(), settings.Keys)
+				})
+				continue
+			}
+			handle(instanceID, m, func(settings types.Settings) error {
+				return execute(actionHold, settings)
+			})
+		}
+	}()
+
+	return nil
+}
+
+// SubscribeActions sets up the NATS subscriptions for the keyboard actions
+// of an instance.
+func SubscribeActions(pluginNamespace string, instanceID string) {
+	nc, _ := natsconn.GetNATSConn()
+
+	for _, t := range []types.ActionType{actionType, actionHotkey, actionSequence, actionMedia} {
+		if _, err := nc.Subscribe(pluginNamespace+"."+string(t), func(m *nats.Msg) {
+			handle(instanceID, m, func(settings types.Settings) error {
+				return execute(t, settings)
+			})
+		}); err != nil {
+			log.Fatal().Err(err).Str("action", string(t)).Msg("Failed to subscribe to keyboard action")
+		}
+	}
+
+	if err := subscribeHold(nc, instanceID, pluginNamespace+"."+string(actionHold)); err != nil {
+		log.Fatal().Err(err).Msg("Failed to subscribe to held keys")
+	}
 }
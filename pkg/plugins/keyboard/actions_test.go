@@ -0,0 +1,65 @@
+package keyboard
+
+import (
+	"encoding/json"
+	"sd/pkg/actions"
+	"sd/pkg/types"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestHandleOnlyOwnInstance(t *testing.T) {
+	data, err := json.Marshal(types.Button{UUID: pluginNamespace + ".type", Settings: types.Settings{Text: "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		buttonKey string
+		want      bool
+	}{
+		{"own key", "instances.desk.devices.D1.profiles.P.pages.1.buttons.0", true},
+		{"other instance", "instances.laptop.devices.D2.profiles.P.pages.1.buttons.0", false},
+		{"instance sharing a prefix", "instances.desk2.devices.D2.profiles.P.pages.1.buttons.0", false},
+	}
+
+	for _, tt := range tests {
+		var typed []string
+		handle("desk", actions.NewMsg(pluginNamespace+".type", tt.buttonKey, "", data), func(settings types.Settings) error {
+			typed = append(typed, settings.Text)
+			return nil
+		})
+
+		if got := len(typed) == 1; got != tt.want {
+			t.Errorf("%s: handled = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandleRejectsUnsigned(t *testing.T) {
+	t.Setenv(actions.SecretEnv, "secret")
+
+	data, err := json.Marshal(types.Button{UUID: pluginNamespace + ".type", Settings: types.Settings{Text: "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "instances.desk.devices.D1.profiles.P.pages.1.buttons.0"
+
+	unsigned := nats.NewMsg(pluginNamespace + ".type")
+	unsigned.Data = data
+	unsigned.Header.Set(actions.ButtonKeyHeader, key)
+
+	for _, m := range []*nats.Msg{unsigned, actions.NewMsg(pluginNamespace+".type", key, "", data)} {
+		typed := false
+		handle("desk", m, func(types.Settings) error {
+			typed = true
+			return nil
+		})
+
+		if signed := m.Header.Get(actions.SignatureHeader) != ""; typed != signed {
+			t.Errorf("signed = %v: typed = %v", signed, typed)
+		}
+	}
+}
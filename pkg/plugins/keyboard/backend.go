@@ -0,0 +1,119 @@
+package keyboard
+
+import (
+	"fmt"
+	"os"
+	"sd/pkg/env"
+	"sync"
+	"time"
+
+	"github.com/go-vgo/robotgo"
+	"github.com/rs/zerolog/log"
+)
+
+// backend sends key events to the desktop.
+type backend interface {
+	down(k key) error
+	up(k key) error
+	typeText(text string) error
+}
+
+// Backends selected with SD_KEYBOARD_BACKEND. Without it uinput is used
+// under Wayland, where X11 key injection does not reach native windows.
+const (
+	backendRobotgo = "robotgo"
+	backendUinput  = "uinput"
+)
+
+var (
+	selectOnce sync.Once
+	selected   backend
+)
+
+// current returns the backend chosen for this session.
+func current() backend {
+	selectOnce.Do(func() {
+		name := env.Get("SD_KEYBOARD_BACKEND", "")
+		if name == "" && os.Getenv("WAYLAND_DISPLAY") != "" {
+			name = backendUinput
+		}
+
+		if name == backendUinput {
+			b, err := newUinput()
+			if err == nil {
+				selected = b
+				return
+			}
+			log.Error().Err(err).Msg("Cannot use uinput, falling back to robotgo")
+		}
+
+		selected = robotgoBackend{}
+	})
+
+	return selected
+}
+
+// tap presses and releases a chord.
+func tap(b backend, c chord) error {
+	if err := press(b, c); err != nil {
+		return err
+	}
+	return release(b, c)
+}
+
+// press presses the keys of a chord in order.
+func press(b backend, c chord) error {
+	for i, k := range c {
+		if err := b.down(k); err != nil {
+			release(b, c[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// release releases the keys of a chord in reverse order.
+func release(b backend, c chord) error {
+	var first error
+	for i := len(c) - 1; i >= 0; i-- {
+		if err := b.up(c[i]); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// playSequence taps the chords of a sequence and waits for its delays.
+func playSequence(b backend, steps []sequenceStep) error {
+	for _, step := range steps {
+		if step.delay > 0 {
+			time.Sleep(step.delay)
+			continue
+		}
+		if err := tap(b, step.chord); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type robotgoBackend struct{}
+
+func (robotgoBackend) down(k key) error {
+	if err := robotgo.KeyToggle(k.name, "down"); err != nil {
+		return fmt.Errorf("failed to press %s: %w", k.name, err)
+	}
+	return nil
+}
+
+func (robotgoBackend) up(k key) error {
+	if err := robotgo.KeyToggle(k.name, "up"); err != nil {
+		return fmt.Errorf("failed to release %s: %w", k.name, err)
+	}
+	return nil
+}
+
+func (robotgoBackend) typeText(text string) error {
+	robotgo.TypeStr(text)
+	return nil
+}
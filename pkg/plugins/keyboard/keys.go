@@ -0,0 +1,219 @@
+package keyboard
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// key is a key known to both backends.
+type key struct {
+	name  string // robotgo key name
+	code  uint16 // Linux input event code
+	shift bool   // needs shift to type the character on a US layout
+}
+
+var modifiers = map[string]key{
+	"ctrl":    {"ctrl", 29, false},
+	"control": {"ctrl", 29, false},
+	"shift":   {"shift", 42, false},
+	"alt":     {"alt", 56, false},
+	"altgr":   {"ralt", 100, false},
+	"super":   {"cmd", 125, false},
+	"meta":    {"cmd", 125, false},
+	"cmd":     {"cmd", 125, false},
+	"win":     {"cmd", 125, false},
+}
+
+// keys maps lowercase key names, including the X keysym names written by
+// xdotool and the Elgato importer, to keys.
+var keys = map[string]key{
+	"escape": {"esc", 1, false}, "esc": {"esc", 1, false},
+	"minus": {"-", 12, false}, "equal": {"=", 13, false},
+	"backspace": {"backspace", 14, false}, "tab": {"tab", 15, false},
+	"bracketleft": {"[", 26, false}, "bracketright": {"]", 27, false},
+	"return": {"enter", 28, false}, "enter": {"enter", 28, false},
+	"semicolon": {";", 39, false}, "apostrophe": {"'", 40, false},
+	"grave": {"`", 41, false}, "backslash": {"\\", 43, false},
+	"comma": {",", 51, false}, "period": {".", 52, false}, "slash": {"/", 53, false},
+	"space": {"space", 57, false}, "caps_lock": {"capslock", 58, false}, "capslock": {"capslock", 58, false},
+	"num_lock": {"num_lock", 69, false}, "scroll_lock": {"scroll_lock", 70, false},
+	"kp_multiply": {"num*", 55, false}, "kp_subtract": {"num-", 74, false}, "kp_add": {"num+", 78, false},
+	"kp_decimal": {"num.", 83, false}, "kp_divide": {"num/", 98, false}, "kp_enter": {"num_enter", 96, false},
+	"print": {"printscreen", 99, false}, "home": {"home", 102, false}, "up": {"up", 103, false},
+	"prior": {"pageup", 104, false}, "pageup": {"pageup", 104, false}, "page_up": {"pageup", 104, false},
+	"left": {"left", 105, false}, "right": {"right", 106, false}, "end": {"end", 107, false},
+	"down": {"down", 108, false},
+	"next": {"pagedown", 109, false}, "pagedown": {"pagedown", 109, false}, "page_down": {"pagedown", 109, false},
+	"insert": {"insert", 110, false}, "delete": {"delete", 111, false},
+	"pause": {"pause", 119, false}, "menu": {"menu", 127, false},
+	"xf86audiomute":        {"audio_mute", 113, false},
+	"xf86audiolowervolume": {"audio_vol_down", 114, false},
+	"xf86audioraisevolume": {"audio_vol_up", 115, false},
+	"xf86audionext":        {"audio_next", 163, false},
+	"xf86audioplay":        {"audio_play", 164, false},
+	"xf86audioprev":        {"audio_prev", 165, false},
+	"xf86audiostop":        {"audio_stop", 166, false},
+	"xf86audiopause":       {"audio_pause", 201, false},
+}
+
+// mediaKeys maps the names offered by the media key action to key names.
+var mediaKeys = map[string]string{
+	"play":        "xf86audioplay",
+	"pause":       "xf86audiopause",
+	"stop":        "xf86audiostop",
+	"next":        "xf86audionext",
+	"previous":    "xf86audioprev",
+	"mute":        "xf86audiomute",
+	"volume_up":   "xf86audioraisevolume",
+	"volume_down": "xf86audiolowervolume",
+}
+
+func init() {
+	rows := []struct {
+		chars string
+		code  uint16
+	}{
+		{"1234567890", 2},
+		{"qwertyuiop", 16},
+		{"asdfghjkl", 30},
+		{"zxcvbnm", 44},
+	}
+	for _, row := range rows {
+		for i, c := range row.chars {
+			keys[string(c)] = key{string(c), row.code + uint16(i), false}
+		}
+	}
+
+	fCodes := []uint16{59, 60, 61, 62, 63, 64, 65, 66, 67, 68, 87, 88}
+	for i := 1; i <= 24; i++ {
+		code := uint16(183 + i - 13)
+		if i <= 12 {
+			code = fCodes[i-1]
+		}
+		keys[fmt.Sprintf("f%d", i)] = key{fmt.Sprintf("f%d", i), code, false}
+	}
+
+	kpCodes := []uint16{82, 79, 80, 81, 75, 76, 77, 71, 72, 73}
+	for i, code := range kpCodes {
+		keys[fmt.Sprintf("kp_%d", i)] = key{fmt.Sprintf("num%d", i), code, false}
+	}
+}
+
+// lookup returns the key or modifier with a case-insensitive name.
+func lookup(name string) (key, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if k, ok := modifiers[name]; ok {
+		return k, true
+	}
+	k, ok := keys[name]
+	return k, ok
+}
+
+// chord is a key pressed together with modifiers, such as ctrl+shift+t.
+type chord []key
+
+// parseChord parses keys joined by "+". The last key may also be a modifier
+// so that a modifier can be held on its own.
+func parseChord(s string) (chord, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("no keys given")
+	}
+
+	var c chord
+	for _, name := range strings.Split(s, "+") {
+		k, ok := lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown key: %q", name)
+		}
+		c = append(c, k)
+	}
+
+	return c, nil
+}
+
+// sequenceStep is a chord to tap or a pause.
+type sequenceStep struct {
+	chord chord
+	delay time.Duration
+}
+
+// parseSequence parses one chord or duration (such as 250ms) per line.
+func parseSequence(s string) ([]sequenceStep, error) {
+	var steps []sequenceStep
+
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if d, err := time.ParseDuration(line); err == nil {
+			if d < 0 || d > time.Minute {
+				return nil, fmt.Errorf("line %d: delay must be between 0 and 1m", i+1)
+			}
+			steps = append(steps, sequenceStep{delay: d})
+			continue
+		}
+
+		c, err := parseChord(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		steps = append(steps, sequenceStep{chord: c})
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("sequence is empty")
+	}
+
+	return steps, nil
+}
+
+// parseMediaKey returns the key of a media key name.
+func parseMediaKey(name string) (key, error) {
+	k, ok := keys[mediaKeys[strings.ToLower(strings.TrimSpace(name))]]
+	if !ok {
+		return key{}, fmt.Errorf("unknown media key: %q", name)
+	}
+	return k, nil
+}
+
+// shifted maps the characters typed with shift on a US layout to their
+// unshifted key.
+var shifted = map[rune]string{
+	'!': "1", '@': "2", '#': "3", '$': "4", '%': "5", '^': "6", '&': "7", '*': "8", '(': "9", ')': "0",
+	'_': "minus", '+': "equal", '{': "bracketleft", '}': "bracketright", ':': "semicolon",
+	'"': "apostrophe", '~': "grave", '|': "backslash", '<': "comma", '>': "period", '?': "slash",
+}
+
+var unshifted = map[rune]string{
+	'-': "minus", '=': "equal", '[': "bracketleft", ']': "bracketright", ';': "semicolon",
+	'\'': "apostrophe", '`': "grave", '\\': "backslash", ',': "comma", '.': "period", '/': "slash",
+	' ': "space", '\n': "return", '\t': "tab",
+}
+
+// charKey returns the key typing r on a US layout.
+func charKey(r rune) (key, bool) {
+	switch {
+	case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		k, ok := keys[string(r)]
+		return k, ok
+	case r >= 'A' && r <= 'Z':
+		k, ok := keys[strings.ToLower(string(r))]
+		k.shift = true
+		return k, ok
+	}
+
+	if name, ok := shifted[r]; ok {
+		k := keys[name]
+		k.shift = true
+		return k, true
+	}
+	if name, ok := unshifted[r]; ok {
+		return keys[name], true
+	}
+
+	return key{}, false
+}
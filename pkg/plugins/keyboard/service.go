@@ -3,17 +3,13 @@ package keyboard
 import (
 	"encoding/json"
 	"sd/pkg/types"
-
-	"github.com/go-vgo/robotgo"
 )
 
 var pluginNamespace = "sd.plugin.keyboard"
 
 // KeyboardPlugin represents the keyboard plugin.
-type KeyboardPlugin struct{}
-
-type TypeConfig struct {
-	Text string `json:"text"`
+type KeyboardPlugin struct {
+	InstanceID string
 }
 
 // Name returns the name of the plugin.
@@ -23,25 +19,32 @@ func (k *KeyboardPlugin) Name() string {
 
 // Subscribe sets up the NATS subscription for this plugin.
 func (k *KeyboardPlugin) Init() {
-	SubscribeActionType(pluginNamespace)
+	SubscribeActions(pluginNamespace, k.InstanceID)
 }
 
 func (k *KeyboardPlugin) GetActionTypes() []types.ActionType {
 	return []types.ActionType{
-		"type",
+		actionType,
+		actionHotkey,
+		actionSequence,
+		actionHold,
+		actionMedia,
 	}
 }
 
 func (k *KeyboardPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
-	var cfg TypeConfig
-	return json.Unmarshal(config, &cfg)
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	_, _, err := validate(actionType, settings)
+	return err
 }
 
 func (k *KeyboardPlugin) ExecuteAction(actionType types.ActionType, config json.RawMessage) error {
-	var cfg TypeConfig
-	if err := json.Unmarshal(config, &cfg); err != nil {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
 		return err
 	}
-	robotgo.TypeStr(cfg.Text)
-	return nil
+	return execute(actionType, settings)
 }
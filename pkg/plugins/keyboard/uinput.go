@@ -0,0 +1,154 @@
+package keyboard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Linux uinput constants from linux/uinput.h and linux/input-event-codes.h.
+const (
+	uiSetEvBit   = 0x40045564
+	uiSetKeyBit  = 0x40045565
+	uiDevCreate  = 0x5501
+	evSyn        = 0x00
+	evKey        = 0x01
+	synReport    = 0
+	busVirtual   = 0x06
+	absCount     = 64
+	deviceName   = "sd virtual keyboard"
+	settleDelay  = 200 * time.Millisecond
+	keystrokeGap = 2 * time.Millisecond
+)
+
+// inputEvent is struct input_event on 64-bit Linux.
+type inputEvent struct {
+	Sec   int64
+	Usec  int64
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// uinputUserDev is struct uinput_user_dev.
+type uinputUserDev struct {
+	Name         [80]byte
+	Bustype      uint16
+	Vendor       uint16
+	Product      uint16
+	Version      uint16
+	FFEffectsMax uint32
+	Absmax       [absCount]int32
+	Absmin       [absCount]int32
+	Absfuzz      [absCount]int32
+	Absflat      [absCount]int32
+}
+
+// uinputBackend injects key events through a virtual keyboard in the
+// kernel, which works under Wayland and X11 alike. The server needs write
+// access to /dev/uinput, usually through the input group or a udev rule.
+type uinputBackend struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newUinput() (*uinputBackend, error) {
+	file, err := os.OpenFile("/dev/uinput", os.O_WRONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open /dev/uinput: %w", err)
+	}
+
+	fd := int(file.Fd())
+
+	if err := unix.IoctlSetInt(fd, uiSetEvBit, evKey); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to enable key events: %w", err)
+	}
+
+	codes := make(map[uint16]bool)
+	for _, table := range []map[string]key{keys, modifiers} {
+		for _, k := range table {
+			codes[k.code] = true
+		}
+	}
+	for code := range codes {
+		if err := unix.IoctlSetInt(fd, uiSetKeyBit, int(code)); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to enable key %d: %w", code, err)
+		}
+	}
+
+	dev := uinputUserDev{Bustype: busVirtual, Vendor: 0x1, Product: 0x1, Version: 1}
+	copy(dev.Name[:], deviceName)
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, dev)
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to set up virtual keyboard: %w", err)
+	}
+
+	if err := unix.IoctlSetInt(fd, uiDevCreate, 0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to create virtual keyboard: %w", err)
+	}
+
+	// Give the compositor time to pick up the new device.
+	time.Sleep(settleDelay)
+
+	return &uinputBackend{file: file}, nil
+}
+
+func (u *uinputBackend) emit(code uint16, value int32) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, inputEvent{Type: evKey, Code: code, Value: value})
+	binary.Write(&buf, binary.LittleEndian, inputEvent{Type: evSyn, Code: synReport})
+
+	if _, err := u.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write key event: %w", err)
+	}
+
+	time.Sleep(keystrokeGap)
+	return nil
+}
+
+func (u *uinputBackend) down(k key) error {
+	return u.emit(k.code, 1)
+}
+
+func (u *uinputBackend) up(k key) error {
+	return u.emit(k.code, 0)
+}
+
+// typeText types text assuming a US layout.
+func (u *uinputBackend) typeText(text string) error {
+	for _, r := range text {
+		if _, ok := charKey(r); !ok {
+			return fmt.Errorf("cannot type %q with uinput", r)
+		}
+	}
+
+	shift := modifiers["shift"]
+
+	for _, r := range text {
+		k, _ := charKey(r)
+
+		c := chord{k}
+		if k.shift {
+			c = chord{shift, k}
+		}
+		if err := tap(u, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
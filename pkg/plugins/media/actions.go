@@ -0,0 +1,189 @@
+package media
+
+import (
+	"encoding/json"
+	"fmt"
+	"sd/pkg/actions"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	actionPlayPause  types.ActionType = "play_pause"
+	actionNext       types.ActionType = "next"
+	actionPrevious   types.ActionType = "previous"
+	actionSeek       types.ActionType = "seek"
+	actionNowPlaying types.ActionType = "now_playing"
+	actionDial       types.ActionType = "dial"
+)
+
+var keyActions = []types.ActionType{actionPlayPause, actionNext, actionPrevious, actionSeek, actionNowPlaying}
+
+const (
+	defaultKeyOffset  = 10 // seconds
+	defaultDialOffset = 5  // seconds per tick
+)
+
+func subject(t types.ActionType) string {
+	return "sd.plugin.media." + string(t)
+}
+
+// offset returns the seek offset of the settings.
+func offset(settings types.Settings, fallback int) time.Duration {
+	if settings.Offset == 0 {
+		return time.Duration(fallback) * time.Second
+	}
+	return time.Duration(settings.Offset) * time.Second
+}
+
+// validate checks the settings of an action.
+func validate(t types.ActionType, settings types.Settings) error {
+	switch t {
+	case actionPlayPause, actionNext, actionPrevious, actionSeek, actionNowPlaying:
+	case actionDial:
+		if settings.Offset < 0 {
+			return fmt.Errorf("seek per tick cannot be negative")
+		}
+	default:
+		return fmt.Errorf("unknown media action: %s", t)
+	}
+
+	return nil
+}
+
+// target returns the player an action controls.
+func target(conn *dbus.Conn, settings types.Settings) (player, error) {
+	players, err := readPlayers(conn)
+	if err != nil {
+		return player{}, err
+	}
+
+	p, ok := pick(players, settings.Player)
+	if !ok {
+		if settings.Player != "" {
+			return player{}, fmt.Errorf("player %s is not running", settings.Player)
+		}
+		return player{}, fmt.Errorf("no media player is running")
+	}
+
+	return p, nil
+}
+
+// seek moves the position of a player by an offset.
+func seek(conn *dbus.Conn, p player, by time.Duration) error {
+	return call(conn, p.name, "Seek", by.Microseconds())
+}
+
+// execute performs a key action with the settings of a button.
+func execute(t types.ActionType, settings types.Settings) error {
+	if err := validate(t, settings); err != nil {
+		return err
+	}
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the session bus: %w", err)
+	}
+
+	p, err := target(conn, settings)
+	if err != nil {
+		return err
+	}
+
+	switch t {
+	case actionPlayPause:
+		return call(conn, p.name, "PlayPause")
+	case actionNext:
+		return call(conn, p.name, "Next")
+	case actionPrevious:
+		return call(conn, p.name, "Previous")
+	case actionSeek:
+		return seek(conn, p, offset(settings, defaultKeyOffset))
+	case actionNowPlaying:
+		return raise(conn, p.name)
+	}
+
+	return fmt.Errorf("%s is bound to dials", t)
+}
+
+// turn seeks by the offset of a dial per tick, and plays or pauses when the
+// dial is pressed.
+func turn(settings types.Settings, input string, ticks int) error {
+	if err := validate(actionDial, settings); err != nil {
+		return err
+	}
+	if input != actions.DialRotate && input != actions.DialPress {
+		return nil
+	}
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the session bus: %w", err)
+	}
+
+	p, err := target(conn, settings)
+	if err != nil {
+		return err
+	}
+
+	if input == actions.DialPress {
+		return call(conn, p.name, "PlayPause")
+	}
+	return seek(conn, p, time.Duration(ticks)*offset(settings, defaultDialOffset))
+}
+
+// subscribeActions sets up the NATS subscriptions for the media actions of
+// an instance.
+func subscribeActions(instanceID string, feedback *feedback) {
+	nc, _ := natsconn.GetNATSConn()
+
+	for _, t := range keyActions {
+		t := t
+		if _, err := nc.Subscribe(subject(t), func(m *nats.Msg) {
+			if !actions.ForInstance(m, instanceID) {
+				return
+			}
+
+			var button types.Button
+			if err := json.Unmarshal(m.Data, &button); err != nil {
+				log.Error().Err(err).Msg("Failed to unmarshal button")
+				actions.Reply(m, err)
+				return
+			}
+
+			err := execute(t, button.Settings)
+			if err != nil {
+				log.Error().Err(err).Str("action", string(t)).Msg("Media action failed")
+			}
+			feedback.trigger()
+			actions.Reply(m, err)
+		}); err != nil {
+			log.Error().Err(err).Str("action", string(t)).Msg("Failed to subscribe")
+		}
+	}
+
+	if _, err := nc.Subscribe(actions.DialSubject(subject(actionDial)), func(m *nats.Msg) {
+		if !actions.ForInstance(m, instanceID) {
+			return
+		}
+
+		var dial types.Button
+		if err := json.Unmarshal(m.Data, &dial); err != nil {
+			log.Error().Err(err).Msg("Failed to unmarshal dial")
+			return
+		}
+
+		input, ticks := actions.DialInput(m)
+		if err := turn(dial.Settings, input, ticks); err != nil {
+			log.Error().Err(err).Msg("Media dial failed")
+		}
+		feedback.trigger()
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to subscribe to media dials")
+	}
+}
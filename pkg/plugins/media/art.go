@@ -0,0 +1,184 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sd/pkg/util"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	artSize    = 144
+	artLimit   = 4 << 20
+	artPixels  = 4096 * 4096 // largest decoded album art
+	artTimeout = 5 * time.Second
+	artEntries = 16
+)
+
+var (
+	artMu    sync.Mutex
+	artCache = make(map[string]string) // base64 JPEG by art URL
+)
+
+// artClient downloads album art, following redirects to https only.
+var artClient = &http.Client{
+	Timeout: artTimeout,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return fmt.Errorf("redirected to %s", req.URL.Scheme)
+		}
+		if len(via) >= 5 {
+			return fmt.Errorf("too many redirects")
+		}
+		return nil
+	},
+}
+
+// artDirs returns the directories players save album art to: the cache
+// directory, where players such as VLC and Rhythmbox keep covers, and the
+// folders of the temporary directory browsers use.
+func artDirs() []string {
+	var dirs []string
+	if cache, err := os.UserCacheDir(); err == nil {
+		dirs = append(dirs, cache)
+	}
+
+	tmp := os.TempDir()
+	dirs = append(dirs, filepath.Join(tmp, "firefox-mpris"))
+	if matches, err := filepath.Glob(filepath.Join(tmp, ".org.chromium.Chromium.*")); err == nil {
+		dirs = append(dirs, matches...)
+	}
+
+	return dirs
+}
+
+// artPath returns the path of album art at a file:// URL, when it is under
+// one of the art directories of players. Links are resolved first.
+func artPath(u *url.URL) (string, error) {
+	path, err := filepath.EvalSymlinks(filepath.Clean(u.Path))
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range artDirs() {
+		if dir, err := filepath.EvalSymlinks(dir); err == nil && strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("album art outside the player cache: %s", path)
+}
+
+// readLimited reads r, failing when it holds more than artLimit bytes.
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, artLimit+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > artLimit {
+		return nil, fmt.Errorf("album art larger than %d bytes", artLimit)
+	}
+	return data, nil
+}
+
+// readArt returns the content of album art at a file:// URL in the cache of
+// a player, or at an https:// URL, as players such as Spotify link to their
+// covers online.
+func readArt(artURL string) ([]byte, error) {
+	u, err := url.Parse(artURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "file":
+		path, err := artPath(u)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readLimited(f)
+	case "https":
+		ctx, cancel := context.WithTimeout(context.Background(), artTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, artURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := artClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+		return readLimited(resp.Body)
+	}
+
+	return nil, fmt.Errorf("unsupported art URL: %s", artURL)
+}
+
+// checkArt checks the dimensions of album art before it is decoded, as a
+// small file can claim to be a huge image.
+func checkArt(data []byte) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode album art: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > artPixels {
+		return fmt.Errorf("album art of %dx%d pixels is too large", config.Width, config.Height)
+	}
+	return nil
+}
+
+// art returns the album art at a URL scaled down for keys and base64
+// encoded for types.Feedback, or "" when there is none.
+func art(artURL string) string {
+	if artURL == "" {
+		return ""
+	}
+
+	artMu.Lock()
+	cached, ok := artCache[artURL]
+	artMu.Unlock()
+	if ok {
+		return cached
+	}
+
+	var encoded string
+	data, err := readArt(artURL)
+	if err == nil {
+		err = checkArt(data)
+	}
+	if err == nil {
+		data, err = util.RenderImage(data, util.ImageSpec{Width: artSize, Height: artSize, Format: util.FormatJPEG, Quality: 85})
+	}
+	if err == nil {
+		encoded = base64.StdEncoding.EncodeToString(data)
+	}
+
+	artMu.Lock()
+	defer artMu.Unlock()
+	if len(artCache) >= artEntries {
+		clear(artCache)
+	}
+	artCache[artURL] = encoded
+
+	return encoded
+}
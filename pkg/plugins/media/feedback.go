@@ -0,0 +1,291 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"sd/pkg/bindings"
+	"sd/pkg/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	retryDelay = 30 * time.Second
+
+	// settleDelay groups the bursts of signals players send for one change.
+	settleDelay = 100 * time.Millisecond
+
+	// positionInterval redraws the position of dials while a player plays,
+	// as players do not signal position changes.
+	positionInterval = time.Second
+
+	titleLength = 20
+)
+
+// binding is a key or dial showing the state of a player.
+type binding struct {
+	action   types.ActionType
+	settings types.Settings
+}
+
+// feedback draws the play state, track title and album art of players on the
+// keys and dials bound to them.
+type feedback struct {
+	instanceID string
+	refresh    chan struct{}
+
+	mu       sync.Mutex
+	bindings map[string]binding
+	drawn    bindings.Drawn
+	players  []player          // nil until read
+	arts     map[string]string // base64 album art by art URL
+}
+
+func newFeedback(instanceID string) *feedback {
+	return &feedback{
+		instanceID: instanceID,
+		refresh:    make(chan struct{}, 1),
+		bindings:   make(map[string]binding),
+		drawn:      make(bindings.Drawn),
+	}
+}
+
+// trigger asks for the state of the players to be read again.
+func (f *feedback) trigger() {
+	select {
+	case f.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func truncate(title string) string {
+	if runes := []rune(title); len(runes) > titleLength {
+		return string(runes[:titleLength-3]) + "..."
+	}
+	return title
+}
+
+// draw updates a key or dial if its state changed. Callers hold f.mu.
+func (f *feedback) draw(key string) {
+	b, ok := f.bindings[key]
+	if !ok || f.players == nil {
+		return
+	}
+
+	p, found := pick(f.players, b.settings.Player)
+
+	switch b.action {
+	case actionPlayPause:
+		state := "0"
+		if found && p.status == statusPlaying {
+			state = "1"
+		}
+
+		if err := f.drawn.State(key, state); err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to draw play state")
+		}
+
+	case actionNowPlaying, actionDial:
+		var fb *types.Feedback
+		if found && p.title != "" {
+			fb = &types.Feedback{Title: truncate(p.title), Image: f.arts[p.artURL]}
+			if b.action == actionDial && p.length > 0 {
+				level := int(min(max(p.position*100/p.length, 0), 100))
+				fb.Level = &level
+			}
+		}
+
+		if err := f.drawn.Feedback(key, fb); err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to draw now playing")
+		}
+	}
+}
+
+// needs reports whether any binding shows album art, and whether any dial
+// shows a position that moves. Callers hold f.mu.
+func (f *feedback) needs() (arts bool, position bool) {
+	for _, b := range f.bindings {
+		switch b.action {
+		case actionNowPlaying:
+			arts = true
+		case actionDial:
+			arts = true
+			if p, ok := pick(f.players, b.settings.Player); ok && p.status == statusPlaying {
+				position = true
+			}
+		}
+	}
+	return arts, position
+}
+
+// run redraws the bound keys and dials whenever a refresh is triggered, and
+// every positionInterval while a dial shows a playing track.
+func (f *feedback) run(ctx context.Context) {
+	ticker := time.NewTicker(positionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.refresh:
+			time.Sleep(settleDelay)
+		case <-ticker.C:
+			f.mu.Lock()
+			_, position := f.needs()
+			f.mu.Unlock()
+			if !position {
+				continue
+			}
+		}
+
+		f.mu.Lock()
+		empty := len(f.bindings) == 0
+		f.mu.Unlock()
+		if empty {
+			continue
+		}
+
+		conn, err := dbus.SessionBus()
+		if err != nil {
+			log.Debug().Err(err).Msg("Cannot connect to the session bus")
+			continue
+		}
+		players, err := readPlayers(conn)
+		if err != nil {
+			log.Debug().Err(err).Msg("Cannot read media players")
+			continue
+		}
+
+		f.mu.Lock()
+		f.players = players
+		arts, _ := f.needs()
+		f.mu.Unlock()
+
+		// Album art may be downloaded, do not hold up the bindings.
+		covers := make(map[string]string)
+		if arts {
+			for _, p := range players {
+				if p.artURL != "" {
+					covers[p.artURL] = art(p.artURL)
+				}
+			}
+		}
+
+		f.mu.Lock()
+		f.arts = covers
+		for key := range f.bindings {
+			f.draw(key)
+		}
+		f.mu.Unlock()
+	}
+}
+
+// update tracks a button or dial key, drawing it when it is bound to a
+// media action and clearing the feedback of keys that no longer are.
+func (f *feedback) update(key string, button types.Button, put bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	action, ok := strings.CutPrefix(button.UUID, "sd.plugin.media.")
+	t := types.ActionType(action)
+	if !ok || (t != actionPlayPause && t != actionNowPlaying && t != actionDial) {
+		if previous, ok := f.bindings[key]; ok {
+			delete(f.bindings, key)
+			if previous.action == actionPlayPause {
+				f.drawn.Forget(key)
+			} else if err := f.drawn.Clear(key, put); err != nil {
+				log.Error().Err(err).Str("key", key).Msg("Failed to clear media feedback")
+			}
+		}
+		return
+	}
+
+	b := binding{action: t, settings: button.Settings}
+	if previous, ok := f.bindings[key]; !ok || previous.action != b.action || previous.settings.Player != b.settings.Player {
+		f.drawn.Forget(key)
+	}
+	f.bindings[key] = b
+
+	if f.players == nil {
+		f.trigger()
+		return
+	}
+	f.draw(key)
+}
+
+// watchBus triggers a refresh whenever a player changes, seeks, starts or
+// quits.
+func (f *feedback) watchBus(ctx context.Context) {
+	for {
+		if err := f.followBus(ctx); err != nil {
+			log.Warn().Err(err).Msg("Cannot watch media players")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (f *feedback) followBus(ctx context.Context) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the session bus: %w", err)
+	}
+	defer conn.Close()
+
+	matches := [][]dbus.MatchOption{
+		{
+			dbus.WithMatchObjectPath(objectPath),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+			dbus.WithMatchMember("PropertiesChanged"),
+		},
+		{
+			dbus.WithMatchObjectPath(objectPath),
+			dbus.WithMatchInterface(playerIface),
+			dbus.WithMatchMember("Seeked"),
+		},
+		{
+			dbus.WithMatchInterface("org.freedesktop.DBus"),
+			dbus.WithMatchMember("NameOwnerChanged"),
+			dbus.WithMatchArg0Namespace(rootIface),
+		},
+	}
+	for _, options := range matches {
+		if err := conn.AddMatchSignal(options...); err != nil {
+			return fmt.Errorf("failed to watch players: %w", err)
+		}
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	f.trigger()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-signals:
+			if !ok {
+				return fmt.Errorf("session bus connection closed")
+			}
+			f.trigger()
+		}
+	}
+}
+
+// start follows the players and the bindings of the instance.
+func (f *feedback) start(ctx context.Context) {
+	go f.run(ctx)
+	go f.watchBus(ctx)
+	go bindings.Watch(ctx, bindings.Buttons(f.instanceID), f.update)
+	go bindings.Watch(ctx, bindings.Dials(f.instanceID), f.update)
+}
@@ -0,0 +1,155 @@
+package media
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	busPrefix   = "org.mpris.MediaPlayer2."
+	objectPath  = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+	rootIface   = "org.mpris.MediaPlayer2"
+	playerIface = "org.mpris.MediaPlayer2.Player"
+
+	statusPlaying = "Playing"
+	statusPaused  = "Paused"
+)
+
+// player is the state of an MPRIS player.
+type player struct {
+	name     string // bus name such as org.mpris.MediaPlayer2.spotify
+	status   string // Playing, Paused or Stopped
+	title    string
+	artist   string
+	artURL   string
+	length   int64 // microseconds
+	position int64 // microseconds
+}
+
+// matches reports whether a bus name is the player named want, such as
+// spotify, ignoring the instance suffix some players add.
+func matches(name string, want string) bool {
+	id := strings.ToLower(strings.TrimPrefix(name, busPrefix))
+	want = strings.ToLower(want)
+	return id == want || strings.HasPrefix(id, want+".")
+}
+
+// listPlayers returns the bus names of the running players.
+func listPlayers(conn *dbus.Conn) ([]string, error) {
+	var names []string
+	if err := conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		return nil, fmt.Errorf("failed to list D-Bus names: %w", err)
+	}
+
+	var players []string
+	for _, name := range names {
+		if strings.HasPrefix(name, busPrefix) {
+			players = append(players, name)
+		}
+	}
+	sort.Strings(players)
+
+	return players, nil
+}
+
+// integer returns an MPRIS integer, which players send as int64 or uint64.
+func integer(v dbus.Variant) int64 {
+	switch n := v.Value().(type) {
+	case int64:
+		return n
+	case uint64:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	}
+	return 0
+}
+
+// readPlayer returns the state of a player.
+func readPlayer(conn *dbus.Conn, name string) (player, error) {
+	p := player{name: name}
+
+	var props map[string]dbus.Variant
+	if err := conn.Object(name, objectPath).Call("org.freedesktop.DBus.Properties.GetAll", 0, playerIface).Store(&props); err != nil {
+		return p, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	p.status, _ = props["PlaybackStatus"].Value().(string)
+	p.position = integer(props["Position"])
+
+	metadata, _ := props["Metadata"].Value().(map[string]dbus.Variant)
+	p.title, _ = metadata["xesam:title"].Value().(string)
+	p.artURL, _ = metadata["mpris:artUrl"].Value().(string)
+	p.length = integer(metadata["mpris:length"])
+	if artists, ok := metadata["xesam:artist"].Value().([]string); ok {
+		p.artist = strings.Join(artists, ", ")
+	}
+
+	return p, nil
+}
+
+// readPlayers returns the state of every running player.
+func readPlayers(conn *dbus.Conn) ([]player, error) {
+	names, err := listPlayers(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	players := make([]player, 0, len(names))
+	for _, name := range names {
+		p, err := readPlayer(conn, name)
+		if err != nil {
+			continue
+		}
+		players = append(players, p)
+	}
+
+	return players, nil
+}
+
+// pick returns the player named want, or the active player when want is
+// empty: the first playing player, else the first paused one, else any.
+func pick(players []player, want string) (player, bool) {
+	if want != "" {
+		for _, p := range players {
+			if matches(p.name, want) {
+				return p, true
+			}
+		}
+		return player{}, false
+	}
+
+	for _, status := range []string{statusPlaying, statusPaused} {
+		for _, p := range players {
+			if p.status == status {
+				return p, true
+			}
+		}
+	}
+
+	if len(players) > 0 {
+		return players[0], true
+	}
+	return player{}, false
+}
+
+// call calls a method of the Player interface of a player.
+func call(conn *dbus.Conn, name string, method string, args ...any) error {
+	if err := conn.Object(name, objectPath).Call(playerIface+"."+method, 0, args...).Err; err != nil {
+		return fmt.Errorf("%s failed: %w", method, err)
+	}
+	return nil
+}
+
+// raise brings the window of a player to the front.
+func raise(conn *dbus.Conn, name string) error {
+	if err := conn.Object(name, objectPath).Call(rootIface+".Raise", 0).Err; err != nil {
+		return fmt.Errorf("Raise failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"sd/pkg/types"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MediaPlugin controls MPRIS media players over D-Bus, and shows their play
+// state, track and album art on the keys and dials bound to them.
+type MediaPlugin struct {
+	InstanceID string
+}
+
+// Name returns the name of the plugin.
+func (p *MediaPlugin) Name() string {
+	return "media"
+}
+
+// Init sets up the NATS subscriptions and the media feedback.
+func (p *MediaPlugin) Init() {
+	f := newFeedback(p.InstanceID)
+	subscribeActions(p.InstanceID, f)
+	f.start(context.Background())
+
+	log.Info().Msg("Media plugin initialized")
+}
+
+// GetActionTypes implements actions.Plugin.
+func (p *MediaPlugin) GetActionTypes() []types.ActionType {
+	return append(append([]types.ActionType{}, keyActions...), actionDial)
+}
+
+// ValidateConfig implements actions.Plugin.
+func (p *MediaPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return validate(actionType, settings)
+}
+
+// ExecuteAction implements actions.Plugin.
+func (p *MediaPlugin) ExecuteAction(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return execute(actionType, settings)
+}
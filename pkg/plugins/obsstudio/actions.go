@@ -0,0 +1,146 @@
+package obsstudio
+
+import (
+	"encoding/json"
+	"fmt"
+	"sd/pkg/actions"
+	"sd/pkg/natsconn"
+	"sd/pkg/obs"
+	"sd/pkg/types"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	actionScene  types.ActionType = "scene"
+	actionSource types.ActionType = "source"
+	actionStream types.ActionType = "stream"
+	actionRecord types.ActionType = "record"
+	actionMute   types.ActionType = "mute"
+)
+
+var actionTypes = []types.ActionType{actionScene, actionSource, actionStream, actionRecord, actionMute}
+
+func subject(t types.ActionType) string {
+	return "sd.plugin.obs." + string(t)
+}
+
+// validate checks the settings of an action.
+func validate(t types.ActionType, settings types.Settings) error {
+	switch t {
+	case actionScene:
+		if settings.Scene == "" {
+			return fmt.Errorf("scene is empty")
+		}
+	case actionSource, actionMute:
+		if settings.Source == "" {
+			return fmt.Errorf("source is empty")
+		}
+	case actionStream, actionRecord:
+	default:
+		return fmt.Errorf("unknown OBS action: %s", t)
+	}
+
+	return nil
+}
+
+// currentScene returns the current program scene.
+func currentScene(c *obs.Client) (string, error) {
+	var resp struct {
+		SceneName string `json:"currentProgramSceneName"`
+	}
+	if err := c.Request("GetCurrentProgramScene", nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.SceneName, nil
+}
+
+// sceneItem returns the scene item of a source in a scene, the current
+// program scene when scene is empty.
+func sceneItem(c *obs.Client, scene string, source string) (string, int, error) {
+	if scene == "" {
+		var err error
+		if scene, err = currentScene(c); err != nil {
+			return "", 0, err
+		}
+	}
+
+	var resp struct {
+		SceneItemID int `json:"sceneItemId"`
+	}
+	err := c.Request("GetSceneItemId", map[string]any{"sceneName": scene, "sourceName": source}, &resp)
+	return scene, resp.SceneItemID, err
+}
+
+// sceneItemEnabled reports whether a scene item is visible.
+func sceneItemEnabled(c *obs.Client, scene string, id int) (bool, error) {
+	var resp struct {
+		Enabled bool `json:"sceneItemEnabled"`
+	}
+	err := c.Request("GetSceneItemEnabled", map[string]any{"sceneName": scene, "sceneItemId": id}, &resp)
+	return resp.Enabled, err
+}
+
+// execute performs an action with the settings of a button.
+func execute(c *obs.Client, t types.ActionType, settings types.Settings) error {
+	if err := validate(t, settings); err != nil {
+		return err
+	}
+
+	switch t {
+	case actionScene:
+		return c.Request("SetCurrentProgramScene", map[string]any{"sceneName": settings.Scene}, nil)
+	case actionSource:
+		scene, id, err := sceneItem(c, settings.Scene, settings.Source)
+		if err != nil {
+			return err
+		}
+		enabled, err := sceneItemEnabled(c, scene, id)
+		if err != nil {
+			return err
+		}
+		return c.Request("SetSceneItemEnabled", map[string]any{"sceneName": scene, "sceneItemId": id, "sceneItemEnabled": !enabled}, nil)
+	case actionStream:
+		return c.Request("ToggleStream", nil, nil)
+	case actionRecord:
+		return c.Request("ToggleRecord", nil, nil)
+	case actionMute:
+		return c.Request("ToggleInputMute", map[string]any{"inputName": settings.Source}, nil)
+	}
+
+	return nil
+}
+
+// subscribeActions sets up the NATS subscriptions for the OBS actions of an
+// instance.
+func subscribeActions(instanceID string, s *session) {
+	nc, _ := natsconn.GetNATSConn()
+
+	for _, t := range actionTypes {
+		t := t
+		if _, err := nc.Subscribe(subject(t), func(m *nats.Msg) {
+			if !actions.ForInstance(m, instanceID) {
+				return
+			}
+
+			var button types.Button
+			if err := json.Unmarshal(m.Data, &button); err != nil {
+				log.Error().Err(err).Msg("Failed to unmarshal button")
+				actions.Reply(m, err)
+				return
+			}
+
+			c, err := s.get()
+			if err == nil {
+				err = execute(c, t, button.Settings)
+			}
+			if err != nil {
+				log.Error().Err(err).Str("action", string(t)).Msg("OBS action failed")
+			}
+			actions.Reply(m, err)
+		}); err != nil {
+			log.Error().Err(err).Str("action", string(t)).Msg("Failed to subscribe")
+		}
+	}
+}
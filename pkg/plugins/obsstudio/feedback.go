@@ -0,0 +1,331 @@
+package obsstudio
+
+import (
+	"context"
+	"encoding/json"
+	"sd/pkg/bindings"
+	"sd/pkg/obs"
+	"sd/pkg/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const reconnectDelay = 10 * time.Second
+
+// Key colours for the live state of OBS.
+const (
+	colorScene   = "#2563eb"
+	colorLive    = "#dc2626"
+	colorVisible = "#16a34a"
+	colorMuted   = "#ea580c"
+)
+
+// binding is a button showing the state of OBS.
+type binding struct {
+	action   types.ActionType
+	settings types.Settings
+	title    string
+}
+
+// item identifies a source in a scene.
+type item struct {
+	scene string
+	id    int
+}
+
+// feedback draws the live state of OBS on the keys bound to it.
+type feedback struct {
+	instanceID string
+	session    *session
+
+	mu        sync.Mutex
+	client    *obs.Client // nil while disconnected
+	bindings  map[string]binding
+	drawn     bindings.Drawn
+	scene     string
+	streaming bool
+	recording bool
+	muted     map[string]bool // by input
+	items     map[string]item // by button key
+	enabled   map[item]bool   // by scene item
+}
+
+func newFeedback(instanceID string, s *session) *feedback {
+	return &feedback{
+		instanceID: instanceID,
+		session:    s,
+		bindings:   make(map[string]binding),
+		drawn:      make(bindings.Drawn),
+		muted:      make(map[string]bool),
+		items:      make(map[string]item),
+		enabled:    make(map[item]bool),
+	}
+}
+
+// render returns the feedback of a binding for the current state.
+func (f *feedback) render(key string, b binding) types.Feedback {
+	var fb types.Feedback
+	if f.client == nil {
+		return fb
+	}
+
+	switch b.action {
+	case actionScene:
+		if b.settings.Scene == f.scene {
+			fb.Color = colorScene
+		}
+		if b.title == "" {
+			fb.Title = b.settings.Scene
+		}
+	case actionStream:
+		if f.streaming {
+			fb.Color = colorLive
+		}
+	case actionRecord:
+		if f.recording {
+			fb.Color = colorLive
+		}
+	case actionMute:
+		if f.muted[b.settings.Source] {
+			fb.Color = colorMuted
+		}
+	case actionSource:
+		if it, ok := f.items[key]; ok && f.enabled[it] {
+			fb.Color = colorVisible
+		}
+	}
+
+	return fb
+}
+
+// draw updates the feedback of a key if it changed. Callers hold f.mu.
+func (f *feedback) draw(key string) {
+	b, ok := f.bindings[key]
+	if !ok {
+		return
+	}
+
+	var err error
+	if fb := f.render(key, b); fb == (types.Feedback{}) {
+		err = f.drawn.Feedback(key, nil)
+	} else {
+		err = f.drawn.Feedback(key, &fb)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to draw OBS state")
+	}
+}
+
+func (f *feedback) drawAll() {
+	for key := range f.bindings {
+		f.draw(key)
+	}
+}
+
+// query reads the state a binding shows that is not followed for every
+// key, the mute state of its input or the visibility of its source. Callers
+// hold f.mu.
+func (f *feedback) query(key string, b binding) {
+	if f.client == nil {
+		return
+	}
+
+	switch b.action {
+	case actionMute:
+		var resp struct {
+			Muted bool `json:"inputMuted"`
+		}
+		if err := f.client.Request("GetInputMute", map[string]any{"inputName": b.settings.Source}, &resp); err != nil {
+			log.Debug().Err(err).Str("input", b.settings.Source).Msg("Cannot read OBS input mute")
+			return
+		}
+		f.muted[b.settings.Source] = resp.Muted
+	case actionSource:
+		scene := b.settings.Scene
+		if scene == "" {
+			scene = f.scene
+		}
+
+		_, id, err := sceneItem(f.client, scene, b.settings.Source)
+		if err != nil {
+			delete(f.items, key)
+			log.Debug().Err(err).Str("source", b.settings.Source).Msg("Cannot find OBS source")
+			return
+		}
+		it := item{scene: scene, id: id}
+		f.items[key] = it
+
+		enabled, err := sceneItemEnabled(f.client, scene, id)
+		if err != nil {
+			return
+		}
+		f.enabled[it] = enabled
+	}
+}
+
+// sync reads the state of OBS after connecting. Callers hold f.mu.
+func (f *feedback) sync() error {
+	scene, err := currentScene(f.client)
+	if err != nil {
+		return err
+	}
+	f.scene = scene
+
+	var stream, record struct {
+		Active bool `json:"outputActive"`
+	}
+	if err := f.client.Request("GetStreamStatus", nil, &stream); err != nil {
+		return err
+	}
+	if err := f.client.Request("GetRecordStatus", nil, &record); err != nil {
+		return err
+	}
+	f.streaming = stream.Active
+	f.recording = record.Active
+
+	for key, b := range f.bindings {
+		f.query(key, b)
+	}
+
+	return nil
+}
+
+// apply updates the state with an OBS event. Callers hold f.mu.
+func (f *feedback) apply(event obs.Event) {
+	switch event.Type {
+	case "CurrentProgramSceneChanged":
+		var data struct {
+			SceneName string `json:"sceneName"`
+		}
+		if json.Unmarshal(event.Data, &data) != nil {
+			return
+		}
+		f.scene = data.SceneName
+
+		// Sources of the current scene now refer to another scene.
+		for key, b := range f.bindings {
+			if b.action == actionSource && b.settings.Scene == "" {
+				f.query(key, b)
+			}
+		}
+	case "StreamStateChanged", "RecordStateChanged":
+		var data struct {
+			Active bool `json:"outputActive"`
+		}
+		if json.Unmarshal(event.Data, &data) != nil {
+			return
+		}
+		if event.Type == "StreamStateChanged" {
+			f.streaming = data.Active
+		} else {
+			f.recording = data.Active
+		}
+	case "InputMuteStateChanged":
+		var data struct {
+			InputName string `json:"inputName"`
+			Muted     bool   `json:"inputMuted"`
+		}
+		if json.Unmarshal(event.Data, &data) != nil {
+			return
+		}
+		f.muted[data.InputName] = data.Muted
+	case "SceneItemEnableStateChanged":
+		var data struct {
+			SceneName string `json:"sceneName"`
+			ID        int    `json:"sceneItemId"`
+			Enabled   bool   `json:"sceneItemEnabled"`
+		}
+		if json.Unmarshal(event.Data, &data) != nil {
+			return
+		}
+		f.enabled[item{scene: data.SceneName, id: data.ID}] = data.Enabled
+	default:
+		return
+	}
+
+	f.drawAll()
+}
+
+// follow draws the state of OBS until the connection is lost.
+func (f *feedback) follow(ctx context.Context, client *obs.Client) {
+	f.mu.Lock()
+	f.client = client
+	if err := f.sync(); err != nil {
+		log.Warn().Err(err).Msg("Cannot read the OBS state")
+	}
+	f.drawAll()
+	f.mu.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-client.Events():
+			if !ok {
+				return
+			}
+			f.mu.Lock()
+			f.apply(event)
+			f.mu.Unlock()
+		}
+	}
+}
+
+// run connects to OBS, follows its state and clears the keys while OBS is
+// not running.
+func (f *feedback) run(ctx context.Context) {
+	for {
+		client, err := f.session.get()
+		if err == nil {
+			log.Info().Msg("Connected to OBS")
+			f.follow(ctx, client)
+		} else {
+			log.Debug().Err(err).Msg("Cannot connect to OBS")
+		}
+
+		f.mu.Lock()
+		f.client = nil
+		f.drawAll()
+		f.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.session.connected:
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// update tracks a button key, drawing it when it is bound to an OBS action
+// and clearing the feedback of keys that no longer are.
+func (f *feedback) update(key string, button types.Button, put bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	action, ok := strings.CutPrefix(button.UUID, "sd.plugin.obs.")
+	if !ok {
+		if _, ok := f.bindings[key]; ok {
+			delete(f.bindings, key)
+			delete(f.items, key)
+			if err := f.drawn.Clear(key, put); err != nil {
+				log.Error().Err(err).Str("key", key).Msg("Failed to clear OBS state")
+			}
+		}
+		return
+	}
+
+	b := binding{action: types.ActionType(action), settings: button.Settings, title: button.Title}
+	previous, ok := f.bindings[key]
+	if !ok || previous.action != b.action || previous.settings.Scene != b.settings.Scene ||
+		previous.settings.Source != b.settings.Source || previous.title != b.title {
+		f.drawn.Forget(key)
+		f.bindings[key] = b
+		f.query(key, b)
+	}
+
+	f.draw(key)
+}
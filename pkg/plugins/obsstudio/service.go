@@ -0,0 +1,68 @@
+package obsstudio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sd/pkg/bindings"
+	"sd/pkg/types"
+
+	"github.com/rs/zerolog/log"
+)
+
+// OBSPlugin controls OBS Studio over obs-websocket v5 and shows its live
+// state on the keys bound to it.
+type OBSPlugin struct {
+	InstanceID string
+
+	session *session
+}
+
+// Name returns the name of the plugin.
+func (p *OBSPlugin) Name() string {
+	return "obs"
+}
+
+// Init sets up the NATS subscriptions and the OBS feedback.
+func (p *OBSPlugin) Init() {
+	p.session = newSession()
+	subscribeActions(p.InstanceID, p.session)
+
+	f := newFeedback(p.InstanceID, p.session)
+	go bindings.Watch(context.Background(), bindings.Buttons(p.InstanceID), f.update)
+	go f.run(context.Background())
+
+	log.Info().Msg("OBS plugin initialized")
+}
+
+// GetActionTypes implements actions.Plugin.
+func (p *OBSPlugin) GetActionTypes() []types.ActionType {
+	return actionTypes
+}
+
+// ValidateConfig implements actions.Plugin.
+func (p *OBSPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return validate(actionType, settings)
+}
+
+// ExecuteAction implements actions.Plugin.
+func (p *OBSPlugin) ExecuteAction(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+
+	if p.session == nil {
+		return fmt.Errorf("OBS plugin is not initialized")
+	}
+
+	c, err := p.session.get()
+	if err != nil {
+		return err
+	}
+	return execute(c, actionType, settings)
+}
@@ -0,0 +1,75 @@
+package obsstudio
+
+import (
+	"sd/pkg/env"
+	"sd/pkg/obs"
+	"sync"
+)
+
+// Environment variables locating obs-websocket. Point SD_OBS_URL at a local
+// fake server to try the plugin without OBS.
+const (
+	urlEnv      = "SD_OBS_URL"
+	passwordEnv = "SD_OBS_PASSWORD"
+	defaultURL  = "ws://localhost:4455"
+)
+
+const subscriptions = obs.SubscribeScenes | obs.SubscribeInputs | obs.SubscribeOutputs | obs.SubscribeSceneItems
+
+// session shares one obs-websocket connection between the actions and the
+// feedback, connecting on demand.
+type session struct {
+	mu        sync.Mutex
+	client    *obs.Client
+	connected chan struct{}
+}
+
+func newSession() *session {
+	return &session{connected: make(chan struct{}, 1)}
+}
+
+// current returns the connection unless it was lost. Callers hold s.mu.
+func (s *session) current() *obs.Client {
+	if s.client != nil {
+		select {
+		case <-s.client.Done():
+			s.client = nil
+		default:
+		}
+	}
+	return s.client
+}
+
+// get returns the current connection, connecting when there is none. The
+// lock is not held while dialling, so that a slow connection attempt does
+// not hold up actions.
+func (s *session) get() (*obs.Client, error) {
+	s.mu.Lock()
+	client := s.current()
+	s.mu.Unlock()
+	if client != nil {
+		return client, nil
+	}
+
+	client, err := obs.Dial(env.Get(urlEnv, defaultURL), env.Get(passwordEnv, ""), subscriptions)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current := s.current(); current != nil {
+		// Another caller connected meanwhile.
+		client.Close()
+		return current, nil
+	}
+	s.client = client
+
+	select {
+	case s.connected <- struct{}{}:
+	default:
+	}
+
+	return client, nil
+}
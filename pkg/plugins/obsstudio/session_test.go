@@ -0,0 +1,80 @@
+package obsstudio
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sd/pkg/obs"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// serveOBS identifies clients without a password, then reads until the
+// connection is closed.
+func serveOBS(conns chan<- *websocket.Conn) websocket.Handler {
+	return func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		var msg struct {
+			Op int             `json:"op"`
+			D  json.RawMessage `json:"d"`
+		}
+		if websocket.JSON.Send(ws, map[string]any{"op": obs.OpHello, "d": map[string]any{}}) != nil {
+			return
+		}
+		if websocket.JSON.Receive(ws, &msg) != nil || msg.Op != obs.OpIdentify {
+			return
+		}
+		if websocket.JSON.Send(ws, map[string]any{"op": obs.OpIdentified, "d": map[string]any{}}) != nil {
+			return
+		}
+		conns <- ws
+
+		for websocket.JSON.Receive(ws, &msg) == nil {
+		}
+	}
+}
+
+func TestSessionReconnects(t *testing.T) {
+	conns := make(chan *websocket.Conn, 4)
+	server := httptest.NewServer(serveOBS(conns))
+	defer server.Close()
+	t.Setenv(urlEnv, "ws"+strings.TrimPrefix(server.URL, "http"))
+
+	s := newSession()
+
+	first, err := s.get()
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	<-s.connected
+
+	if again, err := s.get(); err != nil || again != first {
+		t.Fatalf("get = %p, %v, want the open connection %p", again, err, first)
+	}
+
+	// OBS quits.
+	(<-conns).Close()
+	select {
+	case <-first.Done():
+	case <-time.After(time.Second):
+		t.Fatal("connection not lost")
+	}
+
+	second, err := s.get()
+	if err != nil {
+		t.Fatalf("get after the connection was lost: %v", err)
+	}
+	defer second.Close()
+	if second == first {
+		t.Error("get returned the lost connection")
+	}
+
+	select {
+	case <-s.connected:
+	default:
+		t.Error("reconnecting did not signal connected")
+	}
+}
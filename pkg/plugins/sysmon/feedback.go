@@ -0,0 +1,266 @@
+package sysmon
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sd/pkg/bindings"
+	"sd/pkg/natsconn"
+	"sd/pkg/navigation"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	actionMonitor = "sd.plugin.sysmon.monitor"
+
+	defaultInterval = 2 // seconds
+	maxInterval     = 3600
+
+	// historyLength is the number of samples a sparkline shows.
+	historyLength = 36
+)
+
+// validate checks the settings of a monitor.
+func validate(settings types.Settings) error {
+	if _, ok := labels[settings.Metric]; !ok {
+		return fmt.Errorf("unknown metric: %s", settings.Metric)
+	}
+
+	switch settings.Style {
+	case "", styleSparkline, styleGauge:
+	default:
+		return fmt.Errorf("unknown style: %s", settings.Style)
+	}
+
+	if settings.Interval < 0 || settings.Interval > maxInterval {
+		return fmt.Errorf("interval must be between 1 and %d seconds", maxInterval)
+	}
+
+	return nil
+}
+
+func interval(settings types.Settings) time.Duration {
+	if settings.Interval <= 0 {
+		return defaultInterval * time.Second
+	}
+	return time.Duration(settings.Interval) * time.Second
+}
+
+// feedback runs a monitor for every key bound to sd.plugin.sysmon.monitor
+// on the current page of its device, drawing its metric on the key at its
+// interval. Keys on other pages are not sampled.
+type feedback struct {
+	instanceID string
+
+	mu        sync.Mutex
+	bound     map[string]types.Settings      // by button key
+	locations map[string]navigation.Location // current page by device
+	monitors  map[string]monitor             // by button key
+}
+
+// monitor is the running monitor of a key.
+type monitor struct {
+	settings types.Settings
+	stop     context.CancelFunc
+}
+
+func newFeedback(instanceID string) *feedback {
+	return &feedback{
+		instanceID: instanceID,
+		bound:      make(map[string]types.Settings),
+		locations:  make(map[string]navigation.Location),
+		monitors:   make(map[string]monitor),
+	}
+}
+
+// render renders a sample and the history of a metric onto a key.
+func render(settings types.Settings, s sample, history []float64) (*types.Feedback, error) {
+	var data []byte
+	var err error
+	if settings.Style == styleGauge {
+		data, err = renderGauge(s.value, s.max)
+	} else {
+		data, err = renderSparkline(history, s.max)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Feedback{
+		Title: labels[settings.Metric] + "\n" + s.text,
+		Image: base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// run samples the metric of a key at its interval until ctx is done.
+func (f *feedback) run(ctx context.Context, key string, settings types.Settings) {
+	s := &sampler{metric: settings.Metric, target: settings.Target}
+	history := make([]float64, 0, historyLength)
+
+	ticker := time.NewTicker(interval(settings))
+	defer ticker.Stop()
+
+	var drawn string
+	failing := false
+
+	for {
+		var fb *types.Feedback
+
+		value, err := s.read()
+		if err == nil {
+			if len(history) == historyLength {
+				history = append(history[:0], history[1:]...)
+			}
+			history = append(history, value.value)
+			fb, err = render(settings, value, history)
+		}
+		if err != nil {
+			if !failing {
+				log.Warn().Err(err).Str("key", key).Msg("Cannot sample system metric")
+			}
+			fb = &types.Feedback{Title: labels[settings.Metric] + "\nn/a"}
+		}
+		failing = err != nil
+
+		if current := fb.Title + fb.Image; current != drawn && f.draw(ctx, key, fb) {
+			drawn = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// draw draws the feedback of a monitor unless it was stopped while sampling,
+// as the key may have been rebound or cleared since.
+func (f *feedback) draw(ctx context.Context, key string, fb *types.Feedback) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if ctx.Err() != nil {
+		return false
+	}
+	if err := store.SetFeedback(key, fb); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to draw system metric")
+		return false
+	}
+	return true
+}
+
+// visible reports whether a button key is on the current page of its
+// device.
+func (f *feedback) visible(key string) bool {
+	segments := strings.Split(key, ".")
+	if len(segments) != 10 {
+		return false
+	}
+	return f.locations[segments[3]] == navigation.Location{ProfileID: segments[5], PageID: segments[7]}
+}
+
+// reconcile starts the monitor of a key when it is bound and visible, and
+// stops it otherwise. A monitor whose settings changed is restarted.
+// Callers hold f.mu.
+func (f *feedback) reconcile(key string) {
+	settings, bound := f.bound[key]
+	want := bound && f.visible(key)
+
+	previous, running := f.monitors[key]
+	if running && want && previous.settings.Metric == settings.Metric &&
+		previous.settings.Target == settings.Target && previous.settings.Style == settings.Style &&
+		previous.settings.Interval == settings.Interval {
+		return
+	}
+
+	if running {
+		previous.stop()
+		delete(f.monitors, key)
+	}
+
+	if want {
+		ctx, stop := context.WithCancel(context.Background())
+		f.monitors[key] = monitor{settings: settings, stop: stop}
+		go f.run(ctx, key, settings)
+	}
+}
+
+// update follows a button key as it is bound, changed or unbound.
+func (f *feedback) update(key string, button types.Button, put bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, wasBound := f.bound[key]
+	if button.UUID == actionMonitor && validate(button.Settings) == nil {
+		f.bound[key] = button.Settings
+	} else {
+		delete(f.bound, key)
+	}
+
+	f.reconcile(key)
+
+	if _, bound := f.bound[key]; wasBound && !bound && put {
+		if err := store.SetFeedback(key, nil); err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to clear system metric")
+		}
+	}
+}
+
+// setLocation records the current page of a device and starts or stops the
+// monitors of its keys accordingly.
+func (f *feedback) setLocation(deviceID string, location navigation.Location) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.locations[deviceID] == location {
+		return
+	}
+	f.locations[deviceID] = location
+
+	for key := range f.bound {
+		if segments := strings.Split(key, "."); segments[3] == deviceID {
+			f.reconcile(key)
+		}
+	}
+}
+
+// watchLocations follows the current profile and page of the devices of
+// the instance.
+func (f *feedback) watchLocations(ctx context.Context) {
+	_, kv := natsconn.GetNATSConn()
+
+	devices := fmt.Sprintf("instances.%s.devices.*", f.instanceID)
+
+	watcher, err := kv.WatchFiltered([]string{devices, devices + ".profiles.*"})
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating watcher")
+		return
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-watcher.Updates():
+			if entry == nil {
+				continue
+			}
+			deviceID := strings.Split(entry.Key(), ".")[3]
+			f.setLocation(deviceID, navigation.Current(f.instanceID, deviceID))
+		}
+	}
+}
+
+// start follows the bindings of the instance and the pages of its devices.
+func (f *feedback) start(ctx context.Context) {
+	go f.watchLocations(ctx)
+	go bindings.Watch(ctx, bindings.Buttons(f.instanceID), f.update)
+}
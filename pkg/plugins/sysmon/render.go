@@ -0,0 +1,143 @@
+package sysmon
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sd/pkg/util"
+)
+
+const (
+	styleSparkline = "sparkline"
+	styleGauge     = "gauge"
+
+	// graphSize is the size graphs are drawn at before being scaled to keys.
+	graphSize = 144
+
+	colorLow    = "#16a34a"
+	colorMedium = "#ea580c"
+	colorHigh   = "#dc2626"
+	colorRate   = "#3b82f6"
+	colorTrack  = "#333333"
+)
+
+// levelColor returns the colour of a value: green, orange from 60% of its
+// scale and red from 85%. Rates without a scale are blue.
+func levelColor(value float64, scale float64) color.RGBA {
+	if scale <= 0 {
+		return util.ParseHexColor(colorRate)
+	}
+
+	switch fraction := value / scale; {
+	case fraction >= 0.85:
+		return util.ParseHexColor(colorHigh)
+	case fraction >= 0.6:
+		return util.ParseHexColor(colorMedium)
+	}
+	return util.ParseHexColor(colorLow)
+}
+
+func newGraph() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, graphSize, graphSize))
+	draw.Draw(img, img.Bounds(), image.Black, image.Point{}, draw.Src)
+	return img
+}
+
+// renderSparkline draws the history of a metric as a filled line, scaled to
+// scale or, when it is 0, to the highest value of the history.
+func renderSparkline(history []float64, scale float64) ([]byte, error) {
+	img := newGraph()
+	if len(history) == 0 {
+		return util.EncodeImage(img, util.ImageSpec{Format: util.FormatJPEG})
+	}
+
+	full := scale
+	if full <= 0 {
+		for _, v := range history {
+			full = max(full, v)
+		}
+		full = max(full, 1)
+	}
+
+	c := levelColor(history[len(history)-1], scale)
+	fill := color.RGBA{c.R / 3, c.G / 3, c.B / 3, 0xff}
+
+	// The newest sample is on the right, older samples scroll to the left.
+	height := float64(graphSize - 8)
+	previous := -1
+	for x := 0; x < graphSize; x++ {
+		pos := float64(x) / float64(graphSize-1) * float64(historyLength-1)
+		i := int(pos) - (historyLength - len(history))
+		if i < 0 {
+			continue
+		}
+
+		v := history[i]
+		if i+1 < len(history) {
+			frac := pos - math.Floor(pos)
+			v += (history[i+1] - v) * frac
+		}
+
+		top := graphSize - int(math.Round(min(max(v/full, 0), 1)*height))
+		for y := top; y < graphSize; y++ {
+			img.SetRGBA(x, y, fill)
+		}
+
+		// Join the line to the previous column on steep slopes.
+		from, to := top, top
+		if previous >= 0 {
+			from, to = min(top, previous), max(top, previous)
+		}
+		for y := max(from-1, 0); y < min(to+2, graphSize); y++ {
+			img.SetRGBA(x, y, c)
+		}
+		previous = top
+	}
+
+	return util.EncodeImage(img, util.ImageSpec{Format: util.FormatJPEG})
+}
+
+// renderGauge draws a value as a 240° arc filled up to its share of scale.
+func renderGauge(value float64, scale float64) ([]byte, error) {
+	img := newGraph()
+
+	fraction := 0.0
+	if scale > 0 {
+		fraction = min(max(value/scale, 0), 1)
+	}
+
+	c := levelColor(value, scale)
+	track := util.ParseHexColor(colorTrack)
+
+	const (
+		start = 150.0 // degrees, clockwise from the right
+		sweep = 240.0
+		outer = 64.0
+		inner = 48.0
+	)
+	cx, cy := float64(graphSize)/2, float64(graphSize)/2+8
+
+	for y := 0; y < graphSize; y++ {
+		for x := 0; x < graphSize; x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			r := math.Hypot(dx, dy)
+			if r < inner || r > outer {
+				continue
+			}
+
+			angle := math.Mod(math.Atan2(dy, dx)*180/math.Pi-start+720, 360)
+			if angle > sweep {
+				continue
+			}
+
+			if angle <= fraction*sweep {
+				img.SetRGBA(x, y, c)
+			} else {
+				img.SetRGBA(x, y, track)
+			}
+		}
+	}
+
+	return util.EncodeImage(img, util.ImageSpec{Format: util.FormatJPEG})
+}
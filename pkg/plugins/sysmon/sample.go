@@ -0,0 +1,203 @@
+package sysmon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/sensors"
+)
+
+const (
+	metricCPU         = "cpu"
+	metricMemory      = "memory"
+	metricDisk        = "disk"
+	metricNetwork     = "network"
+	metricTemperature = "temperature"
+	metricLoad        = "load"
+)
+
+var labels = map[string]string{
+	metricCPU:         "CPU",
+	metricMemory:      "MEM",
+	metricDisk:        "DISK",
+	metricNetwork:     "NET",
+	metricTemperature: "TEMP",
+	metricLoad:        "LOAD",
+}
+
+// sample is a reading of a metric.
+type sample struct {
+	value float64
+	max   float64 // full scale, 0 to scale to the history
+	text  string  // such as 42% or 1.2M/s
+}
+
+// sampler reads a metric, keeping the counters rates are derived from
+// between samples.
+type sampler struct {
+	metric string
+	target string
+
+	cpu   *cpu.TimesStat
+	bytes uint64
+	at    time.Time
+}
+
+func percent(value float64) sample {
+	return sample{value: value, max: 100, text: fmt.Sprintf("%.0f%%", value)}
+}
+
+// rate formats a throughput in bytes per second.
+func rate(bytes float64) string {
+	units := []string{"B", "K", "M", "G"}
+	i := 0
+	for bytes >= 1000 && i < len(units)-1 {
+		bytes /= 1000
+		i++
+	}
+	if i > 0 && bytes < 10 {
+		return fmt.Sprintf("%.1f%s/s", bytes, units[i])
+	}
+	return fmt.Sprintf("%.0f%s/s", bytes, units[i])
+}
+
+// read returns the current value of the metric. Rates are averaged since the
+// previous read, so the first read of a rate is zero.
+func (s *sampler) read() (sample, error) {
+	switch s.metric {
+	case metricCPU:
+		times, err := cpu.Times(false)
+		if err != nil || len(times) == 0 {
+			return sample{}, fmt.Errorf("failed to read CPU times: %w", err)
+		}
+		now := times[0]
+		previous := s.cpu
+		s.cpu = &now
+		if previous == nil {
+			return percent(0), nil
+		}
+
+		total := busy(now) + idle(now) - busy(*previous) - idle(*previous)
+		if total <= 0 {
+			return percent(0), nil
+		}
+		return percent(min(max((busy(now)-busy(*previous))/total*100, 0), 100)), nil
+
+	case metricMemory:
+		vm, err := mem.VirtualMemory()
+		if err != nil {
+			return sample{}, fmt.Errorf("failed to read memory: %w", err)
+		}
+		return percent(vm.UsedPercent), nil
+
+	case metricDisk:
+		path := s.target
+		if path == "" {
+			path = "/"
+		}
+		usage, err := disk.Usage(path)
+		if err != nil {
+			return sample{}, fmt.Errorf("failed to read disk usage of %s: %w", path, err)
+		}
+		return percent(usage.UsedPercent), nil
+
+	case metricNetwork:
+		counters, err := net.IOCounters(true)
+		if err != nil {
+			return sample{}, fmt.Errorf("failed to read network counters: %w", err)
+		}
+
+		var bytes uint64
+		found := false
+		for _, c := range counters {
+			if (s.target == "" && c.Name != "lo") || c.Name == s.target {
+				bytes += c.BytesRecv + c.BytesSent
+				found = true
+			}
+		}
+		if !found {
+			return sample{}, fmt.Errorf("unknown network interface: %s", s.target)
+		}
+
+		now := time.Now()
+		var value float64
+		if !s.at.IsZero() && bytes >= s.bytes {
+			value = float64(bytes-s.bytes) / now.Sub(s.at).Seconds()
+		}
+		s.bytes, s.at = bytes, now
+		return sample{value: value, text: rate(value)}, nil
+
+	case metricTemperature:
+		// Partial readings come with a warning error, use whatever was read.
+		temperatures, err := sensors.SensorsTemperatures()
+		if len(temperatures) == 0 {
+			if err == nil {
+				return sample{}, fmt.Errorf("no temperature sensors found")
+			}
+			return sample{}, fmt.Errorf("failed to read temperatures: %w", err)
+		}
+
+		t, ok := sensor(temperatures, s.target)
+		if !ok {
+			return sample{}, fmt.Errorf("unknown temperature sensor: %s", s.target)
+		}
+		return sample{value: t, max: 100, text: fmt.Sprintf("%.0f°C", t)}, nil
+
+	case metricLoad:
+		avg, err := load.Avg()
+		if err != nil {
+			return sample{}, fmt.Errorf("failed to read load average: %w", err)
+		}
+		cpus, err := cpu.Counts(true)
+		if err != nil || cpus < 1 {
+			cpus = 1
+		}
+		return sample{value: avg.Load1, max: float64(cpus), text: fmt.Sprintf("%.2f", avg.Load1)}, nil
+	}
+
+	return sample{}, fmt.Errorf("unknown metric: %s", s.metric)
+}
+
+func idle(t cpu.TimesStat) float64 {
+	return t.Idle + t.Iowait
+}
+
+func busy(t cpu.TimesStat) float64 {
+	return t.User + t.Nice + t.System + t.Irq + t.Softirq + t.Steal
+}
+
+// sensor returns the temperature of the first sensor whose key contains
+// target, or of the CPU package when target is empty, falling back to the
+// hottest sensor.
+func sensor(temperatures []sensors.TemperatureStat, target string) (float64, bool) {
+	target = strings.ToLower(target)
+
+	if target == "" {
+		for _, name := range []string{"package", "tctl", "cpu"} {
+			for _, t := range temperatures {
+				if strings.Contains(strings.ToLower(t.SensorKey), name) {
+					return t.Temperature, true
+				}
+			}
+		}
+
+		hottest := temperatures[0].Temperature
+		for _, t := range temperatures {
+			hottest = max(hottest, t.Temperature)
+		}
+		return hottest, true
+	}
+
+	for _, t := range temperatures {
+		if strings.Contains(strings.ToLower(t.SensorKey), target) {
+			return t.Temperature, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,60 @@
+package sysmon
+
+import (
+	"context"
+	"encoding/json"
+	"sd/pkg/actions"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// SysmonPlugin shows CPU, memory, disk, network, temperature and load
+// metrics as live sparklines or gauges on the keys bound to them.
+type SysmonPlugin struct {
+	InstanceID string
+}
+
+// Name returns the name of the plugin.
+func (p *SysmonPlugin) Name() string {
+	return "sysmon"
+}
+
+// Init sets up the NATS subscription and starts the monitors of the keys.
+func (p *SysmonPlugin) Init() {
+	nc, _ := natsconn.GetNATSConn()
+
+	// Monitors only draw, pressing their key does nothing.
+	if _, err := nc.Subscribe(actionMonitor, func(m *nats.Msg) {
+		if actions.ForInstance(m, p.InstanceID) {
+			actions.Reply(m, nil)
+		}
+	}); err != nil {
+		log.Error().Err(err).Msgf("Failed to subscribe to %s", actionMonitor)
+	}
+
+	newFeedback(p.InstanceID).start(context.Background())
+
+	log.Info().Msg("System monitor plugin initialized")
+}
+
+// GetActionTypes implements actions.Plugin.
+func (p *SysmonPlugin) GetActionTypes() []types.ActionType {
+	return []types.ActionType{"monitor"}
+}
+
+// ValidateConfig implements actions.Plugin.
+func (p *SysmonPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return validate(settings)
+}
+
+// ExecuteAction implements actions.Plugin.
+func (p *SysmonPlugin) ExecuteAction(actionType types.ActionType, config json.RawMessage) error {
+	return nil
+}
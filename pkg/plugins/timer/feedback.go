@@ -0,0 +1,184 @@
+package timer
+
+import (
+	"context"
+	"fmt"
+	"sd/pkg/bindings"
+	"sd/pkg/multiaction"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// tick is how often timers are redrawn. Titles only change every second but
+// flashing keys alternate twice a second.
+const tick = 250 * time.Millisecond
+
+func isTimer(uuid string) bool {
+	return uuid == actionStopwatch || uuid == actionCountdown || uuid == actionPomodoro
+}
+
+// feedback keeps the timers of the keys bound to timer actions, drawing
+// them live and saving their state to the KV store.
+type feedback struct {
+	instanceID string
+
+	mu     sync.Mutex
+	timers map[string]*timer // by button key
+	drawn  bindings.Drawn
+}
+
+func newFeedback(instanceID string) *feedback {
+	return &feedback{
+		instanceID: instanceID,
+		timers:     make(map[string]*timer),
+		drawn:      make(bindings.Drawn),
+	}
+}
+
+// save stores the state of a timer so that it survives restarts.
+func save(t *timer) {
+	if err := store.SetTimer(t.key, t.state); err != nil {
+		log.Error().Err(err).Str("key", t.key).Msg("Failed to save timer")
+	}
+}
+
+// draw draws a timer on its key when it changed since it was last drawn.
+// Callers hold f.mu.
+func (f *feedback) draw(t *timer, now time.Time) {
+	if err := f.drawn.Feedback(t.key, t.render(now)); err != nil {
+		log.Error().Err(err).Str("key", t.key).Msg("Failed to draw timer")
+	}
+}
+
+// complete runs the steps of a countdown that ran out.
+func (f *feedback) complete(t *timer) {
+	log.Info().Str("key", t.key).Msg("Countdown finished")
+
+	steps := t.settings.Steps
+	if len(steps) == 0 {
+		return
+	}
+
+	segments := strings.Split(t.key, ".")
+	device := store.GetDevice(f.instanceID, segments[3])
+	if device == nil {
+		log.Error().Str("key", t.key).Msg("Device of countdown not found")
+		return
+	}
+
+	go func() {
+		if err := multiaction.RunSteps(f.instanceID, device, t.key, "steps", steps); err != nil {
+			log.Error().Err(err).Str("key", t.key).Msg("Countdown steps stopped")
+		}
+	}()
+}
+
+// run advances and redraws the timers until ctx is done.
+func (f *feedback) run(ctx context.Context) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		f.mu.Lock()
+		now := time.Now()
+		for _, t := range f.timers {
+			changed, completed := t.advance(now, true)
+			if changed {
+				save(t)
+			}
+			if completed {
+				f.complete(t)
+			}
+			f.draw(t, now)
+		}
+		f.mu.Unlock()
+	}
+}
+
+// handle handles a press or release of a timer key.
+func (f *feedback) handle(key string, pressed bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, ok := f.timers[key]
+	if !ok {
+		button, err := store.GetButton(key)
+		if err != nil {
+			return err
+		}
+		if err := validate(button.UUID, button.Settings); err != nil {
+			return err
+		}
+		return fmt.Errorf("timer not found")
+	}
+
+	now := time.Now()
+	if pressed {
+		t.pressed = now
+		return nil
+	}
+
+	if t.release(now) {
+		save(t)
+	}
+	f.draw(t, now)
+
+	return nil
+}
+
+// update starts, changes or stops the timer of a button key as it is bound,
+// changed or unbound. The state of a new timer is loaded from the store.
+func (f *feedback) update(key string, button types.Button, put bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, running := f.timers[key]
+	bound := isTimer(button.UUID) && validate(button.UUID, button.Settings) == nil
+
+	if running && bound && t.uuid == button.UUID {
+		// Settings such as the duration changed, keep the state.
+		t.settings = button.Settings
+		f.drawn.Forget(key)
+		return
+	}
+
+	if running {
+		delete(f.timers, key)
+		store.DeleteTimer(key)
+		if err := f.drawn.Clear(key, put); err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to clear timer")
+		}
+	}
+
+	if !bound {
+		return
+	}
+
+	state, err := store.GetTimer(key)
+	if err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("Cannot restore timer")
+	}
+
+	t = &timer{key: key, uuid: button.UUID, settings: button.Settings}
+	if state != nil {
+		t.state = *state
+	}
+
+	now := time.Now()
+	t.restore(now)
+	if changed, _ := t.advance(now, false); changed {
+		save(t)
+	}
+	f.timers[key] = t
+}
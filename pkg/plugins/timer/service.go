@@ -0,0 +1,90 @@
+package timer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sd/pkg/actions"
+	"sd/pkg/bindings"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// TimerPlugin runs stopwatches, countdowns and pomodoros on keys. A tap
+// starts or pauses a timer and holding its key resets it. Timers are drawn
+// live on their key and kept in the KV store so that they keep running
+// across server restarts.
+type TimerPlugin struct {
+	InstanceID string
+}
+
+// Name returns the name of the plugin.
+func (p *TimerPlugin) Name() string {
+	return "timer"
+}
+
+// Init sets up the NATS subscriptions and starts the timers of the keys.
+func (p *TimerPlugin) Init() {
+	nc, _ := natsconn.GetNATSConn()
+	f := newFeedback(p.InstanceID)
+
+	// Presses and releases share a channel so that they are handled in the
+	// order they were sent.
+	ch := make(chan *nats.Msg, 64)
+	for _, uuid := range []string{actionStopwatch, actionCountdown, actionPomodoro} {
+		for _, subject := range []string{uuid, actions.ReleaseSubject(uuid)} {
+			if _, err := nc.ChanSubscribe(subject, ch); err != nil {
+				log.Error().Err(err).Msgf("Failed to subscribe to %s", subject)
+			}
+		}
+	}
+
+	go func() {
+		for m := range ch {
+			if !actions.ForInstance(m, p.InstanceID) {
+				continue
+			}
+
+			key := actions.ButtonKey(m)
+			if key == "" {
+				actions.Reply(m, fmt.Errorf("timers only run on keys"))
+				continue
+			}
+
+			// Releases come on the release subject of the action.
+			err := f.handle(key, isTimer(m.Subject))
+			if err != nil {
+				log.Error().Err(err).Str("key", key).Msg("Timer action failed")
+			}
+			actions.Reply(m, err)
+		}
+	}()
+
+	go bindings.Watch(context.Background(), bindings.Buttons(p.InstanceID), f.update)
+	go f.run(context.Background())
+
+	log.Info().Msg("Timer plugin initialized")
+}
+
+// GetActionTypes implements actions.Plugin.
+func (p *TimerPlugin) GetActionTypes() []types.ActionType {
+	return actionTypes
+}
+
+// ValidateConfig implements actions.Plugin.
+func (p *TimerPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return validate("sd.plugin.timer."+string(actionType), settings)
+}
+
+// ExecuteAction implements actions.Plugin. Timers keep their state on the
+// key they are bound to, so they only run from stored buttons.
+func (p *TimerPlugin) ExecuteAction(actionType types.ActionType, config json.RawMessage) error {
+	return fmt.Errorf("timers only run from stored buttons")
+}
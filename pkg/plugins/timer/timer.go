@@ -0,0 +1,297 @@
+package timer
+
+import (
+	"fmt"
+	"sd/pkg/types"
+	"strings"
+	"time"
+)
+
+const (
+	actionStopwatch = "sd.plugin.timer.stopwatch"
+	actionCountdown = "sd.plugin.timer.countdown"
+	actionPomodoro  = "sd.plugin.timer.pomodoro"
+
+	// holdReset is how long a key is held to reset its timer.
+	holdReset = 800 * time.Millisecond
+
+	// A finished countdown flashes until it is pressed or for flashFor, a
+	// pomodoro flashes for phaseFlash when a phase ends.
+	flashFor   = 10 * time.Second
+	phaseFlash = 3 * time.Second
+
+	maxDuration = 99 * time.Hour
+
+	defaultWork      = 25 * time.Minute
+	defaultBreak     = 5 * time.Minute
+	defaultLongBreak = 15 * time.Minute
+
+	// A pomodoro cycle is four work phases, each followed by a break. The
+	// last break is a long one.
+	pomodoroPhases = 8
+
+	colorWork    = "#dc2626"
+	colorBreak   = "#16a34a"
+	colorRunning = "#ea580c"
+	colorPaused  = "#333333"
+	colorFlash   = "#000000"
+)
+
+var actionTypes = []types.ActionType{"stopwatch", "countdown", "pomodoro"}
+
+// parseDuration parses a duration such as 5m or 1h30m, returning fallback
+// when value is empty. A zero fallback makes the duration required.
+func parseDuration(value string, fallback time.Duration) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if fallback == 0 {
+			return 0, fmt.Errorf("a duration is required")
+		}
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, use a duration such as 5m or 1h30m", value)
+	}
+	if d < time.Second || d > maxDuration {
+		return 0, fmt.Errorf("duration must be between 1s and %s", maxDuration)
+	}
+
+	return d, nil
+}
+
+// validate checks the settings of a timer action.
+func validate(uuid string, settings types.Settings) error {
+	switch uuid {
+	case actionStopwatch:
+		return nil
+	case actionCountdown:
+		_, err := parseDuration(settings.Duration, 0)
+		return err
+	case actionPomodoro:
+		for _, d := range []struct {
+			value    string
+			fallback time.Duration
+		}{
+			{settings.Duration, defaultWork},
+			{settings.Break, defaultBreak},
+			{settings.LongBreak, defaultLongBreak},
+		} {
+			if _, err := parseDuration(d.value, d.fallback); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown timer: %s", uuid)
+}
+
+func isWork(phase int) bool {
+	return phase%2 == 0
+}
+
+// phaseLength returns the length of a pomodoro phase.
+func phaseLength(settings types.Settings, phase int) time.Duration {
+	value, fallback := settings.Break, defaultBreak
+	switch {
+	case isWork(phase):
+		value, fallback = settings.Duration, defaultWork
+	case phase%pomodoroPhases == pomodoroPhases-1:
+		value, fallback = settings.LongBreak, defaultLongBreak
+	}
+
+	d, err := parseDuration(value, fallback)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
+
+// format formats a duration as m:ss, or h:mm:ss from an hour.
+func format(d time.Duration) string {
+	seconds := int(max(d, 0) / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// timer is a timer bound to a key.
+type timer struct {
+	key      string
+	uuid     string
+	settings types.Settings
+	state    types.TimerState
+
+	pressed time.Time // when the key was pressed, zero while it is up
+	flash   time.Time // when the key stops flashing
+}
+
+// elapsed returns how long the timer, or the current pomodoro phase, ran.
+func (t *timer) elapsed(now time.Time) time.Duration {
+	d := time.Duration(t.state.Elapsed) * time.Millisecond
+	if t.state.Running {
+		d += now.Sub(t.state.StartedAt)
+	}
+	return d
+}
+
+// length returns the length of a countdown or of the current pomodoro
+// phase. Stopwatches have none.
+func (t *timer) length() time.Duration {
+	switch t.uuid {
+	case actionCountdown:
+		d, _ := parseDuration(t.settings.Duration, time.Second)
+		return d
+	case actionPomodoro:
+		return phaseLength(t.settings, t.state.Phase)
+	}
+	return 0
+}
+
+// restore fixes the state loaded for a timer. A timer started in the future,
+// by a clock set back, is paused.
+func (t *timer) restore(now time.Time) {
+	if t.state.Running && (t.state.StartedAt.IsZero() || t.state.StartedAt.After(now)) {
+		t.state.Running = false
+		t.state.StartedAt = time.Time{}
+	}
+	t.state.Phase %= pomodoroPhases
+}
+
+// advance brings the timer to now: it ends a countdown that ran out and
+// moves a pomodoro to its next phases. The key flashes only when live, not
+// when catching up on time the server was down. It reports whether the state
+// changed and whether a countdown just completed.
+func (t *timer) advance(now time.Time, live bool) (changed bool, completed bool) {
+	if !t.state.Running {
+		return false, false
+	}
+
+	switch t.uuid {
+	case actionCountdown:
+		length := t.length()
+		if t.elapsed(now) < length {
+			return false, false
+		}
+		t.state = types.TimerState{Elapsed: length.Milliseconds(), Done: true}
+		if live {
+			t.flash = now.Add(flashFor)
+		}
+		return true, live
+
+	case actionPomodoro:
+		// Phases are counted from the start of the current one.
+		if t.state.Elapsed != 0 {
+			t.state.StartedAt = t.state.StartedAt.Add(-time.Duration(t.state.Elapsed) * time.Millisecond)
+			t.state.Elapsed = 0
+		}
+
+		// Skip whole cycles the server was down for.
+		var cycle time.Duration
+		for phase := range pomodoroPhases {
+			cycle += phaseLength(t.settings, phase)
+		}
+		if over := t.elapsed(now) - cycle; over > 0 {
+			t.state.StartedAt = t.state.StartedAt.Add((over/cycle + 1) * cycle)
+			changed = true
+		}
+
+		for length := t.length(); t.elapsed(now) >= length; length = t.length() {
+			t.state.StartedAt = t.state.StartedAt.Add(length)
+			t.state.Phase = (t.state.Phase + 1) % pomodoroPhases
+			changed = true
+		}
+		if changed && live {
+			t.flash = now.Add(phaseFlash)
+		}
+		return changed, false
+	}
+
+	return false, false
+}
+
+// release handles the key of the timer being let go: a tap starts or pauses
+// it, or silences it while it flashes, and a hold resets it. A finished
+// countdown is reset by any press. It reports whether the state changed.
+func (t *timer) release(now time.Time) bool {
+	if t.pressed.IsZero() {
+		return false
+	}
+	held := now.Sub(t.pressed)
+	t.pressed = time.Time{}
+
+	flashing := t.flash.After(now)
+	t.flash = time.Time{}
+
+	switch {
+	case held >= holdReset || t.state.Done:
+		t.state = types.TimerState{}
+	case flashing:
+		return false
+	case t.state.Running:
+		t.state.Elapsed = t.elapsed(now).Milliseconds()
+		t.state.Running = false
+		t.state.StartedAt = time.Time{}
+	default:
+		t.state.Running = true
+		t.state.StartedAt = now
+	}
+
+	return true
+}
+
+// render returns the feedback showing the timer on its key.
+func (t *timer) render(now time.Time) *types.Feedback {
+	elapsed := t.elapsed(now)
+	idle := !t.state.Running && elapsed == 0
+
+	fb := &types.Feedback{}
+	switch t.uuid {
+	case actionStopwatch:
+		fb.Title = format(elapsed)
+		if t.state.Running {
+			fb.Color = colorBreak
+		}
+
+	case actionCountdown:
+		// Round up so that 0:00 shows only once the countdown is done.
+		fb.Title = format(t.length() - elapsed + time.Second - time.Millisecond)
+		switch {
+		case t.state.Done:
+			fb.Color = colorWork
+		case t.state.Running:
+			fb.Color = colorRunning
+		}
+
+	case actionPomodoro:
+		label := "Work"
+		if !isWork(t.state.Phase) {
+			label = "Break"
+			if t.state.Phase == pomodoroPhases-1 {
+				label = "Long break"
+			}
+		}
+		fb.Title = label + "\n" + format(t.length()-elapsed+time.Second-time.Millisecond)
+		switch {
+		case t.state.Running && isWork(t.state.Phase):
+			fb.Color = colorWork
+		case t.state.Running:
+			fb.Color = colorBreak
+		}
+		idle = idle && t.state.Phase == 0
+	}
+
+	if fb.Color == "" && !idle && !t.state.Done {
+		fb.Color = colorPaused
+	}
+
+	// Flash by alternating with black twice a second.
+	if t.flash.After(now) && now.UnixMilli()/500%2 == 1 {
+		fb.Color = colorFlash
+	}
+
+	return fb
+}
@@ -0,0 +1,313 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sd/pkg/env"
+	"sd/pkg/types"
+	"sd/pkg/util"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	authBasic  = "basic"
+	authBearer = "bearer"
+
+	defaultTimeout = 10 * time.Second
+	maxRetries     = 5
+	retryDelay     = time.Second
+	responseLimit  = 1 << 20
+)
+
+var methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// errRetry marks failures worth another attempt, such as a timeout or a
+// 503 response.
+var errRetry = errors.New("temporary failure")
+
+// errUnsent marks temporary failures of requests the server did not act on,
+// such as a refused connection or a 429 response, which are retried for
+// every method.
+var errUnsent = errors.New("request not processed")
+
+// request is a webhook with its templates expanded.
+type request struct {
+	method string
+	url    string
+	body   string
+	header http.Header
+}
+
+func method(settings types.Settings) string {
+	if settings.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(settings.Method)
+}
+
+// parseHeaders parses one "Name: value" header per line, expanding
+// ${SD_BUTTON_NAME} in values. Other dollars are kept.
+func parseHeaders(text string) (http.Header, error) {
+	header := make(http.Header)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header %q, expected Name: value", line)
+		}
+		header.Add(name, env.ExpandButton(strings.TrimSpace(value)))
+	}
+	return header, nil
+}
+
+// validate checks the settings of a webhook.
+func validate(settings types.Settings) error {
+	u, err := url.Parse(settings.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("URL must start with http:// or https://")
+	}
+
+	if !slices.Contains(methods, method(settings)) {
+		return fmt.Errorf("unsupported method: %s", settings.Method)
+	}
+
+	switch settings.Auth {
+	case "", "none":
+	case authBasic, authBearer:
+		if settings.Credentials == "" {
+			return fmt.Errorf("credentials are empty")
+		}
+	default:
+		return fmt.Errorf("unknown authentication: %s", settings.Auth)
+	}
+
+	if _, err := parseHeaders(settings.Headers); err != nil {
+		return err
+	}
+	if settings.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+	if settings.Retries < 0 || settings.Retries > maxRetries {
+		return fmt.Errorf("retries must be between 0 and %d", maxRetries)
+	}
+
+	return nil
+}
+
+// jsonEscape escapes a value inserted into a JSON string.
+func jsonEscape(value string, _ int) string {
+	data, _ := json.Marshal(value)
+	return string(data[1 : len(data)-1])
+}
+
+// prepare expands the templates of a webhook. Values inserted into a JSON
+// body are escaped for JSON strings, and those inserted into the URL are
+// query escaped except at its start.
+func prepare(settings types.Settings) (*request, error) {
+	if err := validate(settings); err != nil {
+		return nil, err
+	}
+
+	header, _ := parseHeaders(settings.Headers)
+
+	u, err := util.ExpandVariables(settings.URL, func(value string, offset int) string {
+		if offset == 0 {
+			return value
+		}
+		return url.QueryEscape(value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body := settings.Body
+	if body != "" {
+		if header.Get("Content-Type") == "" {
+			contentType := "text/plain; charset=utf-8"
+			if trimmed := strings.TrimSpace(body); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+				contentType = "application/json"
+			}
+			header.Set("Content-Type", contentType)
+		}
+
+		escape := func(value string, _ int) string { return value }
+		if strings.Contains(header.Get("Content-Type"), "json") {
+			escape = jsonEscape
+		}
+		if body, err = util.ExpandVariables(body, escape); err != nil {
+			return nil, err
+		}
+	}
+
+	credentials := env.ExpandButton(settings.Credentials)
+	switch settings.Auth {
+	case authBasic:
+		user, password, _ := strings.Cut(credentials, ":")
+		req := http.Request{Header: header}
+		req.SetBasicAuth(user, password)
+	case authBearer:
+		header.Set("Authorization", "Bearer "+credentials)
+	}
+
+	return &request{method: method(settings), url: u, body: body, header: header}, nil
+}
+
+// do sends a request once, returning its status and response body.
+func (r *request) do(timeout time.Duration) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, r.method, r.url, strings.NewReader(r.body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header = r.header.Clone()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return 0, nil, fmt.Errorf("%w: %w: %w", errRetry, errUnsent, err)
+		}
+		return 0, nil, fmt.Errorf("%w: %w", errRetry, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, responseLimit))
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("%w: %w", errRetry, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err = fmt.Errorf("HTTP %d", resp.StatusCode)
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			err = fmt.Errorf("%w: %w: %w", errRetry, errUnsent, err)
+		case resp.StatusCode >= 500:
+			err = fmt.Errorf("%w: %w", errRetry, err)
+		}
+		return resp.StatusCode, data, err
+	}
+
+	return resp.StatusCode, data, nil
+}
+
+// retryable reports whether a failed attempt of a webhook may be tried
+// again. A POST, PUT or PATCH the server may have acted on, such as one
+// answered with a 502, is only sent again when its settings opt in, as it
+// could otherwise create or charge twice.
+func retryable(settings types.Settings, method string, err error) bool {
+	if !errors.Is(err, errRetry) {
+		return false
+	}
+
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return settings.RetryUnsafe || errors.Is(err, errUnsent)
+	}
+	return true
+}
+
+// send sends a webhook, trying again after temporary failures up to the
+// retries of its settings with a growing delay.
+func send(settings types.Settings) (int, []byte, error) {
+	r, err := prepare(settings)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	timeout := defaultTimeout
+	if settings.Timeout > 0 {
+		timeout = time.Duration(settings.Timeout) * time.Second
+	}
+
+	for attempt := 0; ; attempt++ {
+		status, data, err := r.do(timeout)
+		if err == nil || !retryable(settings, r.method, err) || attempt >= settings.Retries {
+			return status, data, err
+		}
+		time.Sleep(time.Duration(attempt+1) * retryDelay)
+	}
+}
+
+// extract returns the value at a dotted path of a JSON document, such as
+// data.items.0.status. Objects and arrays are returned as JSON.
+func extract(data []byte, path string) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("response is not JSON: %w", err)
+	}
+
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path != "" {
+		for _, segment := range strings.Split(path, ".") {
+			switch v := value.(type) {
+			case map[string]any:
+				var ok bool
+				if value, ok = v[segment]; !ok {
+					return "", fmt.Errorf("no %q in response", segment)
+				}
+			case []any:
+				i, err := strconv.Atoi(segment)
+				if err != nil || i < 0 || i >= len(v) {
+					return "", fmt.Errorf("no index %q in response", segment)
+				}
+				value = v[i]
+			default:
+				return "", fmt.Errorf("no %q in response", segment)
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case nil:
+		return "null", nil
+	default:
+		out, err := json.Marshal(v)
+		return string(out), err
+	}
+}
+
+// summary returns the title shown for the outcome of a webhook: the value at
+// its JSON path, or its status.
+func summary(settings types.Settings, status int, data []byte, err error) string {
+	var title string
+	switch {
+	case err != nil && status == 0:
+		title = "failed"
+	case err != nil:
+		title = fmt.Sprintf("HTTP %d", status)
+	case settings.JSONPath != "":
+		if title, err = extract(data, settings.JSONPath); err != nil {
+			title = "no value"
+		}
+	default:
+		title = strconv.Itoa(status)
+	}
+
+	return title
+}
@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"net/http"
+	"sd/pkg/types"
+	"testing"
+)
+
+func TestPrepareKeepsLiteralDollars(t *testing.T) {
+	t.Setenv("SD_BUTTON_TOKEN", "abc")
+
+	settings := types.Settings{
+		URL:         "https://example.com/",
+		Headers:     "X-Price: $5\nX-Token: ${SD_BUTTON_TOKEN}\nX-Hash: $2y$10$abc",
+		Auth:        authBasic,
+		Credentials: "user:pa$$word",
+	}
+
+	r, err := prepare(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"X-Price": "$5", "X-Token": "abc", "X-Hash": "$2y$10$abc"} {
+		if got := r.header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	user, password, ok := (&http.Request{Header: r.header}).BasicAuth()
+	if !ok || user != "user" || password != "pa$$word" {
+		t.Errorf("basic auth = %q, %q, want user, pa$$word", user, password)
+	}
+
+	settings.Auth = authBearer
+	settings.Credentials = "$SD_BUTTON_TOKEN$"
+	if r, err = prepare(settings); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.header.Get("Authorization"); got != "Bearer abc$" {
+		t.Errorf("Authorization = %q, want Bearer abc$", got)
+	}
+}
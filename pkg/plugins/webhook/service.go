@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sd/pkg/actions"
+	"sd/pkg/multiaction"
+	"sd/pkg/natsconn"
+	"sd/pkg/outcome"
+	"sd/pkg/types"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+const actionRequest = "sd.plugin.webhook.request"
+
+// WebhookPlugin sends HTTP requests, such as CI triggers, and shows their
+// outcome or a value of their JSON response on the key.
+type WebhookPlugin struct {
+	InstanceID string
+}
+
+// Name returns the name of the plugin.
+func (p *WebhookPlugin) Name() string {
+	return "webhook"
+}
+
+// Init sets up the NATS subscription for this plugin.
+func (p *WebhookPlugin) Init() {
+	nc, _ := natsconn.GetNATSConn()
+
+	if _, err := nc.Subscribe(actionRequest, func(m *nats.Msg) {
+		// Credentials may read the environment, so only webhooks saved on a
+		// button of this instance are sent.
+		settings, err := multiaction.StoredSettings(p.InstanceID, m)
+		if errors.Is(err, multiaction.ErrOtherInstance) {
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Webhook rejected")
+			actions.Reply(m, err)
+			return
+		}
+
+		// Retries may take a while, do not hold up other presses.
+		go func() {
+			status, data, err := send(settings)
+			if err != nil {
+				log.Error().Err(err).Str("url", settings.URL).Msg("Webhook failed")
+			}
+			outcome.Show(actions.OwnKey(m), settings.Output, err, func() string {
+				return summary(settings, status, data, err)
+			})
+			actions.Reply(m, err)
+		}()
+	}); err != nil {
+		log.Fatal().Err(err).Msgf("Failed to subscribe to %s", actionRequest)
+	}
+
+	log.Info().Msg("Webhook plugin initialized")
+}
+
+// GetActionTypes implements actions.Plugin.
+func (p *WebhookPlugin) GetActionTypes() []types.ActionType {
+	return []types.ActionType{"request"}
+}
+
+// ValidateConfig implements actions.Plugin.
+func (p *WebhookPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return validate(settings)
+}
+
+// ExecuteAction implements actions.Plugin. Webhooks only run for buttons
+// stored on this instance, never from a free-form configuration.
+func (p *WebhookPlugin) ExecuteAction(actionType types.ActionType, config json.RawMessage) error {
+	return fmt.Errorf("webhooks only run from stored buttons")
+}
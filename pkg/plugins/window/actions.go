@@ -0,0 +1,201 @@
+package window
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sd/pkg/actions"
+	"sd/pkg/focus"
+	"sd/pkg/multiaction"
+	"sd/pkg/natsconn"
+	"sd/pkg/plugins/command"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"syscall"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	actionFocus    types.ActionType = "focus"
+	actionRaise    types.ActionType = "raise"
+	actionMinimize types.ActionType = "minimize"
+	actionMaximize types.ActionType = "maximize"
+	actionMonitor  types.ActionType = "monitor"
+	actionClose    types.ActionType = "close"
+	actionLaunch   types.ActionType = "launch"
+)
+
+var actionTypes = []types.ActionType{
+	actionFocus, actionRaise, actionMinimize, actionMaximize, actionMonitor, actionClose, actionLaunch,
+}
+
+func subject(t types.ActionType) string {
+	return "sd.plugin.window." + string(t)
+}
+
+// matchesActive reports whether an action without a class or title targets
+// the active window.
+func matchesActive(t types.ActionType, settings types.Settings) bool {
+	return settings.Class == "" && settings.Window == "" && t != actionFocus && t != actionLaunch
+}
+
+// validate checks the settings of an action.
+func validate(t types.ActionType, settings types.Settings) error {
+	switch t {
+	case actionFocus, actionRaise, actionMinimize, actionMaximize, actionMonitor, actionClose, actionLaunch:
+	default:
+		return fmt.Errorf("unknown window action: %s", t)
+	}
+
+	if _, err := focus.NewMatcher(settings.Class, settings.Window); err != nil {
+		return err
+	}
+	if settings.Class == "" && settings.Window == "" && (t == actionFocus || t == actionLaunch) {
+		return fmt.Errorf("a window class or title is required")
+	}
+
+	switch t {
+	case actionMonitor:
+		return validMonitor(settings.Monitor)
+	case actionLaunch:
+		if settings.Command == "" {
+			return fmt.Errorf("command is empty")
+		}
+	}
+
+	return nil
+}
+
+// launch starts the command of a launch action in its own session, so that
+// the application outlives the server. Like commands of the command plugin,
+// it is recorded in the command audit log.
+func launch(m *nats.Msg, name string) error {
+	command.Audit(m, name, "started", nil)
+
+	cmd := exec.Command("sh", "-c", name)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		err = fmt.Errorf("failed to start %s: %w", name, err)
+		command.Audit(m, name, "failed", err)
+		return err
+	}
+	go cmd.Wait()
+
+	return nil
+}
+
+// execute runs an action on the windows matching its settings. Launch
+// actions need the message they were sent with, for the audit log.
+func execute(m *nats.Msg, t types.ActionType, settings types.Settings) error {
+	if err := validate(t, settings); err != nil {
+		return err
+	}
+
+	matcher, _ := focus.NewMatcher(settings.Class, settings.Window)
+
+	// Focusing goes through i3 and sway when they run.
+	if t == actionFocus || t == actionLaunch {
+		found, err := focus.Raise(matcher)
+		if err != nil {
+			return err
+		}
+		if found {
+			return nil
+		}
+		if t == actionFocus {
+			return fmt.Errorf("no window matches")
+		}
+		return launch(m, settings.Command)
+	}
+
+	xu, err := connect()
+	if err != nil {
+		return err
+	}
+	defer xu.Conn().Close()
+
+	win, err := target(xu, matcher, matchesActive(t, settings))
+	if err != nil {
+		return err
+	}
+
+	switch t {
+	case actionRaise:
+		err = raise(xu, win)
+	case actionMinimize:
+		err = minimize(xu, win)
+	case actionMaximize:
+		err = maximize(xu, win)
+	case actionMonitor:
+		err = moveToMonitor(xu, win, settings.Monitor)
+	case actionClose:
+		err = closeWindow(xu, win)
+	}
+	if err != nil {
+		return err
+	}
+
+	return focus.Flush(xu)
+}
+
+// settingsOf returns the settings of the button a message was sent for.
+// Launch actions run a command, so like the command plugin they only run
+// commands saved on a button of this instance and on its allowlist.
+func settingsOf(instanceID string, t types.ActionType, m *nats.Msg) (types.Settings, error) {
+	if t != actionLaunch {
+		var button types.Button
+		if err := json.Unmarshal(m.Data, &button); err != nil {
+			return types.Settings{}, err
+		}
+		return button.Settings, nil
+	}
+
+	settings, err := multiaction.StoredSettings(instanceID, m)
+	if err != nil {
+		return types.Settings{}, err
+	}
+
+	allowed, err := store.CommandAllowed(instanceID, settings.Command)
+	if err != nil {
+		return types.Settings{}, err
+	}
+	if !allowed {
+		return settings, fmt.Errorf("command is not in the allowlist")
+	}
+
+	return settings, nil
+}
+
+// subscribeActions sets up the NATS subscriptions of the plugin.
+func subscribeActions(instanceID string) {
+	nc, _ := natsconn.GetNATSConn()
+
+	for _, t := range actionTypes {
+		t := t
+		if _, err := nc.Subscribe(subject(t), func(m *nats.Msg) {
+			if !actions.ForInstance(m, instanceID) {
+				return
+			}
+
+			settings, err := settingsOf(instanceID, t, m)
+			if errors.Is(err, multiaction.ErrOtherInstance) {
+				return
+			}
+			if err != nil && t == actionLaunch {
+				command.Audit(m, settings.Command, "rejected", err)
+			}
+			if err == nil {
+				err = execute(m, t, settings)
+			}
+			if err != nil {
+				log.Error().Err(err).Str("action", string(t)).Msg("Window action failed")
+			}
+			actions.Reply(m, err)
+		}); err != nil {
+			log.Error().Err(err).Str("action", string(t)).Msg("Failed to subscribe")
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package window
+
+import (
+	"encoding/json"
+	"fmt"
+	"sd/pkg/types"
+
+	"github.com/rs/zerolog/log"
+)
+
+// WindowPlugin focuses, raises, minimizes, maximizes, moves and closes the
+// windows matching a class and title through EWMH, and launches an
+// application when none of its windows is open. Actions other than focus
+// and launch target the active window when no class or title is set.
+type WindowPlugin struct {
+	InstanceID string
+}
+
+// Name returns the name of the plugin.
+func (p *WindowPlugin) Name() string {
+	return "window"
+}
+
+// Init sets up the NATS subscriptions for this plugin.
+func (p *WindowPlugin) Init() {
+	subscribeActions(p.InstanceID)
+
+	log.Info().Msg("Window plugin initialized")
+}
+
+// GetActionTypes implements actions.Plugin.
+func (p *WindowPlugin) GetActionTypes() []types.ActionType {
+	return actionTypes
+}
+
+// ValidateConfig implements actions.Plugin.
+func (p *WindowPlugin) ValidateConfig(actionType types.ActionType, config json.RawMessage) error {
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return validate(actionType, settings)
+}
+
+// ExecuteAction implements actions.Plugin. Launch actions only run from
+// stored buttons, never from a free-form configuration.
+func (p *WindowPlugin) ExecuteAction(actionType types.ActionType, config json.RawMessage) error {
+	if actionType == actionLaunch {
+		return fmt.Errorf("launch actions only run from stored buttons")
+	}
+
+	var settings types.Settings
+	if err := json.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	return execute(nil, actionType, settings)
+}
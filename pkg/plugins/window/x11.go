@@ -0,0 +1,157 @@
+package window
+
+import (
+	"fmt"
+	"sd/pkg/focus"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/robotn/xgb/xproto"
+	"github.com/robotn/xgbutil"
+	"github.com/robotn/xgbutil/ewmh"
+	"github.com/robotn/xgbutil/icccm"
+	"github.com/robotn/xgbutil/xinerama"
+	"github.com/robotn/xgbutil/xrect"
+	"github.com/robotn/xgbutil/xwindow"
+)
+
+const (
+	monitorNext     = "next"
+	monitorPrevious = "previous"
+
+	stateMaximizedVert = "_NET_WM_STATE_MAXIMIZED_VERT"
+	stateMaximizedHorz = "_NET_WM_STATE_MAXIMIZED_HORZ"
+)
+
+// connect opens a connection to the X server.
+func connect() (*xgbutil.XUtil, error) {
+	xu, err := xgbutil.NewConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X: %w", err)
+	}
+	return xu, nil
+}
+
+// target returns the first window matching m, or the active window when
+// active is set.
+func target(xu *xgbutil.XUtil, m focus.Matcher, active bool) (xproto.Window, error) {
+	if active {
+		win, err := ewmh.ActiveWindowGet(xu)
+		if err != nil || win == 0 {
+			return 0, fmt.Errorf("no active window")
+		}
+		return win, nil
+	}
+
+	windows, err := focus.MatchX11(xu, m)
+	if err != nil {
+		return 0, err
+	}
+	if len(windows) == 0 {
+		return 0, fmt.Errorf("no window matches")
+	}
+	return windows[0], nil
+}
+
+// raise puts a window on top of the others without focusing it.
+func raise(xu *xgbutil.XUtil, win xproto.Window) error {
+	return ewmh.RestackWindow(xu, win)
+}
+
+// minimize iconifies a window.
+func minimize(xu *xgbutil.XUtil, win xproto.Window) error {
+	return ewmh.ClientEvent(xu, win, "WM_CHANGE_STATE", icccm.StateIconic)
+}
+
+// maximize maximizes a window, or restores it when it is maximized.
+func maximize(xu *xgbutil.XUtil, win xproto.Window) error {
+	return ewmh.WmStateReqExtra(xu, win, ewmh.StateToggle, stateMaximizedVert, stateMaximizedHorz, 2)
+}
+
+// closeWindow asks the window manager to close a window.
+func closeWindow(xu *xgbutil.XUtil, win xproto.Window) error {
+	return ewmh.CloseWindow(xu, win)
+}
+
+// validMonitor checks a monitor setting.
+func validMonitor(monitor string) error {
+	switch monitor {
+	case "", monitorNext, monitorPrevious:
+		return nil
+	}
+	if n, err := strconv.Atoi(monitor); err != nil || n < 1 {
+		return fmt.Errorf("monitor must be a number from 1, next or previous")
+	}
+	return nil
+}
+
+// headIndex returns the index of the monitor the centre of r is on.
+func headIndex(heads xinerama.Heads, r xrect.Rect) int {
+	cx, cy := r.X()+r.Width()/2, r.Y()+r.Height()/2
+	for i, h := range heads {
+		if cx >= h.X() && cx < h.X()+h.Width() && cy >= h.Y() && cy < h.Y()+h.Height() {
+			return i
+		}
+	}
+	return 0
+}
+
+// moveToMonitor moves a window to a monitor, keeping its position relative
+// to the monitor. Monitors are numbered from the left. A maximized window
+// is maximized again on its new monitor.
+func moveToMonitor(xu *xgbutil.XUtil, win xproto.Window, monitor string) error {
+	heads, err := xinerama.PhysicalHeads(xu)
+	if err != nil {
+		return fmt.Errorf("failed to list monitors: %w", err)
+	}
+	if len(heads) == 0 {
+		return fmt.Errorf("no monitor found")
+	}
+
+	w := xwindow.New(xu, win)
+	geometry, err := w.DecorGeometry()
+	if err != nil {
+		return fmt.Errorf("failed to get window geometry: %w", err)
+	}
+
+	from := headIndex(heads, geometry)
+	to := from
+	switch monitor {
+	case "", monitorNext:
+		to = (from + 1) % len(heads)
+	case monitorPrevious:
+		to = (from + len(heads) - 1) % len(heads)
+	default:
+		n, _ := strconv.Atoi(monitor)
+		if n > len(heads) {
+			return fmt.Errorf("monitor %d not found, there are %d", n, len(heads))
+		}
+		to = n - 1
+	}
+	if to == from {
+		return nil
+	}
+
+	states, _ := ewmh.WmStateGet(xu, win)
+	maximized := slices.ContainsFunc(states, func(state string) bool {
+		return strings.HasPrefix(state, "_NET_WM_STATE_MAXIMIZED_")
+	})
+	if maximized {
+		if err := ewmh.WmStateReqExtra(xu, win, ewmh.StateRemove, stateMaximizedVert, stateMaximizedHorz, 2); err != nil {
+			return err
+		}
+	}
+
+	source, dest := heads[from], heads[to]
+	x := dest.X() + min(max(geometry.X()-source.X(), 0), max(dest.Width()-geometry.Width(), 0))
+	y := dest.Y() + min(max(geometry.Y()-source.Y(), 0), max(dest.Height()-geometry.Height(), 0))
+	if err := w.WMMove(x, y); err != nil {
+		return err
+	}
+
+	if maximized {
+		return ewmh.WmStateReqExtra(xu, win, ewmh.StateAdd, stateMaximizedVert, stateMaximizedHorz, 2)
+	}
+	return nil
+}
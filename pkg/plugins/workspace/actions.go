@@ -0,0 +1,178 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sd/pkg/actions"
+	"sd/pkg/i3"
+	"sd/pkg/multiaction"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	actionWorkspace       = "sd.plugin.i3.workspace"
+	actionMoveToWorkspace = "sd.plugin.i3.move_to_workspace"
+	actionCommand         = "sd.plugin.i3.command"
+)
+
+// resolve finds the workspace a setting refers to: "#n" is the n-th
+// workspace, anything else a workspace name or number.
+func resolve(setting string, workspaces []i3.Workspace) (i3.Workspace, bool) {
+	if index, ok := strings.CutPrefix(setting, "#"); ok {
+		n, err := strconv.Atoi(index)
+		if err != nil || n < 1 || n > len(workspaces) {
+			return i3.Workspace{}, false
+		}
+		return workspaces[n-1], true
+	}
+
+	for _, workspace := range workspaces {
+		if workspace.Name == setting {
+			return workspace, true
+		}
+	}
+
+	if n, err := strconv.Atoi(setting); err == nil {
+		for _, workspace := range workspaces {
+			if workspace.Num == n {
+				return workspace, true
+			}
+		}
+	}
+
+	return i3.Workspace{}, false
+}
+
+// target returns the workspace argument of an i3 command for a setting.
+func target(conn *i3.Conn, setting string) (string, error) {
+	if setting == "" {
+		return "", fmt.Errorf("workspace is empty")
+	}
+
+	if strings.HasPrefix(setting, "#") {
+		workspaces, err := conn.Workspaces()
+		if err != nil {
+			return "", err
+		}
+		workspace, ok := resolve(setting, workspaces)
+		if !ok {
+			return "", fmt.Errorf("workspace %s does not exist", setting)
+		}
+		return i3.QuoteArgument(workspace.Name), nil
+	}
+
+	if _, err := strconv.Atoi(setting); err == nil {
+		return "number " + setting, nil
+	}
+
+	return i3.QuoteArgument(setting), nil
+}
+
+func command(conn *i3.Conn, subject string, settings types.Settings) (string, error) {
+	switch subject {
+	case actionWorkspace:
+		workspace, err := target(conn, settings.Workspace)
+		if err != nil {
+			return "", err
+		}
+		return "workspace " + workspace, nil
+	case actionMoveToWorkspace:
+		workspace, err := target(conn, settings.Workspace)
+		if err != nil {
+			return "", err
+		}
+		return "move container to workspace " + workspace, nil
+	case actionCommand:
+		if settings.Command == "" {
+			return "", fmt.Errorf("command is empty")
+		}
+		if runsProgram(settings.Command) {
+			return "", fmt.Errorf("i3 commands cannot exec programs, use a command action")
+		}
+		return settings.Command, nil
+	}
+
+	return "", fmt.Errorf("unknown action: %s", subject)
+}
+
+// runsProgram reports whether an i3 command, or one of the commands chained
+// to it with ; or ,, may start a program. Any exec word is refused, even in
+// criteria or quoted arguments, rather than parsing i3 commands.
+func runsProgram(command string) bool {
+	words := strings.FieldsFunc(strings.ToLower(command), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '_'
+	})
+	return slices.Contains(words, "exec")
+}
+
+// settingsOf returns the settings of the button a message was sent for.
+// i3 commands only run from buttons stored on this instance, like the
+// commands of the command plugin.
+func settingsOf(instanceID string, m *nats.Msg) (types.Settings, error) {
+	if m.Subject == actionCommand {
+		return multiaction.StoredSettings(instanceID, m)
+	}
+
+	var button types.Button
+	if err := json.Unmarshal(m.Data, &button); err != nil {
+		return types.Settings{}, err
+	}
+	return button.Settings, nil
+}
+
+func subscribeActions(instanceID string) error {
+	nc, _ := natsconn.GetNATSConn()
+
+	for _, subject := range []string{actionWorkspace, actionMoveToWorkspace, actionCommand} {
+		if _, err := nc.Subscribe(subject, func(m *nats.Msg) {
+			if !actions.ForInstance(m, instanceID) {
+				return
+			}
+
+			settings, err := settingsOf(instanceID, m)
+			if errors.Is(err, multiaction.ErrOtherInstance) {
+				return
+			}
+			if err != nil {
+				log.Error().Err(err).Str("action", m.Subject).Msg("Refusing i3 action")
+				actions.Reply(m, err)
+				return
+			}
+
+			conn, err := i3.Dial()
+			if err != nil {
+				log.Error().Err(err).Msg("Cannot reach i3")
+				actions.Reply(m, err)
+				return
+			}
+			defer conn.Close()
+
+			cmd, err := command(conn, m.Subject, settings)
+			if err != nil {
+				log.Error().Err(err).Str("action", m.Subject).Msg("Invalid i3 action")
+				actions.Reply(m, err)
+				return
+			}
+
+			if err := conn.RunCommand(cmd); err != nil {
+				log.Error().Err(err).Str("command", cmd).Msg("Failed to run i3 command")
+				actions.Reply(m, err)
+				return
+			}
+			actions.Reply(m, nil)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
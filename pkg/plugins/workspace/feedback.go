@@ -0,0 +1,195 @@
+package workspace
+
+import (
+	"context"
+	"sd/pkg/bindings"
+	"sd/pkg/i3"
+	"sd/pkg/types"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const retryDelay = 30 * time.Second
+
+// Key colours for the state of a workspace.
+const (
+	colorFocused = "#2563eb"
+	colorUrgent  = "#ea580c"
+	colorVisible = "#4b5563"
+	colorHidden  = "#1f2937"
+	colorMissing = "#000000"
+)
+
+// binding is a button showing a workspace.
+type binding struct {
+	workspace string
+	title     string
+}
+
+// feedback draws the state of the workspaces on the keys bound to them.
+type feedback struct {
+	instanceID string
+
+	mu         sync.Mutex
+	bindings   map[string]binding
+	drawn      bindings.Drawn
+	workspaces []i3.Workspace
+}
+
+func newFeedback(instanceID string) *feedback {
+	return &feedback{
+		instanceID: instanceID,
+		bindings:   make(map[string]binding),
+		drawn:      make(bindings.Drawn),
+	}
+}
+
+// render returns the feedback of a binding for the current workspaces.
+func (f *feedback) render(b binding) types.Feedback {
+	fb := types.Feedback{Color: colorMissing}
+
+	workspace, ok := resolve(b.workspace, f.workspaces)
+	if ok {
+		switch {
+		case workspace.Urgent:
+			fb.Color = colorUrgent
+		case workspace.Focused:
+			fb.Color = colorFocused
+		case workspace.Visible:
+			fb.Color = colorVisible
+		default:
+			fb.Color = colorHidden
+		}
+	}
+
+	if b.title == "" {
+		fb.Title = b.workspace
+		if ok {
+			fb.Title = workspace.Name
+		}
+	}
+
+	return fb
+}
+
+// draw updates the feedback of a key if it changed. Callers hold f.mu.
+func (f *feedback) draw(key string) {
+	b, ok := f.bindings[key]
+	if !ok {
+		return
+	}
+
+	fb := f.render(b)
+	if err := f.drawn.Feedback(key, &fb); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to draw workspace")
+	}
+}
+
+func (f *feedback) drawAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key := range f.bindings {
+		f.draw(key)
+	}
+}
+
+func (f *feedback) setWorkspaces(workspaces []i3.Workspace) {
+	f.mu.Lock()
+	f.workspaces = workspaces
+	f.mu.Unlock()
+
+	f.drawAll()
+}
+
+// update tracks a button key, drawing it when it is bound to a workspace and
+// clearing the feedback of keys that no longer are.
+func (f *feedback) update(key string, button types.Button, put bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if button.UUID != actionWorkspace {
+		if _, ok := f.bindings[key]; ok {
+			delete(f.bindings, key)
+			if err := f.drawn.Clear(key, put); err != nil {
+				log.Error().Err(err).Str("key", key).Msg("Failed to clear workspace")
+			}
+		}
+		return
+	}
+
+	b := binding{workspace: button.Settings.Workspace, title: button.Title}
+	if previous, ok := f.bindings[key]; !ok || previous != b {
+		f.drawn.Forget(key)
+	}
+	f.bindings[key] = b
+
+	f.draw(key)
+}
+
+// watchWorkspaces redraws the bound keys whenever the workspaces change.
+func (f *feedback) watchWorkspaces(ctx context.Context) {
+	for {
+		if err := f.followWorkspaces(ctx); err != nil {
+			log.Warn().Err(err).Msg("Cannot watch i3 workspaces")
+		}
+
+		f.setWorkspaces(nil)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (f *feedback) followWorkspaces(ctx context.Context) error {
+	// Stop closing the connection on ctx once this attempt is over.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := i3.Dial()
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+
+	queries, err := i3.Dial()
+	if err != nil {
+		return err
+	}
+	defer queries.Close()
+
+	go func() {
+		<-ctx.Done()
+		events.Close()
+	}()
+
+	if err := events.Subscribe("workspace"); err != nil {
+		return err
+	}
+
+	for {
+		workspaces, err := queries.Workspaces()
+		if err != nil {
+			return err
+		}
+		f.setWorkspaces(workspaces)
+
+		for {
+			msgType, _, err := events.Receive()
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			if msgType == i3.EventWorkspace {
+				break
+			}
+		}
+	}
+}
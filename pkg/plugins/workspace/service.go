@@ -0,0 +1,31 @@
+package workspace
+
+import (
+	"context"
+	"sd/pkg/bindings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// WorkspacePlugin controls i3 and sway workspaces and shows their state on
+// the keys bound to them.
+type WorkspacePlugin struct {
+	InstanceID string
+}
+
+// Name returns the name of the plugin.
+func (p *WorkspacePlugin) Name() string {
+	return "i3"
+}
+
+// Init sets up the NATS subscriptions and the workspace feedback.
+func (p *WorkspacePlugin) Init() {
+	if err := subscribeActions(p.InstanceID); err != nil {
+		log.Error().Err(err).Msg("Failed to subscribe to i3 actions")
+		return
+	}
+
+	f := newFeedback(p.InstanceID)
+	go bindings.Watch(context.Background(), bindings.Buttons(p.InstanceID), f.update)
+	go f.watchWorkspaces(context.Background())
+}
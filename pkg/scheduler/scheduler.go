@@ -0,0 +1,300 @@
+// Package scheduler runs the steps of schedules stored on devices on cron
+// expressions, when a device connects or disconnects, when its profile
+// changes, or on messages of a NATS subject.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sd/pkg/cron"
+	"sd/pkg/multiaction"
+	"sd/pkg/natsconn"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+type scheduler struct {
+	instanceID string
+
+	mu            sync.Mutex
+	schedules     map[string][]types.Schedule // by device
+	devices       map[string]types.Device     // last seen, by device
+	subscriptions map[string]*nats.Subscription
+	running       map[string]bool // by device and schedule ID
+}
+
+// Start follows the schedules and devices of an instance and runs schedules
+// until ctx is done. The watcher is created before Start returns, so devices
+// connecting afterwards run their connect schedules.
+func Start(ctx context.Context, instanceID string) error {
+	_, kv := natsconn.GetNATSConn()
+
+	devicesKey := fmt.Sprintf("instances.%s.devices.*", instanceID)
+
+	watcher, err := kv.WatchFiltered([]string{devicesKey, devicesKey + ".schedules"})
+	if err != nil {
+		return fmt.Errorf("failed to watch schedules: %w", err)
+	}
+
+	s := &scheduler{
+		instanceID:    instanceID,
+		schedules:     make(map[string][]types.Schedule),
+		devices:       make(map[string]types.Device),
+		subscriptions: make(map[string]*nats.Subscription),
+		running:       make(map[string]bool),
+	}
+
+	go s.watch(ctx, watcher)
+	go s.tick(ctx)
+
+	return nil
+}
+
+// watch applies changes of schedules and devices. Devices seen before the
+// initial values are all delivered have an unknown status, as it may be
+// left over from a previous run of the server.
+func (s *scheduler) watch(ctx context.Context, watcher nats.KeyWatcher) {
+	defer watcher.Stop()
+
+	initial := true
+	for {
+		select {
+		case <-ctx.Done():
+			s.unsubscribe()
+			return
+		case entry := <-watcher.Updates():
+			if entry == nil {
+				initial = false
+				continue
+			}
+
+			if _, deviceID, ok := store.ParseSchedulesKey(entry.Key()); ok {
+				s.updateSchedules(deviceID, entry)
+				continue
+			}
+
+			segments := strings.Split(entry.Key(), ".")
+			if len(segments) == 4 && entry.Operation() == nats.KeyValuePut {
+				s.updateDevice(entry, initial)
+			}
+		}
+	}
+}
+
+func (s *scheduler) updateSchedules(deviceID string, entry nats.KeyValueEntry) {
+	var schedules []types.Schedule
+	if entry.Operation() == nats.KeyValuePut {
+		if err := json.Unmarshal(entry.Value(), &schedules); err != nil {
+			log.Error().Err(err).Str("device", deviceID).Msg("Error unmarshaling schedules")
+			return
+		}
+	}
+
+	s.mu.Lock()
+	if len(schedules) == 0 {
+		delete(s.schedules, deviceID)
+	} else {
+		s.schedules[deviceID] = schedules
+	}
+	s.mu.Unlock()
+
+	s.resubscribe()
+}
+
+func (s *scheduler) updateDevice(entry nats.KeyValueEntry, initial bool) {
+	var device types.Device
+	if err := json.Unmarshal(entry.Value(), &device); err != nil {
+		log.Error().Err(err).Str("key", entry.Key()).Msg("Error unmarshaling device")
+		return
+	}
+
+	if initial {
+		device.Status = ""
+	}
+
+	s.mu.Lock()
+	previous, known := s.devices[device.ID]
+	s.devices[device.ID] = device
+	s.mu.Unlock()
+
+	if initial {
+		return
+	}
+
+	if device.Status != previous.Status {
+		switch device.Status {
+		case "connected":
+			s.trigger(func(deviceID string, schedule types.Schedule) bool {
+				return deviceID == device.ID && schedule.Trigger == types.TriggerConnect
+			})
+		case "disconnected":
+			s.trigger(func(deviceID string, schedule types.Schedule) bool {
+				return deviceID == device.ID && schedule.Trigger == types.TriggerDisconnect
+			})
+		}
+	}
+
+	if known && previous.CurrentProfile != "" && device.CurrentProfile != previous.CurrentProfile {
+		s.trigger(func(deviceID string, schedule types.Schedule) bool {
+			return deviceID == device.ID && schedule.Trigger == types.TriggerProfile &&
+				(schedule.Profile == "" || schedule.Profile == device.CurrentProfile)
+		})
+	}
+}
+
+// resubscribe subscribes to the subjects of the enabled subject schedules,
+// and unsubscribes from the subjects no schedule uses anymore. Schedules
+// written to the store directly are validated again, so they cannot
+// subscribe to wildcards or reserved subjects.
+func (s *scheduler) resubscribe() {
+	nc, _ := natsconn.GetNATSConn()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subjects := make(map[string]bool)
+	for _, schedules := range s.schedules {
+		for _, schedule := range schedules {
+			if !schedule.Enabled || schedule.Trigger != types.TriggerSubject {
+				continue
+			}
+			if err := store.ValidateSchedule(schedule); err != nil {
+				log.Warn().Err(err).Str("schedule", schedule.Name).Msg("Skipping invalid schedule")
+				continue
+			}
+			subjects[schedule.Subject] = true
+		}
+	}
+
+	for subject, sub := range s.subscriptions {
+		if subjects[subject] {
+			continue
+		}
+		if err := sub.Unsubscribe(); err != nil {
+			log.Warn().Err(err).Str("subject", subject).Msg("Failed to unsubscribe")
+		}
+		delete(s.subscriptions, subject)
+	}
+
+	for subject := range subjects {
+		if _, ok := s.subscriptions[subject]; ok {
+			continue
+		}
+
+		subject := subject
+		sub, err := nc.Subscribe(subject, func(m *nats.Msg) {
+			s.trigger(func(_ string, schedule types.Schedule) bool {
+				return schedule.Trigger == types.TriggerSubject && schedule.Subject == subject
+			})
+		})
+		if err != nil {
+			log.Error().Err(err).Str("subject", subject).Msg("Failed to subscribe")
+			continue
+		}
+		s.subscriptions[subject] = sub
+	}
+}
+
+func (s *scheduler) unsubscribe() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for subject, sub := range s.subscriptions {
+		sub.Unsubscribe()
+		delete(s.subscriptions, subject)
+	}
+}
+
+// tick runs the cron schedules at the start of every minute.
+func (s *scheduler) tick(ctx context.Context) {
+	for {
+		next := time.Now().Truncate(time.Minute).Add(time.Minute)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		s.trigger(func(deviceID string, schedule types.Schedule) bool {
+			if schedule.Trigger != types.TriggerCron {
+				return false
+			}
+
+			expression, err := cron.Parse(schedule.Cron)
+			if err != nil {
+				log.Warn().Err(err).Str("device", deviceID).Str("schedule", schedule.Name).Msg("Invalid cron expression")
+				return false
+			}
+			return expression.Match(next)
+		})
+	}
+}
+
+// trigger runs the enabled schedules for which match returns true.
+func (s *scheduler) trigger(match func(deviceID string, schedule types.Schedule) bool) {
+	type due struct {
+		deviceID string
+		schedule types.Schedule
+	}
+
+	var matched []due
+
+	s.mu.Lock()
+	for deviceID, schedules := range s.schedules {
+		for _, schedule := range schedules {
+			if schedule.Enabled && match(deviceID, schedule) {
+				matched = append(matched, due{deviceID, schedule})
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, d := range matched {
+		s.run(d.deviceID, d.schedule)
+	}
+}
+
+// run executes the steps of a schedule in the background. A schedule still
+// running is skipped, so a busy subject or a slow schedule cannot pile up
+// runs of the same steps.
+func (s *scheduler) run(deviceID string, schedule types.Schedule) {
+	device := store.GetDevice(s.instanceID, deviceID)
+	if device == nil {
+		log.Warn().Str("device", deviceID).Str("schedule", schedule.Name).Msg("Schedule device not found")
+		return
+	}
+
+	runKey := deviceID + "." + schedule.ID
+
+	s.mu.Lock()
+	if s.running[runKey] {
+		s.mu.Unlock()
+		log.Debug().Str("device", deviceID).Str("schedule", schedule.Name).Msg("Schedule still running, skipped")
+		return
+	}
+	s.running[runKey] = true
+	s.mu.Unlock()
+
+	log.Info().Str("device", deviceID).Str("schedule", schedule.Name).Str("trigger", schedule.Trigger).Msg("Running schedule")
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, runKey)
+			s.mu.Unlock()
+		}()
+
+		key := store.SchedulesKey(s.instanceID, deviceID)
+		if err := multiaction.RunSteps(s.instanceID, device, key, schedule.ID, schedule.Steps); err != nil {
+			log.Error().Err(err).Str("device", deviceID).Str("schedule", schedule.Name).Msg("Schedule stopped")
+		}
+	}()
+}
@@ -0,0 +1,417 @@
+package store
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sd/pkg/models"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	profileArchiveVersion = 1
+	profileManifestName   = "manifest.json"
+	maxManifestSize       = 10 << 20
+	maxArchiveImageSize   = 10 << 20
+	maxArchiveSize        = 200 << 20
+)
+
+func profileKey(instanceID string, deviceID string, profileID string) string {
+	return fmt.Sprintf("instances.%s.devices.%s.profiles.%s", instanceID, deviceID, profileID)
+}
+
+// profileConfigKeys returns the keys under a profile that are not pages, such
+// as dial, touch strip and pedal switch configuration.
+func profileConfigKeys(instanceID string, deviceID string, profileID string) ([]string, error) {
+	_, kv := natsconn.GetNATSConn()
+
+	prefix := profileKey(instanceID, deviceID, profileID) + "."
+
+	keyLister, err := kv.ListKeys()
+	if err != nil {
+		return nil, fmt.Errorf("could not list keys: %w", err)
+	}
+
+	var keys []string
+	for key := range keyLister.Keys() {
+		if !strings.HasPrefix(key, prefix) || strings.HasSuffix(key, ".buffer") || strings.HasSuffix(key, ".feedback") {
+			continue
+		}
+		if strings.HasPrefix(key, prefix+"pages.") {
+			continue
+		}
+		keys = append(keys, strings.TrimPrefix(key, prefix))
+	}
+
+	return keys, nil
+}
+
+func sortButtons(buttons []types.Button) {
+	sort.Slice(buttons, func(i, j int) bool {
+		a, errA := strconv.Atoi(buttons[i].ID)
+		b, errB := strconv.Atoi(buttons[j].ID)
+		if errA != nil || errB != nil {
+			return buttons[i].ID < buttons[j].ID
+		}
+		return a < b
+	})
+}
+
+// buildManifest collects a profile, its pages, buttons, configuration and
+// images. Images are returned by ID.
+func buildManifest(instanceID string, device *types.Device, profileID string) (*types.ProfileManifest, map[string][]byte, error) {
+	if instanceID == "" || device == nil || profileID == "" {
+		return nil, nil, fmt.Errorf("instanceID, device and profileID are required")
+	}
+
+	profile := GetProfile(instanceID, device, profileID)
+	if profile == nil {
+		return nil, nil, fmt.Errorf("profile not found")
+	}
+
+	manifest := &types.ProfileManifest{
+		Version:    profileArchiveVersion,
+		DeviceType: device.Type,
+		Profile:    *profile,
+		Config:     make(map[string]json.RawMessage),
+	}
+
+	images := make(map[string][]byte)
+
+	for _, page := range profile.Pages {
+		buttons, err := GetButtons(instanceID, device, profileID, page.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get buttons of page %s: %w", page.ID, err)
+		}
+		sortButtons(buttons)
+
+		// Embed every state image, including legacy images read from disk.
+		for i := range buttons {
+			for j, state := range buttons[i].States {
+				data, err := GetStateImage(state)
+				if err != nil {
+					log.Warn().Err(err).Str("button", buttons[i].ID).Msg("Skipping missing image")
+					continue
+				}
+
+				sum := sha256.Sum256(data)
+				id := hex.EncodeToString(sum[:])
+
+				if _, ok := images[id]; !ok {
+					images[id] = data
+					manifest.Images = append(manifest.Images, types.Image{ID: id, Filename: imageFilename(state), Size: uint64(len(data))})
+				}
+
+				buttons[i].States[j].ImageID = id
+				buttons[i].States[j].ImagePath = ""
+			}
+		}
+
+		manifest.Pages = append(manifest.Pages, types.ProfileManifestPage{Page: page, Buttons: buttons})
+	}
+
+	keys, err := profileConfigKeys(instanceID, device.ID, profileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, kv := natsconn.GetNATSConn()
+	for _, key := range keys {
+		entry, err := kv.Get(profileKey(instanceID, device.ID, profileID) + "." + key)
+		if err != nil {
+			continue
+		}
+		if !json.Valid(entry.Value()) {
+			log.Warn().Str("key", key).Msg("Skipping profile config that is not JSON")
+			continue
+		}
+		manifest.Config[key] = json.RawMessage(entry.Value())
+	}
+
+	return manifest, images, nil
+}
+
+// ExportProfile writes a profile, its pages, buttons, configuration and
+// images to w as a zip archive.
+func ExportProfile(instanceID string, device *types.Device, profileID string, w io.Writer) error {
+	manifest, images, err := buildManifest(instanceID, device, profileID)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	f, err := archive.Create(profileManifestName)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+
+	for _, image := range manifest.Images {
+		f, err := archive.Create(path.Join("images", image.ID))
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(images[image.ID]); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// imageFilename returns the library filename of a state image.
+func imageFilename(state types.State) string {
+	if state.ImageID == "" {
+		return path.Base(state.ImagePath)
+	}
+
+	info, err := natsconn.GetObjectStore().GetInfo(state.ImageID)
+	if err != nil {
+		return ""
+	}
+
+	return info.Metadata["filename"]
+}
+
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	if f.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s is too large", f.Name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is too large", f.Name)
+	}
+
+	return data, nil
+}
+
+// ReadProfileArchive reads the manifest and images of a profile archive.
+func ReadProfileArchive(r io.ReaderAt, size int64) (*types.ProfileManifest, map[string][]byte, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a profile archive: %w", err)
+	}
+
+	var manifest *types.ProfileManifest
+	images := make(map[string][]byte)
+
+	var total uint64
+	for _, f := range archive.File {
+		total += f.UncompressedSize64
+	}
+	if total > maxArchiveSize {
+		return nil, nil, fmt.Errorf("profile archive is too large")
+	}
+
+	for _, f := range archive.File {
+		switch {
+		case f.Name == profileManifestName:
+			data, err := readZipFile(f, maxManifestSize)
+			if err != nil {
+				return nil, nil, err
+			}
+			manifest = &types.ProfileManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+		case path.Dir(f.Name) == "images":
+			data, err := readZipFile(f, maxArchiveImageSize)
+			if err != nil {
+				return nil, nil, err
+			}
+			images[path.Base(f.Name)] = data
+		}
+	}
+
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("archive has no %s", profileManifestName)
+	}
+	if manifest.Version > profileArchiveVersion {
+		return nil, nil, fmt.Errorf("unsupported profile archive version %d", manifest.Version)
+	}
+
+	return manifest, images, nil
+}
+
+// validConfigKey reports whether a relative config key is safe to write under a profile.
+func validConfigKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "pages.") || strings.HasSuffix(key, ".buffer") {
+		return false
+	}
+	for _, token := range strings.Split(key, ".") {
+		if token == "" || strings.ContainsAny(token, "*> \t") {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportProfile creates a new profile on a device from a profile archive.
+func ImportProfile(instanceID string, device *types.Device, r io.ReaderAt, size int64) (*types.Profile, error) {
+	if instanceID == "" || device == nil {
+		return nil, fmt.Errorf("instanceID and device are required")
+	}
+
+	manifest, images, err := ReadProfileArchive(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.DeviceType != "" && manifest.DeviceType != device.Type {
+		return nil, fmt.Errorf("profile was exported from a %s device, this device is a %s", manifest.DeviceType, device.Type)
+	}
+
+	return importManifest(instanceID, device, manifest, images)
+}
+
+// importManifest writes a manifest as a new profile on a device.
+func importManifest(instanceID string, device *types.Device, manifest *types.ProfileManifest, images map[string][]byte) (*types.Profile, error) {
+	imageIDs := make(map[string]string)
+	filenames := make(map[string]string)
+	for _, image := range manifest.Images {
+		filenames[image.ID] = image.Filename
+	}
+
+	for id, data := range images {
+		filename := filenames[id]
+		if filename == "" {
+			filename = id
+		}
+
+		newID, err := PutImage(filename, data)
+		if err != nil {
+			log.Warn().Err(err).Str("image", id).Msg("Skipping image that could not be stored")
+			continue
+		}
+		imageIDs[id] = newID
+	}
+
+	name := manifest.Profile.Name
+	if name == "" {
+		name = "Imported"
+	}
+
+	profile := &types.Profile{
+		ID:    uuid.New().String(),
+		Name:  name,
+		Pages: make([]types.Page, 0, len(manifest.Pages)),
+	}
+
+	if _, err := UpdateProfile(instanceID, device, profile); err != nil {
+		return nil, err
+	}
+
+	fail := func(err error) (*types.Profile, error) {
+		if cleanupErr := DeleteProfile(instanceID, device, profile.ID); cleanupErr != nil {
+			log.Warn().Err(cleanupErr).Str("profile", profile.ID).Msg("Failed to remove partially imported profile")
+		}
+		return nil, err
+	}
+
+	_, kv := natsconn.GetNATSConn()
+	model, _ := models.ByType(device.Type)
+
+	for _, manifestPage := range manifest.Pages {
+		page := types.Page{ID: uuid.New().String()}
+
+		data, err := json.Marshal(page)
+		if err != nil {
+			return fail(fmt.Errorf("failed to serialize page data: %w", err))
+		}
+
+		if _, err := kv.Put(profileKey(instanceID, device.ID, profile.ID)+".pages."+page.ID, data); err != nil {
+			return fail(fmt.Errorf("failed to create page: %w", err))
+		}
+
+		profile.Pages = append(profile.Pages, page)
+		if manifestPage.Page.ID == manifest.Profile.CurrentPage || profile.CurrentPage == "" {
+			profile.CurrentPage = page.ID
+		}
+
+		imported := make(map[string]bool)
+
+		for _, button := range manifestPage.Buttons {
+			// Button IDs become part of KV keys, only accept key numbers of the device.
+			if n, err := strconv.Atoi(button.ID); err != nil || n < 1 || n > model.Keys() || strconv.Itoa(n) != button.ID {
+				return fail(fmt.Errorf("invalid button %q for a %s", button.ID, model.Name))
+			}
+
+			for i, state := range button.States {
+				if id, ok := imageIDs[state.ImageID]; ok {
+					button.States[i].ImageID = id
+				} else {
+					button.States[i].ImageID = DefaultImageID()
+				}
+				button.States[i].ImagePath = ""
+			}
+
+			if _, err := UpdateButton(instanceID, device, profile.ID, page.ID, &button); err != nil {
+				return fail(fmt.Errorf("failed to import button %s: %w", button.ID, err))
+			}
+			imported[button.ID] = true
+		}
+
+		// Keys missing from the archive start blank.
+		for i := 1; i <= model.Keys(); i++ {
+			if !imported[strconv.Itoa(i)] {
+				CreateButton(instanceID, device, profile.ID, page.ID, strconv.Itoa(i))
+			}
+		}
+	}
+
+	for key, value := range manifest.Config {
+		if !validConfigKey(key) {
+			log.Warn().Str("key", key).Msg("Skipping invalid profile config key")
+			continue
+		}
+		if _, err := kv.Put(profileKey(instanceID, device.ID, profile.ID)+"."+key, value); err != nil {
+			return fail(fmt.Errorf("failed to import profile config %s: %w", key, err))
+		}
+	}
+
+	if len(profile.Pages) == 0 {
+		page, err := CreatePage(instanceID, device, profile.ID)
+		if err != nil {
+			return fail(err)
+		}
+		profile.Pages = append(profile.Pages, *page)
+		profile.CurrentPage = page.ID
+	}
+
+	if _, err := UpdateProfile(instanceID, device, profile); err != nil {
+		return fail(err)
+	}
+
+	log.Info().Str("profile", profile.ID).Str("name", profile.Name).Msg("Imported profile")
+
+	return profile, nil
+}
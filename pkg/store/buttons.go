@@ -3,12 +3,12 @@ package store
 import (
 	"encoding/json"
 	"fmt"
-	"sd/pkg/env"
+	"sd/pkg/models"
 	"sd/pkg/natsconn"
 	"sd/pkg/types"
+	"sd/pkg/util"
 	"strings"
 
-	"github.com/h2non/bimg"
 	"github.com/nats-io/nats.go"
 	"github.com/rs/zerolog/log"
 )
@@ -61,7 +61,8 @@ func GetButtons(instanceID string, device *types.Device, profileID string, pageI
 			continue
 		}
 
-		if strings.HasSuffix(key, ".buffer") {
+		// Skip the buffer and feedback keys of buttons.
+		if strings.Contains(strings.TrimPrefix(key, prefix), ".") {
 			log.Info().Str("key", key).Msg("Key has suffix")
 			continue
 		}
@@ -117,6 +118,9 @@ func DeleteButton(instanceID string, device *types.Device, profileID string, pag
 		return err
 	}
 
+	deleteFeedback(buttonKey(instanceID, device.ID, profileID, pageID, buttonID))
+	DeleteTimer(buttonKey(instanceID, device.ID, profileID, pageID, buttonID))
+
 	return nil
 }
 
@@ -214,23 +218,10 @@ func CreateButton(instanceID string, device *types.Device, profileID string, pag
 	log.Info().Str("instanceID", instanceID).Interface("device", device).Str("profileID", profileID).Str("pageID", pageID).Str("buttonID", buttonID).Msg("Creating button")
 
 	// Define the key for the current button
-	key := "instances." + instanceID + ".devices." + device.ID + ".profiles." + profileID + ".pages." + pageID + ".buttons." + buttonID
-
-	//var assetPath = env.Get("ASSET_PATH", "")
+	key := buttonKey(instanceID, device.ID, profileID, pageID, buttonID)
 
 	// Define a new Button.
-	button := types.Button{
-		ID:   buttonID,
-		UUID: "none",
-		States: []types.State{
-			{
-				ID:        "0",
-				ImagePath: env.Get("ASSET_PATH", "") + "images/correct.png",
-			},
-		},
-		State: "0",
-		Title: "",
-	}
+	button := newButton(buttonID)
 
 	// Serialize the Profile struct to JSON
 	data, err := json.Marshal(button)
@@ -252,20 +243,224 @@ func CreateButton(instanceID string, device *types.Device, profileID string, pag
 		return err
 	}
 
-	// updateStateId(key, 0)
-	// updateSettings(key, Settings{})
-	// updateTitle(key, "")
-	updateImageBuffer(key, button.States[0].ImagePath)
+	updateImageBuffer(key, device, button)
 
 	log.Info().Str("key", key).Msg("Created button")
 	return nil
 }
 
-func updateImageBuffer(key string, imagePath string) (err error) {
-	log.Info().Str("image_path", imagePath).Str("key", key).Msg("Updating image buffer")
+// UpdateButton saves the button and re-renders its image buffer.
+func UpdateButton(instanceID string, device *types.Device, profileID string, pageID string, button *types.Button) (*types.Button, error) {
+	if instanceID == "" || device == nil || button == nil || button.ID == "" {
+		return nil, fmt.Errorf("instanceID, device and button are required")
+	}
+
+	_, kv := natsconn.GetNATSConn()
+	key := buttonKey(instanceID, device.ID, profileID, pageID, button.ID)
+
+	data, err := json.Marshal(button)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal button: %w", err)
+	}
+
+	_, err = kv.Put(key, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save button: %w", err)
+	}
+
+	if err := updateImageBuffer(key, device, *button); err != nil {
+		return button, err
+	}
+
+	return button, nil
+}
+
+// SetButtonState switches a button to another of its states, for plugins
+// reflecting an outcome or a running process, and re-renders it.
+func SetButtonState(buttonKey string, state string) error {
+	device, err := buttonDevice(buttonKey)
+	if err != nil {
+		return err
+	}
+
+	button, err := GetButton(buttonKey)
+	if err != nil {
+		return err
+	}
+
+	if button.State == state {
+		return nil
+	}
+	button.State = state
+
+	_, kv := natsconn.GetNATSConn()
+
+	data, err := json.Marshal(button)
+	if err != nil {
+		return fmt.Errorf("failed to marshal button: %w", err)
+	}
+
+	if _, err := kv.Put(buttonKey, data); err != nil {
+		return fmt.Errorf("failed to save button: %w", err)
+	}
+
+	return updateImageBuffer(buttonKey, device, button)
+}
+
+// ClearButton resets a button to the blank default.
+func ClearButton(instanceID string, device *types.Device, profileID string, pageID string, buttonID string) (*types.Button, error) {
+	button := newButton(buttonID)
+	return UpdateButton(instanceID, device, profileID, pageID, &button)
+}
+
+// CopyButton stores a copy of the button in the instance clipboard.
+func CopyButton(instanceID string, device *types.Device, profileID string, pageID string, buttonID string) error {
+	if instanceID == "" || device == nil {
+		return fmt.Errorf("instanceID and device are required")
+	}
+
+	button, err := GetButton(buttonKey(instanceID, device.ID, profileID, pageID, buttonID))
+	if err != nil {
+		return err
+	}
+
+	_, kv := natsconn.GetNATSConn()
+
+	data, err := json.Marshal(button)
+	if err != nil {
+		return fmt.Errorf("failed to marshal button: %w", err)
+	}
+
+	_, err = kv.Put(clipboardKey(instanceID), data)
+	if err != nil {
+		return fmt.Errorf("failed to save clipboard: %w", err)
+	}
+
+	return nil
+}
+
+// CutButton copies the button to the clipboard and clears it.
+func CutButton(instanceID string, device *types.Device, profileID string, pageID string, buttonID string) (*types.Button, error) {
+	if err := CopyButton(instanceID, device, profileID, pageID, buttonID); err != nil {
+		return nil, err
+	}
+
+	return ClearButton(instanceID, device, profileID, pageID, buttonID)
+}
+
+// PasteButton overwrites the button with the clipboard contents.
+func PasteButton(instanceID string, device *types.Device, profileID string, pageID string, buttonID string) (*types.Button, error) {
+	_, kv := natsconn.GetNATSConn()
+
+	entry, err := kv.Get(clipboardKey(instanceID))
+	if err != nil {
+		if err == nats.ErrKeyNotFound {
+			return nil, fmt.Errorf("clipboard is empty")
+		}
+		return nil, fmt.Errorf("failed to get clipboard: %w", err)
+	}
+
+	var button types.Button
+	if err := json.Unmarshal(entry.Value(), &button); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal clipboard: %w", err)
+	}
+
+	button.ID = buttonID
+
+	return UpdateButton(instanceID, device, profileID, pageID, &button)
+}
+
+// SwapButtons exchanges the contents of two buttons on the same page.
+func SwapButtons(instanceID string, device *types.Device, profileID string, pageID string, buttonID string, otherButtonID string) error {
+	if instanceID == "" || device == nil {
+		return fmt.Errorf("instanceID and device are required")
+	}
+
+	a, err := GetButton(buttonKey(instanceID, device.ID, profileID, pageID, buttonID))
+	if err != nil {
+		return err
+	}
+
+	b, err := GetButton(buttonKey(instanceID, device.ID, profileID, pageID, otherButtonID))
+	if err != nil {
+		return err
+	}
+
+	a.ID, b.ID = b.ID, a.ID
+
+	if _, err := UpdateButton(instanceID, device, profileID, pageID, &a); err != nil {
+		return err
+	}
+
+	if _, err := UpdateButton(instanceID, device, profileID, pageID, &b); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func buttonKey(instanceID string, deviceID string, profileID string, pageID string, buttonID string) string {
+	return fmt.Sprintf("instances.%s.devices.%s.profiles.%s.pages.%s.buttons.%s", instanceID, deviceID, profileID, pageID, buttonID)
+}
+
+func clipboardKey(instanceID string) string {
+	return fmt.Sprintf("instances.%s.clipboard", instanceID)
+}
+
+func newButton(buttonID string) types.Button {
+	return types.Button{
+		ID:   buttonID,
+		UUID: "none",
+		States: []types.State{
+			{
+				ID:      "0",
+				ImageID: DefaultImageID(),
+			},
+		},
+		State: "0",
+		Title: "",
+	}
+}
+
+// keySize returns the key image size in pixels for a device type.
+func keySize(deviceType string) int {
+	if model, ok := models.ByType(deviceType); ok {
+		return model.KeySize
+	}
+	return 96
+}
+
+// RenderButton renders the current state image and title of a button for a device.
+func RenderButton(device *types.Device, button types.Button) ([]byte, error) {
+	data, err := GetStateImage(button.CurrentState())
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := util.ConvertButtonImage(data, keySize(device.Type))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert button image: %w", err)
+	}
+
+	return util.DrawTitle(buf, button.Title, button.TitleStyle)
+}
+
+func updateImageBuffer(key string, device *types.Device, button types.Button) (err error) {
+	log.Info().Str("key", key).Msg("Updating image buffer")
 	_, kv := natsconn.GetNATSConn()
 
-	buf, _ := bimg.Read(imagePath)
+	var buf []byte
+	if feedback := GetFeedback(key); feedback != nil {
+		buf, err = renderFeedback(device, button, *feedback)
+	} else {
+		buf, err = RenderButton(device, button)
+	}
+
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to render button")
+		return err
+	}
 
 	_, err = kv.Put(key+".buffer", buf)
 
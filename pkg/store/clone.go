@@ -0,0 +1,131 @@
+package store
+
+import (
+	"fmt"
+	"sd/pkg/models"
+	"sd/pkg/types"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RemapStrategy decides where buttons land when a profile is cloned to a
+// device with a different key grid.
+type RemapStrategy string
+
+const (
+	// RemapPosition keeps each button at the same row and column. Buttons
+	// outside the target grid are dropped.
+	RemapPosition RemapStrategy = "position"
+	// RemapOrder keeps the order of the configured buttons and fills the
+	// target grid row by row, skipping blank keys.
+	RemapOrder RemapStrategy = "order"
+)
+
+// isBlankButton reports whether a button has no action, title or image.
+func isBlankButton(button types.Button) bool {
+	if button.UUID != "" && button.UUID != "none" {
+		return false
+	}
+	if button.Title != "" {
+		return false
+	}
+	for _, state := range button.States {
+		if state.ImagePath != "" || (state.ImageID != "" && state.ImageID != DefaultImageID()) {
+			return false
+		}
+	}
+	return true
+}
+
+// remapButtons moves the buttons of a page from one key grid to another.
+func remapButtons(buttons []types.Button, from models.Model, to models.Model, strategy RemapStrategy) []types.Button {
+	var remapped []types.Button
+	dropped := 0
+
+	switch strategy {
+	case RemapOrder:
+		next := 1
+		for _, button := range buttons {
+			if isBlankButton(button) {
+				continue
+			}
+			if next > to.Keys() {
+				dropped++
+				continue
+			}
+			button.ID = strconv.Itoa(next)
+			remapped = append(remapped, button)
+			next++
+		}
+	default:
+		for _, button := range buttons {
+			id, err := strconv.Atoi(button.ID)
+			if err != nil || id < 1 {
+				continue
+			}
+
+			row, col := (id-1)/from.Cols, (id-1)%from.Cols
+			if row >= to.Rows || col >= to.Cols {
+				if !isBlankButton(button) {
+					dropped++
+				}
+				continue
+			}
+
+			button.ID = strconv.Itoa(row*to.Cols + col + 1)
+			remapped = append(remapped, button)
+		}
+	}
+
+	if dropped > 0 {
+		log.Warn().Int("dropped", dropped).Str("from", from.Name).Str("to", to.Name).Msg("Buttons do not fit on the target device")
+	}
+
+	return remapped
+}
+
+// CloneProfile copies a profile with its pages, buttons and images to a
+// device, which may be of another model or on another instance. Buttons are
+// moved with strategy when the key grids differ.
+func CloneProfile(instanceID string, device *types.Device, profileID string, targetInstanceID string, target *types.Device, strategy RemapStrategy) (*types.Profile, error) {
+	if targetInstanceID == "" || target == nil {
+		return nil, fmt.Errorf("target instanceID and device are required")
+	}
+
+	manifest, images, err := buildManifest(instanceID, device, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if device.Type != target.Type {
+		from, ok := models.ByType(device.Type)
+		if !ok {
+			return nil, fmt.Errorf("unsupported device type: %s", device.Type)
+		}
+		to, ok := models.ByType(target.Type)
+		if !ok {
+			return nil, fmt.Errorf("unsupported device type: %s", target.Type)
+		}
+
+		for i := range manifest.Pages {
+			manifest.Pages[i].Buttons = remapButtons(manifest.Pages[i].Buttons, from, to, strategy)
+		}
+
+		// Dial, touch strip and pedal switch configuration only applies to the same model.
+		manifest.Config = nil
+	}
+
+	if instanceID == targetInstanceID && device.ID == target.ID {
+		manifest.Profile.Name += " (copy)"
+	}
+
+	profile, err := importManifest(targetInstanceID, target, manifest, images)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("profile", profileID).Str("target_instance", targetInstanceID).Str("target_device", target.ID).Msg("Cloned profile")
+
+	return profile, nil
+}
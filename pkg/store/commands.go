@@ -0,0 +1,93 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sd/pkg/natsconn"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+func commandAllowlistKey(instanceID string) string {
+	return fmt.Sprintf("instances.%s.command-allowlist", instanceID)
+}
+
+// GetCommandAllowlist returns the expressions commands of an instance must
+// match. An empty list allows every command stored on a button.
+func GetCommandAllowlist(instanceID string) ([]string, error) {
+	_, kv := natsconn.GetNATSConn()
+
+	entry, err := kv.Get(commandAllowlistKey(instanceID))
+	if err == nats.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get command allowlist: %w", err)
+	}
+
+	var patterns []string
+	if err := json.Unmarshal(entry.Value(), &patterns); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal command allowlist: %w", err)
+	}
+
+	return patterns, nil
+}
+
+// UpdateCommandAllowlist replaces the command allowlist of an instance.
+// Each pattern is a regular expression matched against the whole command.
+func UpdateCommandAllowlist(instanceID string, patterns []string) error {
+	cleaned := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := compileCommandPattern(pattern); err != nil {
+			return fmt.Errorf("invalid command expression %q: %w", pattern, err)
+		}
+		cleaned = append(cleaned, pattern)
+	}
+
+	data, err := json.Marshal(cleaned)
+	if err != nil {
+		return fmt.Errorf("failed to marshal command allowlist: %w", err)
+	}
+
+	_, kv := natsconn.GetNATSConn()
+	if _, err := kv.Put(commandAllowlistKey(instanceID), data); err != nil {
+		return fmt.Errorf("failed to save command allowlist: %w", err)
+	}
+
+	return nil
+}
+
+// CommandAllowed reports whether a command matches the allowlist of an
+// instance.
+func CommandAllowed(instanceID string, command string) (bool, error) {
+	patterns, err := GetCommandAllowlist(instanceID)
+	if err != nil {
+		return false, err
+	}
+
+	if len(patterns) == 0 {
+		return true, nil
+	}
+
+	for _, pattern := range patterns {
+		re, err := compileCommandPattern(pattern)
+		if err != nil {
+			continue
+		}
+		if re.MatchString(command) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func compileCommandPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
@@ -75,6 +75,7 @@ func GetDevices(instanceID string) []types.Device {
 			Type:           device.Type,
 			Status:         device.Status,
 			CurrentProfile: device.CurrentProfile,
+			Brightness:     device.Brightness,
 		})
 
 	}
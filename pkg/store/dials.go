@@ -0,0 +1,88 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DialKey returns the key of a dial of a profile. Dials are numbered from 1
+// and bound per profile, like pedal switches.
+func DialKey(instanceID string, deviceID string, profileID string, dial int) string {
+	return fmt.Sprintf("instances.%s.devices.%s.profiles.%s.dials.%d", instanceID, deviceID, profileID, dial)
+}
+
+// ParseDialKey returns the profile and dial of a dial key.
+func ParseDialKey(key string) (profileID string, dial int, ok bool) {
+	segments := strings.Split(key, ".")
+	if len(segments) != 8 || segments[0] != "instances" || segments[6] != "dials" {
+		return "", 0, false
+	}
+
+	dial, err := strconv.Atoi(segments[7])
+	if err != nil {
+		return "", 0, false
+	}
+
+	return segments[5], dial, true
+}
+
+// GetDial returns the action bound to a dial, or an empty button when the
+// dial is not bound.
+func GetDial(key string) (types.Button, error) {
+	_, kv := natsconn.GetNATSConn()
+
+	entry, err := kv.Get(key)
+	if err == nats.ErrKeyNotFound {
+		return types.Button{}, nil
+	}
+	if err != nil {
+		return types.Button{}, fmt.Errorf("failed to get dial: %w", err)
+	}
+
+	var dial types.Button
+	if err := json.Unmarshal(entry.Value(), &dial); err != nil {
+		return types.Button{}, fmt.Errorf("invalid dial: %w", err)
+	}
+
+	return dial, nil
+}
+
+// UpdateDial binds a dial to the action of dial.
+func UpdateDial(key string, dial types.Button) error {
+	_, dialNumber, ok := ParseDialKey(key)
+	if !ok {
+		return fmt.Errorf("not a dial key: %s", key)
+	}
+	dial.ID = strconv.Itoa(dialNumber)
+
+	data, err := json.Marshal(dial)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dial: %w", err)
+	}
+
+	_, kv := natsconn.GetNATSConn()
+	if _, err := kv.Put(key, data); err != nil {
+		return fmt.Errorf("failed to save dial: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDial unbinds a dial and clears its feedback.
+func DeleteDial(key string) error {
+	_, kv := natsconn.GetNATSConn()
+
+	deleteFeedback(key)
+
+	if err := kv.Delete(key); err != nil && err != nats.ErrKeyNotFound {
+		return fmt.Errorf("failed to delete dial: %w", err)
+	}
+
+	return nil
+}
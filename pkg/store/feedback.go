@@ -0,0 +1,136 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+	"sd/pkg/util"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+func feedbackKey(buttonKey string) string {
+	return buttonKey + ".feedback"
+}
+
+// GetFeedback returns the live feedback drawn on a button, or nil.
+func GetFeedback(buttonKey string) *types.Feedback {
+	_, kv := natsconn.GetNATSConn()
+
+	entry, err := kv.Get(feedbackKey(buttonKey))
+	if err != nil {
+		return nil
+	}
+
+	var feedback types.Feedback
+	if err := json.Unmarshal(entry.Value(), &feedback); err != nil {
+		log.Warn().Err(err).Str("key", buttonKey).Msg("Invalid button feedback")
+		return nil
+	}
+
+	return &feedback
+}
+
+// SetFeedback draws live feedback on a button and re-renders its image
+// buffer. A nil feedback restores the configured image and title. Feedback
+// for a dial key is drawn on its touch strip segment by the device.
+func SetFeedback(buttonKey string, feedback *types.Feedback) error {
+	if _, _, ok := ParseDialKey(buttonKey); ok {
+		return putFeedback(buttonKey, feedback)
+	}
+
+	device, err := buttonDevice(buttonKey)
+	if err != nil {
+		return err
+	}
+
+	button, err := GetButton(buttonKey)
+	if err != nil {
+		return err
+	}
+
+	if err := putFeedback(buttonKey, feedback); err != nil {
+		return err
+	}
+
+	return updateImageBuffer(buttonKey, device, button)
+}
+
+func putFeedback(key string, feedback *types.Feedback) error {
+	if feedback == nil {
+		deleteFeedback(key)
+		return nil
+	}
+
+	data, err := json.Marshal(feedback)
+	if err != nil {
+		return fmt.Errorf("failed to marshal feedback: %w", err)
+	}
+
+	_, kv := natsconn.GetNATSConn()
+	if _, err := kv.Put(feedbackKey(key), data); err != nil {
+		return fmt.Errorf("failed to save feedback: %w", err)
+	}
+
+	return nil
+}
+
+// buttonDevice returns the device of a button key.
+func buttonDevice(buttonKey string) (*types.Device, error) {
+	segments := strings.Split(buttonKey, ".")
+	if len(segments) != 10 || segments[8] != "buttons" {
+		return nil, fmt.Errorf("not a button key: %s", buttonKey)
+	}
+
+	device := GetDevice(segments[1], segments[3])
+	if device == nil {
+		return nil, fmt.Errorf("device not found")
+	}
+
+	return device, nil
+}
+
+func deleteFeedback(buttonKey string) {
+	_, kv := natsconn.GetNATSConn()
+
+	if err := kv.Delete(feedbackKey(buttonKey)); err != nil && err != nats.ErrKeyNotFound {
+		log.Warn().Err(err).Str("key", buttonKey).Msg("Failed to delete button feedback")
+	}
+}
+
+// renderFeedback renders a button with its feedback drawn over the
+// configured image and title.
+func renderFeedback(device *types.Device, button types.Button, feedback types.Feedback) ([]byte, error) {
+	title := button.Title
+	style := button.TitleStyle
+	if feedback.Title != "" {
+		title = feedback.Title
+		style.Hidden = false
+	}
+
+	var buf []byte
+	var err error
+	switch {
+	case feedback.Image != "":
+		var data []byte
+		if data, err = base64.StdEncoding.DecodeString(feedback.Image); err != nil {
+			return nil, fmt.Errorf("invalid feedback image: %w", err)
+		}
+		buf, err = util.ConvertButtonImage(data, keySize(device.Type))
+	case feedback.Color != "":
+		buf, err = util.ColorImage(feedback.Color, keySize(device.Type))
+	default:
+		button.Title = title
+		button.TitleStyle = style
+		return RenderButton(device, button)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return util.DrawTitle(buf, title, style)
+}
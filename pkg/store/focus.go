@@ -0,0 +1,100 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+func focusRulesKey(instanceID string, deviceID string) string {
+	return fmt.Sprintf("instances.%s.devices.%s.focus-rules", instanceID, deviceID)
+}
+
+// GetFocusRules returns the automatic profile switching rules of a device in
+// the order they are evaluated.
+func GetFocusRules(instanceID string, deviceID string) ([]types.FocusRule, error) {
+	_, kv := natsconn.GetNATSConn()
+
+	entry, err := kv.Get(focusRulesKey(instanceID, deviceID))
+	if err == nats.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get focus rules: %w", err)
+	}
+
+	var rules []types.FocusRule
+	if err := json.Unmarshal(entry.Value(), &rules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal focus rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// UpdateFocusRules replaces the automatic profile switching rules of a device.
+func UpdateFocusRules(instanceID string, deviceID string, rules []types.FocusRule) error {
+	for _, rule := range rules {
+		if _, err := regexp.Compile(rule.Class); err != nil {
+			return fmt.Errorf("invalid class expression %q: %w", rule.Class, err)
+		}
+		if _, err := regexp.Compile(rule.Title); err != nil {
+			return fmt.Errorf("invalid title expression %q: %w", rule.Title, err)
+		}
+	}
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return fmt.Errorf("failed to marshal focus rules: %w", err)
+	}
+
+	_, kv := natsconn.GetNATSConn()
+	if _, err := kv.Put(focusRulesKey(instanceID, deviceID), data); err != nil {
+		return fmt.Errorf("failed to save focus rules: %w", err)
+	}
+
+	return nil
+}
+
+// AddFocusRule appends a rule to the rules of a device.
+func AddFocusRule(instanceID string, deviceID string, rule types.FocusRule) (*types.FocusRule, error) {
+	if rule.ProfileID == "" {
+		return nil, fmt.Errorf("profileID is required")
+	}
+	if rule.Class == "" && rule.Title == "" {
+		return nil, fmt.Errorf("a class or title expression is required")
+	}
+
+	rules, err := GetFocusRules(instanceID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.ID = uuid.New().String()
+	if err := UpdateFocusRules(instanceID, deviceID, append(rules, rule)); err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// DeleteFocusRule removes a rule from the rules of a device.
+func DeleteFocusRule(instanceID string, deviceID string, ruleID string) error {
+	rules, err := GetFocusRules(instanceID, deviceID)
+	if err != nil {
+		return err
+	}
+
+	kept := rules[:0]
+	for _, rule := range rules {
+		if rule.ID != ruleID {
+			kept = append(kept, rule)
+		}
+	}
+
+	return UpdateFocusRules(instanceID, deviceID, kept)
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sd/pkg/env"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+	"sd/pkg/util"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	defaultImageID string
+	defaultImageMu sync.Mutex
+)
+
+// PutImage stores an image in the Object Store and returns its ID.
+// Images are keyed by the SHA-256 of their content so uploading the same
+// image twice stores it once.
+func PutImage(filename string, data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", fmt.Errorf("unsupported image type: %s", contentType)
+	}
+	if !util.IsSupportedImage(data) {
+		return "", fmt.Errorf("unsupported image type: %s", contentType)
+	}
+
+	objects := natsconn.GetObjectStore()
+
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:])
+
+	if _, err := objects.GetInfo(id); err == nil {
+		return id, nil
+	} else if err != nats.ErrObjectNotFound {
+		return "", fmt.Errorf("failed to look up image: %w", err)
+	}
+
+	meta := &nats.ObjectMeta{
+		Name:     id,
+		Headers:  nats.Header{"Content-Type": []string{contentType}},
+		Metadata: map[string]string{"filename": filepath.Base(filename)},
+	}
+
+	if _, err := objects.Put(meta, bytes.NewReader(data)); err != nil {
+		return "", fmt.Errorf("failed to store image: %w", err)
+	}
+
+	log.Info().Str("id", id).Str("filename", filename).Msg("Stored image")
+
+	return id, nil
+}
+
+// GetImage returns the content of an image stored in the Object Store.
+func GetImage(id string) ([]byte, error) {
+	data, err := natsconn.GetObjectStore().GetBytes(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image %s: %w", id, err)
+	}
+
+	return data, nil
+}
+
+// GetImages lists the images in the library.
+func GetImages() []types.Image {
+	images := make([]types.Image, 0)
+
+	infos, err := natsconn.GetObjectStore().List()
+	if err != nil {
+		if err != nats.ErrNoObjectsFound {
+			log.Warn().Err(err).Msg("Failed to list images")
+		}
+		return images
+	}
+
+	for _, info := range infos {
+		images = append(images, types.Image{
+			ID:       info.Name,
+			Filename: info.Metadata["filename"],
+			Size:     info.Size,
+		})
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Filename < images[j].Filename
+	})
+
+	return images
+}
+
+// ImageExists reports whether an image is in the library.
+func ImageExists(id string) bool {
+	_, err := natsconn.GetObjectStore().GetInfo(id)
+	return err == nil
+}
+
+// DefaultImageID returns the ID of the image used for blank keys, uploading
+// it from ASSET_PATH on first use. A failed upload is retried on the next
+// call.
+func DefaultImageID() string {
+	defaultImageMu.Lock()
+	defer defaultImageMu.Unlock()
+
+	if defaultImageID != "" {
+		return defaultImageID
+	}
+
+	path := env.Get("ASSET_PATH", "") + "images/correct.png"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Failed to read default image")
+		return ""
+	}
+
+	id, err := PutImage(filepath.Base(path), data)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to store default image")
+		return ""
+	}
+
+	defaultImageID = id
+	return defaultImageID
+}
+
+// GetDefaultImage returns the content of the image used for blank keys.
+func GetDefaultImage() ([]byte, error) {
+	id := DefaultImageID()
+	if id == "" {
+		return nil, fmt.Errorf("default image is not available")
+	}
+
+	return GetImage(id)
+}
+
+// GetStateImage returns the image of a button state, reading legacy
+// ImagePath states from the images directory of ASSET_PATH.
+func GetStateImage(state types.State) ([]byte, error) {
+	switch {
+	case state.ImageID != "":
+		return GetImage(state.ImageID)
+	case state.ImagePath != "":
+		path, err := legacyImagePath(state.ImagePath)
+		if err != nil {
+			return nil, err
+		}
+		return os.ReadFile(path)
+	default:
+		return GetDefaultImage()
+	}
+}
+
+// legacyImagePath resolves the path of a legacy ImagePath state. Stored and
+// imported states are not trusted: paths resolving outside the images
+// directory, through ".." or symbolic links, are rejected.
+func legacyImagePath(imagePath string) (string, error) {
+	dir, err := filepath.Abs(env.Get("ASSET_PATH", "") + "images")
+	if err != nil {
+		return "", err
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return "", fmt.Errorf("images directory not found: %w", err)
+	}
+
+	path, err := filepath.Abs(imagePath)
+	if err != nil {
+		return "", err
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return "", fmt.Errorf("image not found: %s", imagePath)
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("image is outside the images directory: %s", imagePath)
+	}
+
+	return path, nil
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"encoding/json"
 	"fmt"
+	"sd/pkg/models"
 	"sd/pkg/natsconn"
 	"strconv"
 	"strings"
@@ -84,9 +85,9 @@ func CreatePage(instanceID string, device *types.Device, profileID string) (*typ
 
 	log.Info().Str("device_type", device.Type).Msg("device.Type")
 
-	if device.Type == "xl" {
-		// Create 32 buttons for the page
-		for i := 0; i < 32; i++ {
+	if model, ok := models.ByType(device.Type); ok {
+		// Create a button for every key of the model
+		for i := 0; i < model.Keys(); i++ {
 			CreateButton(instanceID, device, profileID, newPage.ID, strconv.Itoa(i+1))
 		}
 	}
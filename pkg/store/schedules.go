@@ -0,0 +1,160 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sd/pkg/cron"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// SchedulesKey returns the key of the schedules of a device. Steps run by a
+// schedule are sent with this key.
+func SchedulesKey(instanceID string, deviceID string) string {
+	return fmt.Sprintf("instances.%s.devices.%s.schedules", instanceID, deviceID)
+}
+
+// ParseSchedulesKey returns the instance and device of a schedules key.
+func ParseSchedulesKey(key string) (instanceID string, deviceID string, ok bool) {
+	segments := strings.Split(key, ".")
+	if len(segments) != 5 || segments[0] != "instances" || segments[2] != "devices" || segments[4] != "schedules" {
+		return "", "", false
+	}
+	return segments[1], segments[3], true
+}
+
+// GetSchedules returns the schedules of a device.
+func GetSchedules(instanceID string, deviceID string) ([]types.Schedule, error) {
+	_, kv := natsconn.GetNATSConn()
+
+	entry, err := kv.Get(SchedulesKey(instanceID, deviceID))
+	if err == nats.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+
+	var schedules []types.Schedule
+	if err := json.Unmarshal(entry.Value(), &schedules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// ValidateSchedule checks the trigger and steps of a schedule.
+func ValidateSchedule(schedule types.Schedule) error {
+	switch schedule.Trigger {
+	case types.TriggerCron:
+		if _, err := cron.Parse(schedule.Cron); err != nil {
+			return err
+		}
+	case types.TriggerSubject:
+		if err := validateSubject(schedule.Subject); err != nil {
+			return err
+		}
+	case types.TriggerConnect, types.TriggerDisconnect, types.TriggerProfile:
+	default:
+		return fmt.Errorf("unknown trigger: %s", schedule.Trigger)
+	}
+
+	if len(schedule.Steps) == 0 {
+		return fmt.Errorf("a schedule needs at least one step")
+	}
+
+	return nil
+}
+
+// validateSubject allows a single literal subject outside the subjects of
+// the server and NATS itself: wildcards would trigger on every matching
+// message, and sd.*, $JS.*, $KV.* and _INBOX.* are busy with the server's
+// own traffic.
+func validateSubject(subject string) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n*>") {
+		return fmt.Errorf("invalid subject %q", subject)
+	}
+	for _, token := range strings.Split(subject, ".") {
+		if token == "" {
+			return fmt.Errorf("invalid subject %q", subject)
+		}
+	}
+	if strings.HasPrefix(subject, "sd.") || strings.HasPrefix(subject, "$") || strings.HasPrefix(subject, "_INBOX.") {
+		return fmt.Errorf("subject %q is reserved", subject)
+	}
+
+	return nil
+}
+
+// UpdateSchedules replaces the schedules of a device.
+func UpdateSchedules(instanceID string, deviceID string, schedules []types.Schedule) error {
+	for _, schedule := range schedules {
+		if err := ValidateSchedule(schedule); err != nil {
+			return fmt.Errorf("schedule %q: %w", schedule.Name, err)
+		}
+	}
+
+	data, err := json.Marshal(schedules)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedules: %w", err)
+	}
+
+	_, kv := natsconn.GetNATSConn()
+	if _, err := kv.Put(SchedulesKey(instanceID, deviceID), data); err != nil {
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+
+	return nil
+}
+
+// SaveSchedule adds a schedule to a device, or replaces the schedule with
+// the same ID.
+func SaveSchedule(instanceID string, deviceID string, schedule types.Schedule) (*types.Schedule, error) {
+	if schedule.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	schedules, err := GetSchedules(instanceID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	replaced := false
+	for i := range schedules {
+		if schedule.ID != "" && schedules[i].ID == schedule.ID {
+			schedules[i] = schedule
+			replaced = true
+		}
+	}
+	if !replaced {
+		schedule.ID = uuid.New().String()
+		schedules = append(schedules, schedule)
+	}
+
+	if err := UpdateSchedules(instanceID, deviceID, schedules); err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+// DeleteSchedule removes a schedule from a device.
+func DeleteSchedule(instanceID string, deviceID string, scheduleID string) error {
+	schedules, err := GetSchedules(instanceID, deviceID)
+	if err != nil {
+		return err
+	}
+
+	kept := schedules[:0]
+	for _, schedule := range schedules {
+		if schedule.ID != scheduleID {
+			kept = append(kept, schedule)
+		}
+	}
+
+	return UpdateSchedules(instanceID, deviceID, kept)
+}
@@ -0,0 +1,28 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SwitchKey returns the key of a pedal switch of a profile. Switches are
+// numbered from 1 and bound per profile, like dials.
+func SwitchKey(instanceID string, deviceID string, profileID string, switchNumber int) string {
+	return fmt.Sprintf("instances.%s.devices.%s.profiles.%s.switches.%d", instanceID, deviceID, profileID, switchNumber)
+}
+
+// ParseSwitchKey returns the profile and switch of a switch key.
+func ParseSwitchKey(key string) (profileID string, switchNumber int, ok bool) {
+	segments := strings.Split(key, ".")
+	if len(segments) != 8 || segments[0] != "instances" || segments[6] != "switches" {
+		return "", 0, false
+	}
+
+	switchNumber, err := strconv.Atoi(segments[7])
+	if err != nil {
+		return "", 0, false
+	}
+
+	return segments[5], switchNumber, true
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sd/pkg/natsconn"
+	"sd/pkg/types"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+func timerKey(buttonKey string) string {
+	return buttonKey + ".timer"
+}
+
+// GetTimer returns the state of the timer bound to a button, or nil when it
+// was never started.
+func GetTimer(buttonKey string) (*types.TimerState, error) {
+	_, kv := natsconn.GetNATSConn()
+
+	entry, err := kv.Get(timerKey(buttonKey))
+	if err == nats.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get timer: %w", err)
+	}
+
+	var state types.TimerState
+	if err := json.Unmarshal(entry.Value(), &state); err != nil {
+		return nil, fmt.Errorf("invalid timer: %w", err)
+	}
+
+	return &state, nil
+}
+
+// SetTimer saves the state of the timer bound to a button.
+func SetTimer(buttonKey string, state types.TimerState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal timer: %w", err)
+	}
+
+	_, kv := natsconn.GetNATSConn()
+	if _, err := kv.Put(timerKey(buttonKey), data); err != nil {
+		return fmt.Errorf("failed to save timer: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTimer forgets the state of the timer bound to a button.
+func DeleteTimer(buttonKey string) {
+	_, kv := natsconn.GetNATSConn()
+
+	if err := kv.Delete(timerKey(buttonKey)); err != nil && err != nats.ErrKeyNotFound {
+		log.Warn().Err(err).Str("key", buttonKey).Msg("Failed to delete button timer")
+	}
+}
@@ -0,0 +1,543 @@
+package animation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+const (
+	// MaxFrames bounds the memory used by a single animated key.
+	MaxFrames = 200
+
+	// Uploaded images are not trusted: canvases and the frames decoded from
+	// them are bounded before anything is allocated.
+	maxCanvasSide    = 4096
+	maxCanvasPixels  = 1 << 21
+	maxDecodedPixels = 1 << 25
+
+	minDelay     = 20 * time.Millisecond
+	defaultDelay = 100 * time.Millisecond
+)
+
+// ErrNotAnimated is returned by Decode for still images.
+var ErrNotAnimated = errors.New("image is not animated")
+
+// Frame is a fully composed frame of an animation.
+type Frame struct {
+	Image image.Image
+	Delay time.Duration
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// IsAnimated reports whether data is an animated GIF, APNG or WebP without
+// decoding the frames.
+func IsAnimated(data []byte) bool {
+	switch {
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		return gifFrameCount(data, 2) > 1
+	case bytes.HasPrefix(data, pngSignature):
+		_, ok := findPNGChunk(data, "acTL")
+		return ok
+	case isWebP(data):
+		return len(data) > 20 && string(data[12:16]) == "VP8X" && data[20]&0x02 != 0
+	}
+	return false
+}
+
+// Decode decodes every frame of an animated GIF, APNG or WebP image.
+func Decode(data []byte) ([]Frame, error) {
+	var frames []Frame
+	var err error
+
+	switch {
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		frames, err = decodeGIF(data)
+	case bytes.HasPrefix(data, pngSignature):
+		frames, err = decodeAPNG(data)
+	case isWebP(data):
+		frames, err = decodeWebP(data)
+	default:
+		return nil, ErrNotAnimated
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) < 2 {
+		return nil, ErrNotAnimated
+	}
+
+	return frames, nil
+}
+
+func frameDelay(d time.Duration) time.Duration {
+	// Browsers treat very short delays as unset, do the same.
+	if d <= 10*time.Millisecond {
+		return defaultDelay
+	}
+	return max(d, minDelay)
+}
+
+// frameLimit returns how many frames of a canvas may be decoded, or an error
+// when the canvas is too large.
+func frameLimit(width, height int) (int, error) {
+	if width <= 0 || height <= 0 || width > maxCanvasSide || height > maxCanvasSide || width*height > maxCanvasPixels {
+		return 0, fmt.Errorf("invalid animation size %dx%d", width, height)
+	}
+	return max(min(MaxFrames, maxDecodedPixels/(width*height)), 1), nil
+}
+
+// inCanvas checks that a frame lies within the canvas.
+func inCanvas(rect image.Rectangle, canvas *image.RGBA) error {
+	if rect.Empty() || !rect.In(canvas.Bounds()) {
+		return fmt.Errorf("frame %v is outside the canvas %v", rect, canvas.Bounds())
+	}
+	return nil
+}
+
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Rect)
+	copy(dst.Pix, src.Pix)
+	return dst
+}
+
+// gifFrameCount walks the GIF blocks counting image descriptors, stopping at limit.
+func gifFrameCount(data []byte, limit int) int {
+	if len(data) < 13 {
+		return 0
+	}
+
+	skipColorTable := func(i int, flags byte) int {
+		if flags&0x80 != 0 {
+			i += 3 << ((flags & 0x07) + 1)
+		}
+		return i
+	}
+
+	skipSubBlocks := func(i int) int {
+		for i < len(data) && data[i] != 0 {
+			i += int(data[i]) + 1
+		}
+		return i + 1
+	}
+
+	count := 0
+	for i := skipColorTable(13, data[10]); i < len(data) && count < limit; {
+		switch data[i] {
+		case 0x21:
+			i = skipSubBlocks(i + 2)
+		case 0x2c:
+			if i+10 > len(data) {
+				return count
+			}
+			count++
+			i = skipSubBlocks(skipColorTable(i+10, data[i+9]) + 1)
+		default:
+			return count
+		}
+	}
+	return count
+}
+
+func decodeGIF(data []byte) ([]Frame, error) {
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode gif: %w", err)
+	}
+
+	limit, err := frameLimit(config.Width, config.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	// DecodeAll decodes every frame, refuse to decode more than are kept.
+	if gifFrameCount(data, limit+1) > limit {
+		return nil, fmt.Errorf("gif has more than %d frames", limit)
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode gif: %w", err)
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, config.Width, config.Height))
+	frames := make([]Frame, 0, len(g.Image))
+
+	for i, frame := range g.Image {
+		if err := inCanvas(frame.Bounds(), canvas); err != nil {
+			return nil, err
+		}
+
+		var previous *image.RGBA
+		if g.Disposal[i] == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		frames = append(frames, Frame{
+			Image: cloneRGBA(canvas),
+			Delay: frameDelay(time.Duration(g.Delay[i]) * 10 * time.Millisecond),
+		})
+
+		switch g.Disposal[i] {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return frames, nil
+}
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+func readPNGChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, fmt.Errorf("not a png")
+	}
+
+	var chunks []pngChunk
+	for rest := data[len(pngSignature):]; len(rest) >= 12; {
+		length := binary.BigEndian.Uint32(rest[:4])
+		if uint64(length)+12 > uint64(len(rest)) {
+			return nil, fmt.Errorf("truncated png chunk")
+		}
+
+		chunk := pngChunk{typ: string(rest[4:8]), data: rest[8 : 8+length]}
+		chunks = append(chunks, chunk)
+		rest = rest[12+length:]
+
+		if chunk.typ == "IEND" {
+			break
+		}
+	}
+
+	return chunks, nil
+}
+
+func findPNGChunk(data []byte, typ string) ([]byte, bool) {
+	chunks, err := readPNGChunks(data)
+	if err != nil {
+		return nil, false
+	}
+	for _, chunk := range chunks {
+		if chunk.typ == typ {
+			return chunk.data, true
+		}
+		if chunk.typ == "IDAT" {
+			// acTL must come before the image data.
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+func writePNGChunk(buf *bytes.Buffer, typ string, data []byte) {
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.WriteString(typ)
+	buf.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	binary.Write(buf, binary.BigEndian, crc.Sum32())
+}
+
+// apngFrame is a frame control chunk and its image data.
+type apngFrame struct {
+	width, height uint32
+	x, y          int
+	delay         time.Duration
+	dispose       byte
+	blend         byte
+	data          [][]byte
+}
+
+func decodeAPNG(data []byte) ([]Frame, error) {
+	chunks, err := readPNGChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var header []byte
+	var shared []pngChunk
+	var animation []*apngFrame
+	var current *apngFrame
+	seenIDAT := false
+
+	for _, chunk := range chunks {
+		switch chunk.typ {
+		case "IHDR":
+			header = chunk.data
+		case "fcTL":
+			if len(chunk.data) < 26 {
+				return nil, fmt.Errorf("invalid fcTL chunk")
+			}
+			num := binary.BigEndian.Uint16(chunk.data[20:22])
+			den := binary.BigEndian.Uint16(chunk.data[22:24])
+			if den == 0 {
+				den = 100
+			}
+			current = &apngFrame{
+				width:   binary.BigEndian.Uint32(chunk.data[4:8]),
+				height:  binary.BigEndian.Uint32(chunk.data[8:12]),
+				x:       int(binary.BigEndian.Uint32(chunk.data[12:16])),
+				y:       int(binary.BigEndian.Uint32(chunk.data[16:20])),
+				delay:   frameDelay(time.Duration(num) * time.Second / time.Duration(den)),
+				dispose: chunk.data[24],
+				blend:   chunk.data[25],
+			}
+			animation = append(animation, current)
+		case "IDAT":
+			seenIDAT = true
+			// The default image is only part of the animation when an fcTL precedes it.
+			if current != nil {
+				current.data = append(current.data, chunk.data)
+			}
+		case "fdAT":
+			if current != nil && len(chunk.data) > 4 {
+				current.data = append(current.data, chunk.data[4:])
+			}
+		case "acTL", "IEND":
+		default:
+			// Palette, transparency and colour chunks apply to every frame.
+			if !seenIDAT {
+				shared = append(shared, chunk)
+			}
+		}
+	}
+
+	if len(header) < 13 {
+		return nil, fmt.Errorf("missing IHDR chunk")
+	}
+
+	width, height := binary.BigEndian.Uint32(header[0:4]), binary.BigEndian.Uint32(header[4:8])
+	if width > maxCanvasSide || height > maxCanvasSide {
+		return nil, fmt.Errorf("invalid animation size %dx%d", width, height)
+	}
+
+	limit, err := frameLimit(int(width), int(height))
+	if err != nil {
+		return nil, err
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	frames := make([]Frame, 0, min(len(animation), limit))
+
+	for i, f := range animation {
+		if i == limit {
+			break
+		}
+
+		if f.width > maxCanvasSide || f.height > maxCanvasSide || f.x > maxCanvasSide || f.y > maxCanvasSide {
+			return nil, fmt.Errorf("apng frame %d is outside the canvas", i)
+		}
+		rect := image.Rect(f.x, f.y, f.x+int(f.width), f.y+int(f.height))
+		if err := inCanvas(rect, canvas); err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		buf.Write(pngSignature)
+
+		ihdr := append([]byte(nil), header...)
+		binary.BigEndian.PutUint32(ihdr[0:4], f.width)
+		binary.BigEndian.PutUint32(ihdr[4:8], f.height)
+		writePNGChunk(&buf, "IHDR", ihdr)
+
+		for _, chunk := range shared {
+			writePNGChunk(&buf, chunk.typ, chunk.data)
+		}
+		for _, d := range f.data {
+			writePNGChunk(&buf, "IDAT", d)
+		}
+		writePNGChunk(&buf, "IEND", nil)
+
+		img, err := png.Decode(&buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode apng frame %d: %w", i, err)
+		}
+		if img.Bounds().Dx() != rect.Dx() || img.Bounds().Dy() != rect.Dy() {
+			return nil, fmt.Errorf("apng frame %d does not match its frame control", i)
+		}
+
+		var previous *image.RGBA
+		if f.dispose == 2 {
+			previous = cloneRGBA(canvas)
+		}
+
+		op := draw.Over
+		if f.blend == 0 {
+			op = draw.Src
+		}
+		draw.Draw(canvas, rect, img, img.Bounds().Min, op)
+		frames = append(frames, Frame{Image: cloneRGBA(canvas), Delay: f.delay})
+
+		switch f.dispose {
+		case 1:
+			draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+		case 2:
+			canvas = previous
+		}
+	}
+
+	return frames, nil
+}
+
+func isWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+type riffChunk struct {
+	fourCC string
+	data   []byte
+}
+
+func readRIFFChunks(data []byte) ([]riffChunk, error) {
+	var chunks []riffChunk
+	for len(data) >= 8 {
+		size := binary.LittleEndian.Uint32(data[4:8])
+		if uint64(size)+8 > uint64(len(data)) {
+			return nil, fmt.Errorf("truncated webp chunk")
+		}
+
+		chunks = append(chunks, riffChunk{fourCC: string(data[0:4]), data: data[8 : 8+size]})
+
+		// Chunks are padded to an even size.
+		next := 8 + int(size) + int(size&1)
+		if next > len(data) {
+			break
+		}
+		data = data[next:]
+	}
+	return chunks, nil
+}
+
+func uint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+func writeRIFFChunk(buf *bytes.Buffer, fourCC string, data []byte) {
+	buf.WriteString(fourCC)
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	if len(data)&1 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+// webpFrame wraps the image chunks of an ANMF frame in a standalone WebP.
+func webpFrame(chunks []riffChunk, width, height int) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+
+	hasAlpha := false
+	for _, chunk := range chunks {
+		if chunk.fourCC == "ALPH" {
+			hasAlpha = true
+		}
+	}
+
+	if hasAlpha {
+		vp8x := make([]byte, 10)
+		vp8x[0] = 0x10
+		vp8x[4], vp8x[5], vp8x[6] = byte(width-1), byte((width-1)>>8), byte((width-1)>>16)
+		vp8x[7], vp8x[8], vp8x[9] = byte(height-1), byte((height-1)>>8), byte((height-1)>>16)
+		writeRIFFChunk(&body, "VP8X", vp8x)
+	}
+
+	for _, chunk := range chunks {
+		switch chunk.fourCC {
+		case "ALPH", "VP8 ", "VP8L":
+			writeRIFFChunk(&body, chunk.fourCC, chunk.data)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+func decodeWebP(data []byte) ([]Frame, error) {
+	chunks, err := readRIFFChunks(data[12:])
+	if err != nil {
+		return nil, err
+	}
+
+	var canvas *image.RGBA
+	limit := 0
+	frames := make([]Frame, 0)
+
+	for _, chunk := range chunks {
+		switch chunk.fourCC {
+		case "VP8X":
+			if len(chunk.data) < 10 {
+				return nil, fmt.Errorf("invalid VP8X chunk")
+			}
+			width, height := uint24(chunk.data[4:7])+1, uint24(chunk.data[7:10])+1
+			if limit, err = frameLimit(width, height); err != nil {
+				return nil, err
+			}
+			canvas = image.NewRGBA(image.Rect(0, 0, width, height))
+		case "ANMF":
+			if canvas == nil || len(chunk.data) < 16 {
+				return nil, fmt.Errorf("invalid ANMF chunk")
+			}
+			if len(frames) == limit {
+				continue
+			}
+
+			x := uint24(chunk.data[0:3]) * 2
+			y := uint24(chunk.data[3:6]) * 2
+			width := uint24(chunk.data[6:9]) + 1
+			height := uint24(chunk.data[9:12]) + 1
+			duration := time.Duration(uint24(chunk.data[12:15])) * time.Millisecond
+			flags := chunk.data[15]
+
+			rect := image.Rect(x, y, x+width, y+height)
+			if err := inCanvas(rect, canvas); err != nil {
+				return nil, err
+			}
+
+			sub, err := readRIFFChunks(chunk.data[16:])
+			if err != nil {
+				return nil, err
+			}
+
+			img, err := webp.Decode(bytes.NewReader(webpFrame(sub, width, height)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode webp frame %d: %w", len(frames), err)
+			}
+
+			op := draw.Over
+			if flags&0x02 != 0 {
+				op = draw.Src
+			}
+			draw.Draw(canvas, rect, img, img.Bounds().Min, op)
+			frames = append(frames, Frame{Image: cloneRGBA(canvas), Delay: frameDelay(duration)})
+
+			if flags&0x01 != 0 {
+				draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	return frames, nil
+}
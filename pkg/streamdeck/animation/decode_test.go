@@ -0,0 +1,508 @@
+package animation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	transparent = color.RGBA{}
+	red         = color.RGBA{R: 0xff, A: 0xff}
+	green       = color.RGBA{G: 0xff, A: 0xff}
+	blue        = color.RGBA{B: 0xff, A: 0xff}
+
+	palette = color.Palette{transparent, red, green, blue}
+)
+
+// paletted returns an image of a single colour of palette covering rect.
+func paletted(rect image.Rectangle, c color.Color) *image.Paletted {
+	img := image.NewPaletted(rect, palette)
+	for i := range img.Pix {
+		img.Pix[i] = uint8(palette.Index(c))
+	}
+	return img
+}
+
+// testGIFFrame is an image with its delay in hundredths of a second.
+type testGIFFrame struct {
+	image    *image.Paletted
+	delay    int
+	disposal byte
+}
+
+func encodeGIF(t testing.TB, width, height int, frames []testGIFFrame) []byte {
+	t.Helper()
+
+	g := &gif.GIF{Config: image.Config{ColorModel: palette, Width: width, Height: height}}
+	for _, f := range frames {
+		g.Image = append(g.Image, f.image)
+		g.Delay = append(g.Delay, f.delay)
+		g.Disposal = append(g.Disposal, f.disposal)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("encoding gif: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// testAPNGFrame is a frame with its frame control fields.
+type testAPNGFrame struct {
+	image          *image.Paletted
+	num, den       uint16
+	dispose, blend byte
+}
+
+// encodeAPNG writes frames as an APNG, the first frame being the default
+// image. Every frame shares the palette, so the frames differ only in their
+// image data.
+func encodeAPNG(t testing.TB, width, height int, frames []testAPNGFrame) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+
+	sequence := uint32(0)
+	for i, f := range frames {
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, f.image); err != nil {
+			t.Fatalf("encoding png: %v", err)
+		}
+		chunks, err := readPNGChunks(encoded.Bytes())
+		if err != nil {
+			t.Fatalf("reading png: %v", err)
+		}
+
+		if i == 0 {
+			ihdr := append([]byte(nil), chunks[0].data...)
+			binary.BigEndian.PutUint32(ihdr[0:4], uint32(width))
+			binary.BigEndian.PutUint32(ihdr[4:8], uint32(height))
+			writePNGChunk(&buf, "IHDR", ihdr)
+
+			actl := make([]byte, 8)
+			binary.BigEndian.PutUint32(actl[0:4], uint32(len(frames)))
+			writePNGChunk(&buf, "acTL", actl)
+
+			for _, chunk := range chunks {
+				if chunk.typ == "PLTE" || chunk.typ == "tRNS" {
+					writePNGChunk(&buf, chunk.typ, chunk.data)
+				}
+			}
+		}
+
+		bounds := f.image.Bounds()
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:4], sequence)
+		binary.BigEndian.PutUint32(fctl[4:8], uint32(bounds.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:12], uint32(bounds.Dy()))
+		binary.BigEndian.PutUint32(fctl[12:16], uint32(bounds.Min.X))
+		binary.BigEndian.PutUint32(fctl[16:20], uint32(bounds.Min.Y))
+		binary.BigEndian.PutUint16(fctl[20:22], f.num)
+		binary.BigEndian.PutUint16(fctl[22:24], f.den)
+		fctl[24], fctl[25] = f.dispose, f.blend
+		writePNGChunk(&buf, "fcTL", fctl)
+		sequence++
+
+		for _, chunk := range chunks {
+			if chunk.typ != "IDAT" {
+				continue
+			}
+			if i == 0 {
+				writePNGChunk(&buf, "IDAT", chunk.data)
+				continue
+			}
+			fdat := binary.BigEndian.AppendUint32(nil, sequence)
+			writePNGChunk(&buf, "fdAT", append(fdat, chunk.data...))
+			sequence++
+		}
+	}
+
+	writePNGChunk(&buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+// bitWriter writes the least significant bits first, as VP8L does.
+type bitWriter struct {
+	buf   []byte
+	nbits uint
+}
+
+func (w *bitWriter) write(v uint32, n uint) {
+	for i := uint(0); i < n; i++ {
+		if w.nbits%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		w.buf[len(w.buf)-1] |= byte(v>>i&1) << (w.nbits % 8)
+		w.nbits++
+	}
+}
+
+// solidVP8L encodes a lossless WebP bitstream of a single colour. Every
+// prefix code has a single symbol, so the pixels take no bits at all.
+func solidVP8L(width, height int, c color.NRGBA) []byte {
+	var w bitWriter
+	w.write(0x2f, 8)
+	w.write(uint32(width-1), 14)
+	w.write(uint32(height-1), 14)
+	w.write(1, 1) // alpha is used
+	w.write(0, 3) // version
+	w.write(0, 1) // no transform
+	w.write(0, 1) // no colour cache
+	w.write(0, 1) // no meta prefix codes
+
+	// Green, red, blue and alpha: one 8-bit symbol each.
+	for _, v := range []uint8{c.G, c.R, c.B, c.A} {
+		w.write(1, 1)
+		w.write(0, 1)
+		w.write(1, 1)
+		w.write(uint32(v), 8)
+	}
+	// Distance: the 1-bit symbol 0.
+	w.write(1, 1)
+	w.write(0, 1)
+	w.write(0, 1)
+	w.write(0, 1)
+
+	return w.buf
+}
+
+// testWebPFrame is a solid ANMF frame. x must be even.
+type testWebPFrame struct {
+	x, y, width, height int
+	color               color.NRGBA
+	duration            int // milliseconds
+	flags               byte
+}
+
+func put24(b []byte, v int) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+func encodeWebP(width, height int, frames []testWebPFrame) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x02 | 0x10
+	put24(vp8x[4:7], width-1)
+	put24(vp8x[7:10], height-1)
+	writeRIFFChunk(&body, "VP8X", vp8x)
+	writeRIFFChunk(&body, "ANIM", make([]byte, 6))
+
+	for _, f := range frames {
+		anmf := make([]byte, 16)
+		put24(anmf[0:3], f.x/2)
+		put24(anmf[3:6], f.y/2)
+		put24(anmf[6:9], f.width-1)
+		put24(anmf[9:12], f.height-1)
+		put24(anmf[12:15], f.duration)
+		anmf[15] = f.flags
+
+		frame := bytes.NewBuffer(anmf)
+		writeRIFFChunk(frame, "VP8L", solidVP8L(f.width, f.height, f.color))
+		writeRIFFChunk(&body, "ANMF", frame.Bytes())
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+func nrgba(c color.RGBA) color.NRGBA {
+	return color.NRGBAModel.Convert(c).(color.NRGBA)
+}
+
+// testGIF, testAPNG and testWebP each play four frames exercising disposal
+// and blending, checked by TestDecode.
+func testGIF(t testing.TB) []byte {
+	return encodeGIF(t, 2, 1, []testGIFFrame{
+		{image: paletted(image.Rect(0, 0, 2, 1), red), delay: 10},
+		{image: paletted(image.Rect(1, 0, 2, 1), green), delay: 5, disposal: gif.DisposalBackground},
+		{image: paletted(image.Rect(0, 0, 1, 1), blue), delay: 0, disposal: gif.DisposalPrevious},
+		{image: paletted(image.Rect(0, 0, 1, 1), transparent), delay: 1},
+	})
+}
+
+func testAPNG(t testing.TB) []byte {
+	return encodeAPNG(t, 2, 1, []testAPNGFrame{
+		{image: paletted(image.Rect(0, 0, 2, 1), red), num: 1, den: 10},
+		{image: paletted(image.Rect(1, 0, 2, 1), transparent), num: 50, den: 1000, dispose: 1, blend: 1},
+		{image: paletted(image.Rect(0, 0, 1, 1), green), num: 0, den: 1000, dispose: 2},
+		{image: paletted(image.Rect(0, 0, 1, 1), transparent), num: 3, den: 0},
+	})
+}
+
+func testWebP() []byte {
+	return encodeWebP(4, 1, []testWebPFrame{
+		{x: 0, width: 2, height: 1, color: nrgba(red), duration: 100},
+		{x: 2, width: 2, height: 1, color: nrgba(green), duration: 50, flags: 0x01},
+		{x: 0, width: 2, height: 1, color: nrgba(transparent), duration: 0, flags: 0x02},
+		{x: 2, width: 2, height: 1, color: nrgba(blue), duration: 30},
+	})
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		points []image.Point // pixels checked on every frame
+		want   [][]color.RGBA
+		delays []time.Duration
+	}{
+		{
+			// The background disposal clears the green pixel, the previous
+			// disposal brings back the red one and a transparent frame drawn
+			// over the canvas changes nothing.
+			name:   "gif",
+			data:   testGIF(t),
+			points: []image.Point{{0, 0}, {1, 0}},
+			want:   [][]color.RGBA{{red, red}, {red, green}, {blue, transparent}, {red, transparent}},
+			delays: []time.Duration{100 * time.Millisecond, 50 * time.Millisecond, defaultDelay, defaultDelay},
+		},
+		{
+			// A transparent frame blended over the canvas changes nothing,
+			// one replacing it clears the pixel.
+			name:   "apng",
+			data:   testAPNG(t),
+			points: []image.Point{{0, 0}, {1, 0}},
+			want:   [][]color.RGBA{{red, red}, {red, red}, {green, transparent}, {transparent, transparent}},
+			delays: []time.Duration{100 * time.Millisecond, 50 * time.Millisecond, defaultDelay, 30 * time.Millisecond},
+		},
+		{
+			name:   "webp",
+			data:   testWebP(),
+			points: []image.Point{{0, 0}, {2, 0}},
+			want:   [][]color.RGBA{{red, transparent}, {red, green}, {transparent, transparent}, {transparent, blue}},
+			delays: []time.Duration{100 * time.Millisecond, 50 * time.Millisecond, defaultDelay, 30 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !IsAnimated(tt.data) {
+				t.Error("IsAnimated = false")
+			}
+
+			frames, err := Decode(tt.data)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if len(frames) != len(tt.want) {
+				t.Fatalf("got %d frames, want %d", len(frames), len(tt.want))
+			}
+
+			for i, frame := range frames {
+				if frame.Delay != tt.delays[i] {
+					t.Errorf("frame %d delay = %v, want %v", i, frame.Delay, tt.delays[i])
+				}
+				for j, p := range tt.points {
+					got := color.RGBAModel.Convert(frame.Image.At(p.X, p.Y)).(color.RGBA)
+					if got != tt.want[i][j] {
+						t.Errorf("frame %d pixel %v = %v, want %v", i, p, got, tt.want[i][j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeStill(t *testing.T) {
+	var still bytes.Buffer
+	if err := png.Encode(&still, paletted(image.Rect(0, 0, 2, 1), red)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]byte{
+		"png":            still.Bytes(),
+		"gif":            encodeGIF(t, 2, 1, []testGIFFrame{{image: paletted(image.Rect(0, 0, 2, 1), red)}}),
+		"single frame":   encodeAPNG(t, 2, 1, []testAPNGFrame{{image: paletted(image.Rect(0, 0, 2, 1), red)}}),
+		"unknown format": []byte("BM not an animation"),
+	}
+
+	for name, data := range tests {
+		if _, err := Decode(data); !errors.Is(err, ErrNotAnimated) {
+			t.Errorf("%s: Decode = %v, want ErrNotAnimated", name, err)
+		}
+	}
+
+	if IsAnimated(still.Bytes()) {
+		t.Error("IsAnimated(png) = true")
+	}
+}
+
+func TestDecodeFrameLimits(t *testing.T) {
+	var gifFrames []testGIFFrame
+	var apngFrames []testAPNGFrame
+	var webpFrames []testWebPFrame
+	for i := 0; i < MaxFrames+5; i++ {
+		gifFrames = append(gifFrames, testGIFFrame{image: paletted(image.Rect(0, 0, 2, 1), red)})
+		apngFrames = append(apngFrames, testAPNGFrame{image: paletted(image.Rect(0, 0, 2, 1), red)})
+		webpFrames = append(webpFrames, testWebPFrame{width: 2, height: 1, color: nrgba(red)})
+	}
+
+	// DecodeAll would decode every frame of a gif, so it is refused.
+	if _, err := Decode(encodeGIF(t, 2, 1, gifFrames)); err == nil || !strings.Contains(err.Error(), "frames") {
+		t.Errorf("gif with %d frames: Decode = %v, want a frame count error", len(gifFrames), err)
+	}
+
+	// The others are decoded frame by frame and cut short.
+	for name, data := range map[string][]byte{
+		"apng": encodeAPNG(t, 2, 1, apngFrames),
+		"webp": encodeWebP(2, 1, webpFrames),
+	} {
+		frames, err := Decode(data)
+		if err != nil {
+			t.Errorf("%s: Decode: %v", name, err)
+			continue
+		}
+		if len(frames) != MaxFrames {
+			t.Errorf("%s: got %d frames, want %d", name, len(frames), MaxFrames)
+		}
+	}
+
+	// Larger canvases keep fewer frames.
+	width, height := 2048, 1024
+	limit := maxDecodedPixels / (width * height)
+	gifFrames = gifFrames[:limit+1]
+	for i := range gifFrames {
+		gifFrames[i].image = paletted(image.Rect(0, 0, 1, 1), red)
+	}
+	if _, err := Decode(encodeGIF(t, width, height, gifFrames)); err == nil {
+		t.Errorf("gif of %dx%d with %d frames decoded, want at most %d", width, height, len(gifFrames), limit)
+	}
+	if _, err := Decode(encodeGIF(t, width, height, gifFrames[:limit])); err != nil {
+		t.Errorf("gif of %dx%d with %d frames: Decode: %v", width, height, limit, err)
+	}
+}
+
+func TestDecodeCanvasBounds(t *testing.T) {
+	twoFrames := func(rect image.Rectangle) []testGIFFrame {
+		return []testGIFFrame{{image: paletted(rect, red)}, {image: paletted(rect, green)}}
+	}
+
+	apngOffset := encodeAPNG(t, 2, 1, []testAPNGFrame{
+		{image: paletted(image.Rect(0, 0, 2, 1), red)},
+		{image: paletted(image.Rect(1, 0, 3, 1), green)},
+	})
+
+	tests := map[string][]byte{
+		"gif wider than the canvas limit": encodeGIF(t, maxCanvasSide+1, 1, twoFrames(image.Rect(0, 0, 1, 1))),
+		"gif with too many pixels":        encodeGIF(t, 2048, 2048, twoFrames(image.Rect(0, 0, 1, 1))),
+		"apng wider than the canvas limit": encodeAPNG(t, maxCanvasSide+1, 1, []testAPNGFrame{
+			{image: paletted(image.Rect(0, 0, 1, 1), red)},
+			{image: paletted(image.Rect(0, 0, 1, 1), green)},
+		}),
+		"apng frame outside the canvas": apngOffset,
+		"webp wider than the canvas limit": encodeWebP(maxCanvasSide+2, 1, []testWebPFrame{
+			{width: 2, height: 1, color: nrgba(red)},
+			{width: 2, height: 1, color: nrgba(green)},
+		}),
+		"webp frame outside the canvas": encodeWebP(2, 1, []testWebPFrame{
+			{width: 2, height: 1, color: nrgba(red)},
+			{x: 2, width: 2, height: 1, color: nrgba(green)},
+		}),
+	}
+
+	for name, data := range tests {
+		if _, err := Decode(data); err == nil || errors.Is(err, ErrNotAnimated) {
+			t.Errorf("%s: Decode = %v, want an error", name, err)
+		}
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	gifData, apngData, webpData := testGIF(t), testAPNG(t), testWebP()
+
+	// The second fcTL chunk of testAPNG, after the signature, IHDR, acTL,
+	// PLTE, tRNS, the first fcTL and IDAT.
+	chunks, err := readPNGChunks(apngData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := len(pngSignature)
+	fctls := 0
+	for _, chunk := range chunks {
+		if chunk.typ == "fcTL" {
+			fctls++
+			if fctls == 2 {
+				break
+			}
+		}
+		offset += 12 + len(chunk.data)
+	}
+
+	shortFCTL := append([]byte(nil), apngData...)
+	binary.BigEndian.PutUint32(shortFCTL[offset:], 8)
+
+	corruptIDAT := append([]byte(nil), apngData...)
+	for i := offset - 8; i > offset-16; i-- {
+		corruptIDAT[i] ^= 0xff
+	}
+
+	// ANMF chunks need the canvas of the VP8X chunk before them.
+	noVP8X := append([]byte(nil), webpData...)
+	copy(noVP8X[12:16], "JUNK")
+
+	tests := map[string][]byte{
+		"truncated gif":        gifData[:len(gifData)-12],
+		"truncated apng":       apngData[:len(apngData)-20],
+		"truncated webp":       webpData[:len(webpData)-10],
+		"short fcTL":           shortFCTL,
+		"corrupt apng IDAT":    corruptIDAT,
+		"webp without VP8X":    noVP8X,
+		"gif header only":      gifData[:13],
+		"png signature only":   pngSignature,
+		"webp header only":     webpData[:12],
+		"apng without a frame": apngData[:offset],
+	}
+
+	for name, data := range tests {
+		frames, err := Decode(data)
+		if err == nil {
+			t.Errorf("%s: decoded %d frames, want an error", name, len(frames))
+		}
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add(testGIF(f))
+	f.Add(testAPNG(f))
+	f.Add(testWebP())
+	f.Add(encodeWebP(maxCanvasSide+2, 1, []testWebPFrame{{width: 2, height: 1}}))
+	f.Add(pngSignature)
+	f.Add([]byte("GIF89a"))
+	f.Add([]byte("RIFF\x04\x00\x00\x00WEBP"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		IsAnimated(data)
+
+		frames, err := Decode(data)
+		if err != nil {
+			return
+		}
+		if len(frames) < 2 || len(frames) > MaxFrames {
+			t.Fatalf("decoded %d frames", len(frames))
+		}
+
+		bounds := frames[0].Image.Bounds()
+		for i, frame := range frames {
+			if frame.Image.Bounds() != bounds {
+				t.Fatalf("frame %d is %v, the first frame %v", i, frame.Image.Bounds(), bounds)
+			}
+			if frame.Delay < minDelay {
+				t.Fatalf("frame %d delay %v is below %v", i, frame.Delay, minDelay)
+			}
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package animation
+
+import (
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"sd/pkg/util"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Encode scales frames to size and encodes them as JPEG key images with the
+// button title drawn on top.
+func Encode(frames []Frame, size int, title string, style types.TitleStyle) ([]KeyFrame, error) {
+	keyFrames := make([]KeyFrame, 0, len(frames))
+
+	for _, frame := range frames {
+		encoded, err := util.EncodeImage(util.FillImage(frame.Image, size, size), util.ImageSpec{Format: util.FormatJPEG})
+		if err != nil {
+			return nil, err
+		}
+
+		buffer, err := util.DrawTitle(encoded, title, style)
+		if err != nil {
+			return nil, err
+		}
+
+		keyFrames = append(keyFrames, KeyFrame{Buffer: buffer, Delay: frame.Delay})
+	}
+
+	return keyFrames, nil
+}
+
+// ButtonFrames returns the key frames for the current state of a button, or
+// nil if its image is not animated.
+func ButtonFrames(button types.Button, size int) ([]KeyFrame, error) {
+	data, err := store.GetStateImage(button.CurrentState())
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsAnimated(data) {
+		return nil, nil
+	}
+
+	frames, err := Decode(data)
+	if err == ErrNotAnimated {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return Encode(frames, size, button.Title, button.TitleStyle)
+}
+
+// SetButton writes the rendered buffer of a button to a key, playing the
+// button image on the key instead when it is animated.
+func (s *Scheduler) SetButton(keyID int, bufferKey string, buffer []byte, size int) error {
+	key := strings.TrimSuffix(bufferKey, ".buffer")
+
+	// A feedback colour replaces the image, so there is nothing to play.
+	button, err := store.GetButton(key)
+	if feedback := store.GetFeedback(key); err == nil && (feedback == nil || feedback.Color == "") {
+		frames, err := ButtonFrames(button, size)
+		if err != nil {
+			log.Warn().Err(err).Str("key", bufferKey).Msg("Failed to decode animated key, showing a still")
+		}
+		if len(frames) > 0 {
+			s.Animate(keyID, frames)
+			return nil
+		}
+	}
+
+	return s.SetKey(keyID, buffer)
+}
@@ -0,0 +1,33 @@
+package animation
+
+import (
+	"bytes"
+	"image"
+	"sd/pkg/types"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	frames, err := Decode(testGIF(t))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	keyFrames, err := Encode(frames, 72, "", types.TitleStyle{})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if len(keyFrames) != len(frames) {
+		t.Fatalf("got %d key frames, want %d", len(keyFrames), len(frames))
+	}
+
+	for i, keyFrame := range keyFrames {
+		if keyFrame.Delay != frames[i].Delay {
+			t.Errorf("key frame %d delay = %v, want %v", i, keyFrame.Delay, frames[i].Delay)
+		}
+		config, format, err := image.DecodeConfig(bytes.NewReader(keyFrame.Buffer))
+		if err != nil || format != "jpeg" || config.Width != 72 || config.Height != 72 {
+			t.Errorf("key frame %d = %s %dx%d (%v), want a 72x72 jpeg", i, format, config.Width, config.Height, err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package animation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxWritesPerTick caps the keys written in one batch so a page full of
+// animations still leaves the device time to report input.
+const maxWritesPerTick = 8
+
+// WriteFunc writes an encoded image to a key on the device.
+type WriteFunc func(keyID int, buffer []byte) error
+
+// KeyFrame is an encoded frame ready to be written to a key.
+type KeyFrame struct {
+	Buffer []byte
+	Delay  time.Duration
+}
+
+type animatedKey struct {
+	frames []KeyFrame
+	index  int
+	next   time.Time
+}
+
+// Scheduler is the single writer of key images on a device. It plays
+// animated keys at their frame timing and serialises them with still images.
+type Scheduler struct {
+	write WriteFunc
+
+	writeMu sync.Mutex // serialises HID writes, a key image spans several reports
+
+	mu   sync.Mutex
+	keys map[int]*animatedKey
+	wake chan struct{}
+}
+
+// NewScheduler creates a scheduler writing to a device with write. Call Run
+// to start playing animations.
+func NewScheduler(write WriteFunc) *Scheduler {
+	return &Scheduler{
+		write: write,
+		keys:  make(map[int]*animatedKey),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// SetKey writes a still image to a key, stopping any animation on it.
+func (s *Scheduler) SetKey(keyID int, buffer []byte) error {
+	s.Stop(keyID)
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.write(keyID, buffer)
+}
+
+// Exclusive runs a write other than a key image, such as a touch strip
+// segment, between the key writes of the scheduler.
+func (s *Scheduler) Exclusive(write func() error) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return write()
+}
+
+// Animate plays frames on a key until it is stopped or replaced.
+func (s *Scheduler) Animate(keyID int, frames []KeyFrame) {
+	if len(frames) == 0 {
+		s.Stop(keyID)
+		return
+	}
+
+	s.mu.Lock()
+	s.keys[keyID] = &animatedKey{frames: frames, next: time.Now()}
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// Stop stops the animation on a key, leaving the current frame displayed.
+func (s *Scheduler) Stop(keyID int) {
+	s.mu.Lock()
+	delete(s.keys, keyID)
+	s.mu.Unlock()
+}
+
+// Clear stops every animation.
+func (s *Scheduler) Clear() {
+	s.mu.Lock()
+	clear(s.keys)
+	s.mu.Unlock()
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+type pendingWrite struct {
+	keyID  int
+	buffer []byte
+}
+
+// due collects the frames to write now and returns how long to sleep until
+// the next one.
+func (s *Scheduler) due(now time.Time) ([]pendingWrite, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := make([]pendingWrite, 0, maxWritesPerTick)
+	wait := time.Hour
+
+	for keyID, key := range s.keys {
+		if !key.next.After(now) && len(batch) < maxWritesPerTick {
+			frame := key.frames[key.index]
+			batch = append(batch, pendingWrite{keyID: keyID, buffer: frame.Buffer})
+
+			key.index = (key.index + 1) % len(key.frames)
+			key.next = key.next.Add(frame.Delay)
+
+			// Drop frames we fell behind on rather than playing them fast.
+			if key.next.Before(now) {
+				key.next = now.Add(frame.Delay)
+			}
+		}
+
+		wait = min(wait, max(key.next.Sub(now), 0))
+	}
+
+	return batch, wait
+}
+
+// Run plays animations until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+
+		// Collect under the write lock so SetKey can't be overwritten by a stale frame.
+		s.writeMu.Lock()
+		batch, wait := s.due(time.Now())
+		for _, w := range batch {
+			if err := s.write(w.keyID, w.buffer); err != nil {
+				log.Error().Err(err).Int("key", w.keyID).Msg("Failed to write animation frame")
+			}
+		}
+		s.writeMu.Unlock()
+
+		timer.Reset(wait)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sd/pkg/actions"
+	"sd/pkg/models"
 	"sd/pkg/natsconn"
 	"sd/pkg/store"
 	"sd/pkg/types"
@@ -14,13 +16,8 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// Constants for device configuration
-const (
-	vendorID  = 0x0fd9
-	productID = 0x0086
-	numKeys   = 3
-	keySize   = 96
-)
+// model describes the Stream Deck Pedal hardware.
+var model, _ = models.ByType(models.TypePedal)
 
 type Pedal struct {
 	instanceID string
@@ -70,7 +67,7 @@ func (pedal *Pedal) ensureDeviceConnection() error {
 		return nil
 	}
 
-	devices := hid.Enumerate(vendorID, productID)
+	devices := hid.Enumerate(models.VendorIDElgato, model.ProductID)
 	if len(devices) == 0 {
 		return fmt.Errorf("no Stream Deck Pedal devices found")
 	}
@@ -97,12 +94,13 @@ func (pedal *Pedal) ensureDefaultProfile() error {
 	return nil
 }
 
-func (pedal *Pedal) handleButtonPress(buttonIndex int, nc *nats.Conn, kv nats.KeyValue) error {
+// switchKey returns the key of a switch of the current profile.
+func (pedal *Pedal) switchKey(switchNumber int) string {
 	device := store.GetDevice(pedal.instanceID, pedal.device.Serial)
+	return store.SwitchKey(pedal.instanceID, pedal.device.Serial, device.CurrentProfile, switchNumber)
+}
 
-	key := fmt.Sprintf("instances.%s.devices.%s.profiles.%s.switches.%d",
-		pedal.instanceID, pedal.device.Serial, device.CurrentProfile, buttonIndex)
-
+func (pedal *Pedal) handleButtonPress(key string, nc *nats.Conn, kv nats.KeyValue) error {
 	entry, err := kv.Get(key)
 	if err != nil {
 		return fmt.Errorf("failed to get switch data: %w", err)
@@ -117,13 +115,64 @@ func (pedal *Pedal) handleButtonPress(buttonIndex int, nc *nats.Conn, kv nats.Ke
 		return fmt.Errorf("missing UUID in payload")
 	}
 
-	return nc.Publish(payload.UUID, entry.Value())
+	return actions.Publish(nc, payload.UUID, key, entry.Value())
+}
+
+// handleButtonRelease tells the action of the switch pressed with key that
+// the switch was let go.
+func (pedal *Pedal) handleButtonRelease(key string, nc *nats.Conn) error {
+	button, err := store.GetButton(key)
+	if err != nil {
+		return fmt.Errorf("failed to get switch data: %w", err)
+	}
+	return actions.PublishRelease(nc, key, button)
+}
+
+// handleReport acts on the switches pressed and released since the last
+// report. pressed holds the switches that are down, with the key they
+// pressed.
+func (pedal *Pedal) handleReport(buf []byte, pressed map[int]string, nc *nats.Conn, kv nats.KeyValue) {
+	down := make(map[int]bool)
+	for _, switchNumber := range util.ParseEventBuffer(buf) {
+		if switchNumber != 0 {
+			down[switchNumber] = true
+		}
+	}
+
+	// Reports list every switch that is down, act on the changes.
+	for switchNumber := range down {
+		if _, ok := pressed[switchNumber]; ok {
+			continue
+		}
+
+		log.Info().Int("switch", switchNumber).Msg("Switch pressed")
+
+		key := pedal.switchKey(switchNumber)
+		pressed[switchNumber] = key
+
+		if err := pedal.handleButtonPress(key, nc, kv); err != nil {
+			log.Error().Err(err).Msg("Error handling switch press")
+		}
+	}
+
+	for switchNumber, key := range pressed {
+		if down[switchNumber] {
+			continue
+		}
+		delete(pressed, switchNumber)
+
+		if err := pedal.handleButtonRelease(key, nc); err != nil {
+			log.Error().Err(err).Msg("Error handling switch release")
+		}
+	}
 }
 
 func (pedal *Pedal) handleButtonInput(ctx context.Context) {
-	buf := make([]byte, 512)
+	buf := make([]byte, model.InputReportLength)
 	nc, kv := natsconn.GetNATSConn()
 
+	pressed := make(map[int]string)
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -136,21 +185,7 @@ func (pedal *Pedal) handleButtonInput(ctx context.Context) {
 			}
 
 			if n > 0 {
-				pressedButtons := util.ParseEventBuffer(buf)
-
-				// TODO implement long press.
-				for _, buttonIndex := range pressedButtons {
-					// Ignore button up event for now.
-					if buttonIndex == 0 {
-						continue
-					}
-
-					log.Info().Int("buttonIndex", buttonIndex).Msg("Switch pressed")
-
-					if err := pedal.handleButtonPress(buttonIndex, nc, kv); err != nil {
-						log.Error().Err(err).Msg("Error handling switch press")
-					}
-				}
+				pedal.handleReport(buf, pressed, nc, kv)
 			}
 		}
 	}
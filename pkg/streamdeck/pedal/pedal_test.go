@@ -0,0 +1,156 @@
+package pedal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sd/pkg/actions"
+	"sd/pkg/models"
+	"sd/pkg/natsconn"
+	"sd/pkg/natsconn/natstest"
+	"sd/pkg/plugins/browser"
+	"sd/pkg/plugins/command"
+	"sd/pkg/store"
+	"sd/pkg/types"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karalabe/hid"
+	"github.com/nats-io/nats.go"
+)
+
+const testInstance = "test"
+
+func TestMain(m *testing.M) {
+	os.Exit(natstest.Run(m))
+}
+
+// newPedal stores a pedal on its default profile with switches bound.
+func newPedal(t *testing.T, serial string, switches map[int]types.Button) *Pedal {
+	t.Helper()
+
+	device := &types.Device{ID: serial, Instance: testInstance, Type: models.TypePedal, Status: "connected", CurrentProfile: "default"}
+	if _, err := store.UpdateDevice(testInstance, device); err != nil {
+		t.Fatalf("storing the pedal: %v", err)
+	}
+
+	_, kv := natsconn.GetNATSConn()
+	for n, button := range switches {
+		data, err := json.Marshal(button)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := kv.Put(store.SwitchKey(testInstance, serial, "default", n), data); err != nil {
+			t.Fatalf("binding switch %d: %v", n, err)
+		}
+	}
+
+	return &Pedal{instanceID: testInstance, device: &hid.Device{DeviceInfo: hid.DeviceInfo{Serial: serial}}}
+}
+
+// report returns an input report with the given switches down.
+func report(switches ...int) []byte {
+	buf := make([]byte, model.InputReportLength)
+	for _, n := range switches {
+		buf[4+n-1] = 0x01
+	}
+	return buf
+}
+
+// press presses and releases a switch.
+func press(p *Pedal, switchNumber int) {
+	nc, kv := natsconn.GetNATSConn()
+
+	pressed := make(map[int]string)
+	p.handleReport(report(switchNumber), pressed, nc, kv)
+	p.handleReport(report(), pressed, nc, kv)
+}
+
+// waitForFile returns the content of a file once it was written.
+func waitForFile(t *testing.T, path string) string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+			return string(data)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatalf("%s was not written", path)
+	return ""
+}
+
+func TestSwitchRunsStoredCommand(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir) // for the command audit log
+
+	ran := filepath.Join(dir, "ran")
+	p := newPedal(t, "PEDAL1", map[int]types.Button{
+		2: {UUID: "sd.plugin.command.exec", Settings: types.Settings{Command: "echo pedal > " + ran}},
+	})
+
+	command.OpenSubscriber(testInstance)
+	press(p, 2)
+
+	if got := waitForFile(t, ran); got != "pedal\n" {
+		t.Errorf("command wrote %q, want pedal", got)
+	}
+}
+
+func TestSwitchOpensStoredURL(t *testing.T) {
+	dir := t.TempDir()
+
+	// A browser writing the arguments it was started with.
+	opened := filepath.Join(dir, "opened")
+	script := "#!/bin/sh\necho \"$@\" > " + opened + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "firefox"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	p := newPedal(t, "PEDAL2", map[int]types.Button{
+		1: {UUID: "sd.plugin.browser.open_url", Settings: types.Settings{URL: "https://example.com/", Browser: "firefox"}},
+	})
+
+	(&browser.BrowserPlugin{InstanceID: testInstance}).Init()
+	press(p, 1)
+
+	if got := strings.TrimSpace(waitForFile(t, opened)); got != "-- https://example.com/" {
+		t.Errorf("browser started with %q, want the stored URL", got)
+	}
+}
+
+func TestSwitchRelease(t *testing.T) {
+	nc, _ := natsconn.GetNATSConn()
+
+	hold := "sd.plugin.keyboard.hold"
+	p := newPedal(t, "PEDAL3", map[int]types.Button{
+		3: {UUID: hold, Settings: types.Settings{Keys: "shift"}},
+	})
+
+	messages := make(chan *nats.Msg, 4)
+	for _, subject := range []string{hold, actions.ReleaseSubject(hold)} {
+		sub, err := nc.ChanSubscribe(subject, messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Unsubscribe()
+	}
+
+	press(p, 3)
+
+	key := store.SwitchKey(testInstance, "PEDAL3", "default", 3)
+	for _, want := range []string{hold, actions.ReleaseSubject(hold)} {
+		select {
+		case m := <-messages:
+			if m.Subject != want || actions.ButtonKey(m) != key {
+				t.Errorf("got %s for %q, want %s for %q", m.Subject, actions.ButtonKey(m), want, key)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s message", want)
+		}
+	}
+}
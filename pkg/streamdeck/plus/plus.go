@@ -2,15 +2,21 @@ package plus
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"sd/pkg/env"
+	"sd/pkg/actions"
+	"sd/pkg/models"
+	"sd/pkg/multiaction"
 	"sd/pkg/natsconn"
+	"sd/pkg/navigation"
 	"sd/pkg/store"
+	"sd/pkg/streamdeck/animation"
 	"sd/pkg/types"
 	"sd/pkg/util"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/karalabe/hid"
@@ -18,13 +24,11 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// Constants for device configuration
-const (
-	vendorID  = 0x0fd9
-	productID = 0x0084
-	numKeys   = 8
-	keySize   = 120
+// model describes the Stream Deck Plus hardware.
+var model, _ = models.ByType(models.TypePlus)
 
+// Constants for device input and the touch screen
+const (
 	DialTurningFlag          = 0x01
 	DialTurnRight            = 0x01
 	DialTurnLeft             = 0xFF
@@ -49,6 +53,9 @@ type Plus struct {
 	wasDialPressed   [4]bool
 	wasScreenPressed bool
 	lastX            int
+	keys             *animation.Scheduler
+	mu               *sync.Mutex
+	location         navigation.Location
 	//touchScreen      *TouchScreenManager
 }
 
@@ -57,6 +64,7 @@ type DialEvent struct {
 	IsTurning bool
 	IsPressed bool
 	Direction int // 1 for right, -1 for left, 0 for press/release
+	Ticks     int // detents turned, negative to the left
 }
 
 type TouchEvent struct {
@@ -73,6 +81,7 @@ func New(instanceID string, device *hid.Device) Plus {
 		device:     device,
 		ctx:        ctx,
 		cancel:     cancel,
+		mu:         &sync.Mutex{},
 	}
 	//plus.touchScreen = NewTouchScreenManager(&plus)
 	return plus
@@ -94,6 +103,11 @@ func (plus *Plus) Init() error {
 		return err
 	}
 
+	plus.keys = animation.NewScheduler(func(keyID int, buffer []byte) error {
+		return model.WriteKeyImage(plus.device, keyID, buffer)
+	})
+	go plus.keys.Run(plus.ctx)
+
 	plus.blankAllKeys()
 
 	if err := plus.ensureDefaultProfile(); err != nil {
@@ -103,7 +117,10 @@ func (plus *Plus) Init() error {
 	// Start watchers and input handlers
 	go plus.watchForButtonChanges(plus.ctx)
 	go plus.watchKVForButtonImageBufferChanges(plus.ctx)
+	go plus.watchDials(plus.ctx)
 	go plus.handleInput(plus.ctx)
+	go navigation.Follow(plus.ctx, plus.instanceID, plus.device.Serial, plus.showPage)
+	go navigation.FollowBrightness(plus.ctx, plus.instanceID, plus.device.Serial, plus.setBrightness)
 
 	// Initialize touch screen with current profile
 	//device := store.GetDevice(plus.instanceID, plus.device.Serial)
@@ -117,34 +134,93 @@ func (plus *Plus) Init() error {
 	return nil
 }
 
-func BlankKey(device *hid.Device, keyId int, buffer []byte) {
-	// Update Key.
-	util.SetKeyFromBuffer(device, keyId, buffer, false)
+// blankBuffer converts the default image from the Object Store for a key.
+func blankBuffer() ([]byte, error) {
+	image, err := store.GetDefaultImage()
+	if err != nil {
+		return nil, err
+	}
+
+	return util.ConvertButtonImage(image, model.KeySize)
 }
 
 func (plus *Plus) blankAllKeys() {
-	var assetPath = env.Get("ASSET_PATH", "")
-	var buffer, err = util.ConvertButtonImageToBuffer(assetPath+"images/correct.png", keySize)
+	buffer, err := blankBuffer()
 
 	if err != nil {
 		log.Error().Err(err).Msg("Could not convert blank image to buffer")
+		return
 	}
 
-	for i := 1; i <= numKeys; i++ {
-		BlankKey(plus.device, i, buffer)
+	plus.keys.Clear()
+
+	for i := 1; i <= model.Keys(); i++ {
+		plus.keys.SetKey(i, buffer)
 	}
 }
 
-func (plus *Plus) watchKVForButtonImageBufferChanges(ctx context.Context) {
+// currentLocation returns the profile page shown on the device.
+func (plus *Plus) currentLocation() navigation.Location {
+	plus.mu.Lock()
+	defer plus.mu.Unlock()
+	return plus.location
+}
+
+// setBrightness writes the brightness between key images, as both share
+// the device.
+func (plus *Plus) setBrightness(percent int) {
+	err := plus.keys.Exclusive(func() error {
+		return model.SetBrightness(plus.device, percent)
+	})
+	if err != nil {
+		log.Error().Err(err).Int("brightness", percent).Msg("Failed to set brightness")
+	}
+}
+
+// showPage draws every key of a profile page.
+func (plus *Plus) showPage(location navigation.Location) {
+	plus.mu.Lock()
+	profileChanged := plus.location.ProfileID != location.ProfileID
+	plus.location = location
+	plus.mu.Unlock()
+
+	if profileChanged {
+		go plus.drawSegments(location.ProfileID)
+	}
+
 	_, kv := natsconn.GetNATSConn()
 
-	device := store.GetDevice(plus.instanceID, plus.device.Serial)
-	profile := store.GetProfile(plus.instanceID, device, device.CurrentProfile)
+	blank, err := blankBuffer()
+	if err != nil {
+		log.Error().Err(err).Msg("Could not convert blank image to buffer")
+	}
+
+	plus.keys.Clear()
+
+	for i := 1; i <= model.Keys(); i++ {
+		key := navigation.BufferKey(plus.instanceID, plus.device.Serial, location, i)
+
+		entry, err := kv.Get(key)
+		if err != nil {
+			if blank != nil {
+				plus.keys.SetKey(i, blank)
+			}
+			continue
+		}
+
+		if err := plus.keys.SetButton(i, key, entry.Value(), model.KeySize); err != nil {
+			log.Error().Err(err).Int("key", i).Msg("Failed to update key")
+		}
+	}
+}
 
-	pattern := fmt.Sprintf("instances.%s.devices.%s.profiles.%s.pages.%s.buttons.*.buffer",
-		plus.instanceID, plus.device.Serial, device.CurrentProfile, profile.CurrentPage)
+func (plus *Plus) watchKVForButtonImageBufferChanges(ctx context.Context) {
+	_, kv := natsconn.GetNATSConn()
+
+	pattern := fmt.Sprintf("instances.%s.devices.%s.profiles.*.pages.*.buttons.*.buffer",
+		plus.instanceID, plus.device.Serial)
 
-	watcher, err := kv.Watch(pattern)
+	watcher, err := kv.Watch(pattern, nats.UpdatesOnly())
 	if err != nil {
 		log.Error().Err(err).Msg("Error creating watcher")
 		return
@@ -160,15 +236,16 @@ func (plus *Plus) watchKVForButtonImageBufferChanges(ctx context.Context) {
 				continue
 			}
 
+			location, id, ok := navigation.ParseBufferKey(update.Key())
+			if !ok || location != plus.currentLocation() {
+				continue
+			}
+
 			switch update.Operation() {
 			case nats.KeyValuePut:
-				segments := strings.Split(update.Key(), ".")
-				sdKeyId := segments[len(segments)-2]
-				id, err := strconv.Atoi(sdKeyId)
-				if err != nil {
-					continue
+				if err := plus.keys.SetButton(id, update.Key(), update.Value(), model.KeySize); err != nil {
+					log.Error().Err(err).Int("key", id).Msg("Failed to update key")
 				}
-				util.SetKeyFromBuffer(plus.device, id, update.Value(), false)
 			}
 		}
 	}
@@ -195,6 +272,10 @@ func (plus *Plus) watchForButtonChanges(ctx context.Context) {
 			}
 
 			segments := strings.Split(update.Key(), ".")
+			if len(segments) != 10 || (navigation.Location{ProfileID: segments[5], PageID: segments[7]}) != plus.currentLocation() {
+				continue
+			}
+
 			buttonNum := segments[len(segments)-1]
 
 			id, err := strconv.Atoi(buttonNum)
@@ -202,35 +283,28 @@ func (plus *Plus) watchForButtonChanges(ctx context.Context) {
 				continue
 			}
 
-			switch update.Operation() {
-			case nats.KeyValueDelete:
-				buffer, _ := util.ConvertButtonImageToBuffer(env.Get("ASSET_PATH", "")+"images/correct.png", keySize)
-				BlankKey(plus.device, id, buffer)
-			case nats.KeyValuePut:
-				var button types.Button
-				if err := json.Unmarshal(update.Value(), &button); err != nil {
-					log.Error().Err(err).Msg("Failed to unmarshal button")
+			// Puts are rendered into the button buffer by the store.
+			if update.Operation() == nats.KeyValueDelete {
+				buffer, err := blankBuffer()
+				if err != nil {
+					log.Error().Err(err).Msg("Could not convert blank image to buffer")
 					continue
 				}
-				if len(button.States) > 0 {
-					buf, err := util.ConvertButtonImageToBuffer(button.States[0].ImagePath, keySize)
-					if err != nil {
-						log.Error().Err(err).Msg("Failed to create button buffer")
-						continue
-					}
-					BlankKey(plus.device, id, buf)
-				}
+				plus.keys.SetKey(id, buffer)
 			}
 		}
 	}
 }
 
-func (plus *Plus) handleButtonPress(buttonIndex int) {
-	device := store.GetDevice(plus.instanceID, plus.device.Serial)
-	profile := store.GetProfile(plus.instanceID, device, device.CurrentProfile)
+// buttonKey returns the key of a button of a page.
+func (plus *Plus) buttonKey(location navigation.Location, buttonIndex int) string {
+	return fmt.Sprintf("instances.%s.devices.%s.profiles.%s.pages.%s.buttons.%d",
+		plus.instanceID, plus.device.Serial, location.ProfileID, location.PageID, buttonIndex)
+}
 
-	key := fmt.Sprintf("instances.%s.devices.%s.profiles.%s.pages.%s.buttons.%s",
-		plus.instanceID, plus.device.Serial, device.CurrentProfile, profile.CurrentPage, strconv.Itoa(buttonIndex))
+func (plus *Plus) handleButtonPress(location navigation.Location, buttonIndex int) {
+	device := store.GetDevice(plus.instanceID, plus.device.Serial)
+	key := plus.buttonKey(location, buttonIndex)
 
 	button, err := store.GetButton(key)
 	if err != nil {
@@ -238,6 +312,20 @@ func (plus *Plus) handleButtonPress(buttonIndex int) {
 		return
 	}
 
+	if navigation.IsNavigation(button.UUID) {
+		if err := navigation.Handle(plus.instanceID, device, button); err != nil {
+			log.Error().Err(err).Msg("Failed to navigate")
+		}
+		return
+	}
+
+	if multiaction.IsMultiAction(button.UUID) {
+		if err := multiaction.Handle(plus.instanceID, device, location, button); err != nil {
+			log.Error().Err(err).Msg("Failed to run multi action")
+		}
+		return
+	}
+
 	// Get NATS connection
 	nc, _ := natsconn.GetNATSConn()
 
@@ -258,7 +346,53 @@ func (plus *Plus) handleButtonPress(buttonIndex int) {
 	}
 
 	// Publish to NATS using the UUID as the topic
-	nc.Publish(button.UUID, data)
+	if err := actions.Publish(nc, button.UUID, key, data); err != nil {
+		log.Error().Err(err).Msg("Failed to publish button press")
+	}
+}
+
+// handleButtonRelease tells the action of the button pressed with key that
+// the key was let go.
+func (plus *Plus) handleButtonRelease(key string) {
+	button, err := store.GetButton(key)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to get button configuration")
+		return
+	}
+
+	nc, _ := natsconn.GetNATSConn()
+	if err := actions.PublishRelease(nc, key, button); err != nil {
+		log.Error().Err(err).Msg("Failed to publish button release")
+	}
+}
+
+// handleKeyEvent acts on the keys pressed or released since the last key
+// report, which lists every key that is down.
+func (plus *Plus) handleKeyEvent(buf []byte, pressed map[int]string) {
+	down := make(map[int]bool)
+	for _, buttonIndex := range util.ParseEventBuffer(buf[:4+model.Keys()]) {
+		if buttonIndex != 0 {
+			down[buttonIndex] = true
+		}
+	}
+
+	for buttonIndex := range down {
+		if _, ok := pressed[buttonIndex]; ok {
+			continue
+		}
+
+		location := plus.currentLocation()
+		pressed[buttonIndex] = plus.buttonKey(location, buttonIndex)
+		plus.handleButtonPress(location, buttonIndex)
+	}
+
+	for buttonIndex, key := range pressed {
+		if down[buttonIndex] {
+			continue
+		}
+		delete(pressed, buttonIndex)
+		plus.handleButtonRelease(key)
+	}
 }
 
 func (plus *Plus) handleDialEvent(buf []byte) {
@@ -280,20 +414,20 @@ func (plus *Plus) handleDialEvent(buf []byte) {
 		}
 
 		if isTurning {
-			switch dialValue {
-			case DialTurnRight:
+			// Fast turns report several detents, as a signed byte.
+			event.Ticks = int(int8(dialValue))
+			switch {
+			case event.Ticks > 0:
 				event.Direction = 1
-				log.Info().
-					Int("dial", event.DialIndex).
-					Msg("Dial turned right")
-			case DialTurnLeft:
+			case event.Ticks < 0:
 				event.Direction = -1
-				log.Info().
-					Int("dial", event.DialIndex).
-					Msg("Dial turned left")
 			default:
 				continue
 			}
+			log.Debug().
+				Int("dial", event.DialIndex).
+				Int("ticks", event.Ticks).
+				Msg("Dial turned")
 		} else {
 			// Not turning - handle press/release
 			if dialValue == DialPressedFlag {
@@ -371,10 +505,134 @@ func (plus *Plus) handleDialAction(event DialEvent) {
 	topic := fmt.Sprintf("instances.%s.devices.%s.dials.%d",
 		plus.instanceID, plus.device.Serial, event.DialIndex)
 	nc.Publish(topic, data)
+
+	plus.dispatchDial(event)
+}
+
+// dispatchDial sends a dial event to the action the dial is bound to in the
+// current profile.
+func (plus *Plus) dispatchDial(event DialEvent) {
+	key := store.DialKey(plus.instanceID, plus.device.Serial, plus.currentLocation().ProfileID, event.DialIndex)
+
+	dial, err := store.GetDial(key)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to get dial configuration")
+		return
+	}
+	if dial.UUID == "" || dial.UUID == actions.None {
+		return
+	}
+
+	input := actions.DialRelease
+	switch {
+	case event.IsTurning:
+		input = actions.DialRotate
+	case event.IsPressed:
+		input = actions.DialPress
+	}
+
+	nc, _ := natsconn.GetNATSConn()
+	if err := actions.PublishDial(nc, key, dial, input, event.Ticks); err != nil {
+		log.Error().Err(err).Msg("Failed to publish dial event")
+	}
+}
+
+// drawSegment draws the title and feedback of the dial below a touch strip
+// segment.
+func (plus *Plus) drawSegment(profileID string, dialIndex int) {
+	key := store.DialKey(plus.instanceID, plus.device.Serial, profileID, dialIndex)
+
+	dial, err := store.GetDial(key)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to get dial configuration")
+		return
+	}
+
+	title := dial.Title
+	var level *int
+	var background []byte
+	if feedback := store.GetFeedback(key); feedback != nil {
+		if feedback.Title != "" {
+			title = feedback.Title
+		}
+		level = feedback.Level
+		if feedback.Image != "" {
+			if background, err = base64.StdEncoding.DecodeString(feedback.Image); err != nil {
+				log.Warn().Err(err).Str("key", key).Msg("Invalid dial feedback image")
+			}
+		}
+	}
+
+	buffer, err := util.RenderSegment(title, level, background, SegmentWidth, ScreenHeight)
+	if err != nil {
+		log.Error().Err(err).Int("dial", dialIndex).Msg("Failed to render touch strip segment")
+		return
+	}
+
+	if err := plus.keys.Exclusive(func() error {
+		return plus.SetScreenSegment(dialIndex, buffer)
+	}); err != nil {
+		log.Error().Err(err).Int("dial", dialIndex).Msg("Failed to draw touch strip segment")
+	}
+}
+
+// drawSegments draws the touch strip for the dials of a profile.
+func (plus *Plus) drawSegments(profileID string) {
+	for i := 1; i <= model.Dials; i++ {
+		plus.drawSegment(profileID, i)
+	}
+}
+
+// watchDials redraws the touch strip segment of a dial of the current
+// profile when its binding or feedback changes.
+func (plus *Plus) watchDials(ctx context.Context) {
+	_, kv := natsconn.GetNATSConn()
+
+	pattern := fmt.Sprintf("instances.%s.devices.%s.profiles.*.dials.>", plus.instanceID, plus.device.Serial)
+
+	watcher, err := kv.Watch(pattern, nats.UpdatesOnly())
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating dial watcher")
+		return
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update := <-watcher.Updates():
+			// Drawing a segment takes a while, only draw the latest state
+			// of dials updated in the meantime, such as a turning volume.
+			dirty := make(map[int]bool)
+			for more := true; more; {
+				if update != nil {
+					profileID, dialIndex, ok := store.ParseDialKey(strings.TrimSuffix(update.Key(), ".feedback"))
+					if ok && profileID == plus.currentLocation().ProfileID {
+						dirty[dialIndex] = true
+					}
+				}
+
+				select {
+				case update = <-watcher.Updates():
+				default:
+					more = false
+				}
+			}
+
+			profileID := plus.currentLocation().ProfileID
+			for dialIndex := range dirty {
+				plus.drawSegment(profileID, dialIndex)
+			}
+		}
+	}
 }
 
 func (plus *Plus) handleInput(ctx context.Context) {
-	buf := make([]byte, 512)
+	buf := make([]byte, model.InputReportLength)
+
+	// Keys that are down, with the button they pressed.
+	pressed := make(map[int]string)
 
 	for {
 		select {
@@ -401,8 +659,8 @@ func (plus *Plus) handleInput(ctx context.Context) {
 				}
 
 				// Handle button events
-				if buf[0] == 0x01 {
-					plus.handleButtonPress(int(buf[1]))
+				if buf[0] == 0x01 && buf[1] == 0x00 {
+					plus.handleKeyEvent(buf, pressed)
 				}
 			}
 		}
@@ -528,7 +786,7 @@ func (plus *Plus) ensureDeviceConnection() error {
 		return nil
 	}
 
-	devices := hid.Enumerate(vendorID, productID)
+	devices := hid.Enumerate(models.VendorIDElgato, model.ProductID)
 	if len(devices) == 0 {
 		return fmt.Errorf("no Stream Deck Plus devices found")
 	}
@@ -1,6 +1,7 @@
 package streamdeck
 
 import (
+	"sd/pkg/models"
 	"sd/pkg/streamdeck/pedal"
 	"sd/pkg/streamdeck/plus"
 	"sd/pkg/streamdeck/xl"
@@ -11,13 +12,6 @@ import (
 	"github.com/karalabe/hid"
 )
 
-const (
-	VendorIDElgato = 0x0fd9
-	ProductIDXL    = 0x006c
-	ProductIDPlus  = 0x0084
-	ProductIDPedal = 0x0086
-)
-
 var devices = struct {
 	sync.RWMutex
 	list map[string]*StreamDeck
@@ -31,7 +25,7 @@ type StreamDeck struct {
 }
 
 func New(instanceID string, deviceID string, productID uint16) error {
-	devices := hid.Enumerate(VendorIDElgato, productID)
+	devices := hid.Enumerate(models.VendorIDElgato, productID)
 	if len(devices) == 0 {
 		return fmt.Errorf("no devices found with product ID: %x", productID)
 	}
@@ -41,14 +35,14 @@ func New(instanceID string, deviceID string, productID uint16) error {
 		return fmt.Errorf("failed to open device: %w", err)
 	}
 
-	switch productID {
-	case ProductIDXL:
+	switch models.DeviceType(productID) {
+	case models.TypeXL:
 		xlDevice := xl.New(instanceID, device)
 		return xlDevice.Init()
-	case ProductIDPlus:
+	case models.TypePlus:
 		plusDevice := plus.New(instanceID, device)
 		return plusDevice.Init()
-	case ProductIDPedal:
+	case models.TypePedal:
 		pedalDevice := pedal.New(instanceID, device)
 		return pedalDevice.Init()
 	default:
@@ -4,32 +4,35 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sd/pkg/env"
+	"sd/pkg/actions"
+	"sd/pkg/models"
+	"sd/pkg/multiaction"
 	"sd/pkg/natsconn"
+	"sd/pkg/navigation"
 	"sd/pkg/store"
+	"sd/pkg/streamdeck/animation"
 	"sd/pkg/types"
 	"sd/pkg/util"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/karalabe/hid"
 	"github.com/nats-io/nats.go"
 	"github.com/rs/zerolog/log"
 )
 
-// Constants for device configuration
-const (
-	vendorID  = 0x0fd9
-	productID = 0x006c
-	numKeys   = 32
-	keySize   = 96
-)
+// model describes the Stream Deck XL hardware.
+var model, _ = models.ByType(models.TypeXL)
 
 type XL struct {
 	instanceID string
 	device     *hid.Device
 	cancel     context.CancelFunc
 	ctx        context.Context
+	keys       *animation.Scheduler
+	mu         *sync.Mutex
+	location   navigation.Location
 }
 
 func New(instanceID string, device *hid.Device) XL {
@@ -39,6 +42,7 @@ func New(instanceID string, device *hid.Device) XL {
 		device:     device,
 		ctx:        ctx,
 		cancel:     cancel,
+		mu:         &sync.Mutex{},
 	}
 }
 
@@ -58,6 +62,11 @@ func (xl *XL) Init() error {
 		return err
 	}
 
+	xl.keys = animation.NewScheduler(func(keyID int, buffer []byte) error {
+		return model.WriteKeyImage(xl.device, keyID, buffer)
+	})
+	go xl.keys.Run(xl.ctx)
+
 	xl.blankAllKeys()
 
 	if err := xl.ensureDefaultProfile(); err != nil {
@@ -68,7 +77,8 @@ func (xl *XL) Init() error {
 	go xl.watchForButtonChanges(xl.ctx)
 	go xl.watchKVForButtonImageBufferChanges(xl.ctx)
 	go xl.handleButtonInput(xl.ctx)
-	go xl.watchKVForProfileChanges(xl.ctx)
+	go navigation.Follow(xl.ctx, xl.instanceID, xl.device.Serial, xl.showPage)
+	go navigation.FollowBrightness(xl.ctx, xl.instanceID, xl.device.Serial, xl.setBrightness)
 
 	return nil
 }
@@ -78,7 +88,7 @@ func (xl *XL) ensureDeviceConnection() error {
 		return nil
 	}
 
-	devices := hid.Enumerate(vendorID, productID)
+	devices := hid.Enumerate(models.VendorIDElgato, model.ProductID)
 	if len(devices) == 0 {
 		return fmt.Errorf("no Stream Deck XL devices found")
 	}
@@ -110,12 +120,15 @@ func (xl *XL) ensureDefaultProfile() error {
 	return nil
 }
 
-func (xl *XL) handleButtonPress(buttonIndex int, nc *nats.Conn, kv nats.KeyValue) error {
-	device := store.GetDevice(xl.instanceID, xl.device.Serial)
-	profile := store.GetProfile(xl.instanceID, device, device.CurrentProfile)
+// buttonKey returns the key of a button of a page.
+func (xl *XL) buttonKey(location navigation.Location, buttonIndex int) string {
+	return fmt.Sprintf("instances.%s.devices.%s.profiles.%s.pages.%s.buttons.%d",
+		xl.instanceID, xl.device.Serial, location.ProfileID, location.PageID, buttonIndex)
+}
 
-	key := fmt.Sprintf("instances.%s.devices.%s.profiles.%s.pages.%s.buttons.%d",
-		xl.instanceID, xl.device.Serial, device.CurrentProfile, profile.CurrentPage, buttonIndex)
+func (xl *XL) handleButtonPress(location navigation.Location, buttonIndex int, nc *nats.Conn, kv nats.KeyValue) error {
+	device := store.GetDevice(xl.instanceID, xl.device.Serial)
+	key := xl.buttonKey(location, buttonIndex)
 
 	entry, err := kv.Get(key)
 	if err != nil {
@@ -131,13 +144,42 @@ func (xl *XL) handleButtonPress(buttonIndex int, nc *nats.Conn, kv nats.KeyValue
 		return fmt.Errorf("missing UUID in payload")
 	}
 
-	return nc.Publish(payload.UUID, entry.Value())
+	if navigation.IsNavigation(payload.UUID) {
+		button, err := store.GetButton(key)
+		if err != nil {
+			return fmt.Errorf("failed to get button data: %w", err)
+		}
+		return navigation.Handle(xl.instanceID, device, button)
+	}
+
+	if multiaction.IsMultiAction(payload.UUID) {
+		button, err := store.GetButton(key)
+		if err != nil {
+			return fmt.Errorf("failed to get button data: %w", err)
+		}
+		return multiaction.Handle(xl.instanceID, device, location, button)
+	}
+
+	return actions.Publish(nc, payload.UUID, key, entry.Value())
+}
+
+// handleButtonRelease tells the action of the button pressed with key that
+// the key was let go.
+func (xl *XL) handleButtonRelease(key string, nc *nats.Conn) error {
+	button, err := store.GetButton(key)
+	if err != nil {
+		return fmt.Errorf("failed to get button data: %w", err)
+	}
+	return actions.PublishRelease(nc, key, button)
 }
 
 func (xl *XL) handleButtonInput(ctx context.Context) {
-	buf := make([]byte, 512)
+	buf := make([]byte, model.InputReportLength)
 	nc, kv := natsconn.GetNATSConn()
 
+	// Keys that are down, with the button they pressed.
+	pressed := make(map[int]string)
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -150,41 +192,76 @@ func (xl *XL) handleButtonInput(ctx context.Context) {
 			}
 
 			if n > 0 {
-				pressedButtons := util.ParseEventBuffer(buf)
-
-				// TODO implement long press.
-				for _, buttonIndex := range pressedButtons {
+				down := make(map[int]bool)
+				for _, buttonIndex := range util.ParseEventBuffer(buf) {
+					if buttonIndex != 0 {
+						down[buttonIndex] = true
+					}
+				}
 
-					// Ignore button up event for now.
-					if buttonIndex == 0 {
+				// Reports list every key that is down, act on the changes.
+				for buttonIndex := range down {
+					if _, ok := pressed[buttonIndex]; ok {
 						continue
 					}
 
 					log.Info().Int("buttonIndex", buttonIndex).Msg("Button pressed")
 
-					if err := xl.handleButtonPress(buttonIndex, nc, kv); err != nil {
+					location := xl.currentLocation()
+					pressed[buttonIndex] = xl.buttonKey(location, buttonIndex)
+
+					if err := xl.handleButtonPress(location, buttonIndex, nc, kv); err != nil {
 						log.Error().Err(err).Msg("Error handling button press")
 					}
 				}
+
+				for buttonIndex, key := range pressed {
+					if down[buttonIndex] {
+						continue
+					}
+					delete(pressed, buttonIndex)
+
+					if err := xl.handleButtonRelease(key, nc); err != nil {
+						log.Error().Err(err).Msg("Error handling button release")
+					}
+				}
 			}
 		}
 	}
 }
 
+// blankBuffer converts the default image from the Object Store for a key.
+func blankBuffer() ([]byte, error) {
+	image, err := store.GetDefaultImage()
+	if err != nil {
+		return nil, err
+	}
+
+	return util.ConvertButtonImage(image, model.KeySize)
+}
+
 func (xl *XL) blankKey(keyId int) {
-	var assetPath = env.Get("ASSET_PATH", "")
-	var buffer, err = util.ConvertImageToBuffer(assetPath+"images/correct.png", keySize)
+	buffer, err := blankBuffer()
 
 	if err != nil {
 		log.Error().Err(err).Msg("Could not convert blank image to buffer")
+		return
 	}
-	// Update Key with rotation for XL
-	util.SetKeyFromBuffer(xl.device, keyId, buffer, true)
+	xl.keys.SetKey(keyId, buffer)
 }
 
 func (xl *XL) blankAllKeys() {
-	for i := 1; i <= numKeys; i++ {
-		xl.blankKey(i)
+	buffer, err := blankBuffer()
+
+	if err != nil {
+		log.Error().Err(err).Msg("Could not convert blank image to buffer")
+		return
+	}
+
+	xl.keys.Clear()
+
+	for i := 1; i <= model.Keys(); i++ {
+		xl.keys.SetKey(i, buffer)
 	}
 }
 
@@ -210,6 +287,10 @@ func (xl *XL) watchForButtonChanges(ctx context.Context) {
 
 			// Get button number from the key
 			segments := strings.Split(update.Key(), ".")
+			if len(segments) != 10 || (navigation.Location{ProfileID: segments[5], PageID: segments[7]}) != xl.currentLocation() {
+				continue
+			}
+
 			buttonNum := segments[len(segments)-1]
 
 			id, err := strconv.Atoi(buttonNum)
@@ -217,57 +298,60 @@ func (xl *XL) watchForButtonChanges(ctx context.Context) {
 				continue
 			}
 
-			switch update.Operation() {
-			case nats.KeyValueDelete:
+			// Puts are rendered into the button buffer by the store.
+			if update.Operation() == nats.KeyValueDelete {
 				xl.blankKey(id)
-			case nats.KeyValuePut:
-				var button types.Button
-				if err := json.Unmarshal(update.Value(), &button); err != nil {
-					log.Error().Err(err).Msg("Failed to unmarshal button")
-					continue
-				}
-				if len(button.States) > 0 {
-					buf, err := util.ConvertImageToBuffer(button.States[0].ImagePath, 96)
-					if err != nil {
-						log.Error().Err(err).Msg("Failed to create button buffer")
-						continue
-					}
-
-					key := update.Key() + ".buffer"
-					kv.Put(key, buf)
-				}
 			}
 		}
 	}
 }
 
-func (xl *XL) watchKVForProfileChanges(ctx context.Context) {
-	_, kv := natsconn.GetNATSConn()
+// currentLocation returns the profile page shown on the device.
+func (xl *XL) currentLocation() navigation.Location {
+	xl.mu.Lock()
+	defer xl.mu.Unlock()
+	return xl.location
+}
+
+// setBrightness writes the brightness between key images, as both share
+// the device.
+func (xl *XL) setBrightness(percent int) {
+	err := xl.keys.Exclusive(func() error {
+		return model.SetBrightness(xl.device, percent)
+	})
+	if err != nil {
+		log.Error().Err(err).Int("brightness", percent).Msg("Failed to set brightness")
+	}
+}
 
-	pattern := "instances.*.devices.*.profiles.*"
+// showPage draws every key of a profile page.
+func (xl *XL) showPage(location navigation.Location) {
+	xl.mu.Lock()
+	xl.location = location
+	xl.mu.Unlock()
 
-	watcher, err := kv.Watch(pattern)
+	_, kv := natsconn.GetNATSConn()
 
+	blank, err := blankBuffer()
 	if err != nil {
-		log.Error().Err(err).Msg("Error creating watcher")
-		return
+		log.Error().Err(err).Msg("Could not convert blank image to buffer")
 	}
 
-	defer watcher.Stop()
+	xl.keys.Clear()
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case update := <-watcher.Updates():
-			if update == nil {
-				continue
-			}
+	for i := 1; i <= model.Keys(); i++ {
+		key := navigation.BufferKey(xl.instanceID, xl.device.Serial, location, i)
 
-			log.Info().Str("key", update.Key()).Msg("Profile changed")
+		entry, err := kv.Get(key)
+		if err != nil {
+			if blank != nil {
+				xl.keys.SetKey(i, blank)
+			}
+			continue
+		}
 
-			xl.blankAllKeys()
-			// TODO Update all keys with the new profile
+		if err := xl.keys.SetButton(i, key, entry.Value(), model.KeySize); err != nil {
+			log.Error().Err(err).Int("key", i).Msg("Failed to update key")
 		}
 	}
 }
@@ -275,15 +359,11 @@ func (xl *XL) watchKVForProfileChanges(ctx context.Context) {
 func (xl *XL) watchKVForButtonImageBufferChanges(ctx context.Context) {
 	_, kv := natsconn.GetNATSConn()
 
-	// Get current profile and page, with error checking
-	device := store.GetDevice(xl.instanceID, xl.device.Serial)
-	profile := store.GetProfile(xl.instanceID, device, device.CurrentProfile)
-
-	// Create watcher with error handling
-	pattern := fmt.Sprintf("instances.%s.devices.%s.profiles.%s.pages.%s.buttons.*.buffer",
-		xl.instanceID, xl.device.Serial, device.CurrentProfile, profile.CurrentPage)
+	// Buffers of every page are watched, only the page shown is drawn.
+	pattern := fmt.Sprintf("instances.%s.devices.%s.profiles.*.pages.*.buttons.*.buffer",
+		xl.instanceID, xl.device.Serial)
 
-	watcher, err := kv.Watch(pattern)
+	watcher, err := kv.Watch(pattern, nats.UpdatesOnly())
 	if err != nil {
 		log.Error().Err(err).Msg("Error creating watcher")
 		return
@@ -299,27 +379,18 @@ func (xl *XL) watchKVForButtonImageBufferChanges(ctx context.Context) {
 				continue
 			}
 
+			location, id, ok := navigation.ParseBufferKey(update.Key())
+			if !ok || location != xl.currentLocation() {
+				continue
+			}
+
 			switch update.Operation() {
 			case nats.KeyValuePut:
 				log.Info().Str("key", update.Key()).Msg("Key added/updated")
-				// Get Stream Deck key id from the kv key.
-
-				// Split the string by the delimiter ".".
-				segments := strings.Split(update.Key(), ".")
-
-				// Get the last segment.
-				sdKeyId := segments[len(segments)-2]
 
-				// Convert to an int.
-				id, err := strconv.Atoi(sdKeyId)
-
-				if err != nil {
-					// ... handle error.
-					panic(err)
+				if err := xl.keys.SetButton(id, update.Key(), update.Value(), model.KeySize); err != nil {
+					log.Error().Err(err).Int("key", id).Msg("Failed to update key")
 				}
-
-				// Update Key with rotation for XL
-				util.SetKeyFromBuffer(xl.device, id, update.Value(), true)
 			case nats.KeyValueDelete:
 				log.Info().Str("key", update.Key()).Msg("Key deleted")
 			default:
@@ -1,6 +1,10 @@
 package types
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
 
 type Plugin interface {
 	Name() string
@@ -51,6 +55,21 @@ func (p Profile) IsEmpty() bool {
 	return p.ID == ""
 }
 
+// ProfileManifest is the manifest.json of an exported profile archive.
+type ProfileManifest struct {
+	Version    int                        `json:"version"`
+	DeviceType string                     `json:"deviceType"`
+	Profile    Profile                    `json:"profile"`
+	Pages      []ProfileManifestPage      `json:"pages"`
+	Config     map[string]json.RawMessage `json:"config,omitempty"` // Other profile keys such as dials, touch strip and pedal switches, relative to the profile key
+	Images     []Image                    `json:"images"`           // Stored as images/<id> in the archive
+}
+
+type ProfileManifestPage struct {
+	Page    Page     `json:"page"`
+	Buttons []Button `json:"buttons"`
+}
+
 type Instance struct {
 	ID     string `json:"id"`
 	Status string `json:"status"`
@@ -66,21 +85,38 @@ type Device struct {
 	Type           string `json:"type"`
 	Status         string `json:"status"`
 	CurrentProfile string `json:"currentProfile"`
+	Brightness     int    `json:"brightness,omitempty"` // percent, 0 leaves the device as it is
 }
 
 func (d Device) IsEmpty() bool {
 	return d.ID == ""
 }
 
+// FocusRule switches a device to a profile while a matching window is focused.
+// Class and Title are regular expressions, an empty expression matches anything.
+type FocusRule struct {
+	ID        string `json:"id"`
+	Class     string `json:"class,omitempty"`
+	Title     string `json:"title,omitempty"`
+	ProfileID string `json:"profileId"`
+}
+
 type State struct {
 	ID        string `json:"id"`
-	ImagePath string `json:"imagePath"`
+	ImageID   string `json:"imageId,omitempty"`   // Object Store image, see store.PutImage
+	ImagePath string `json:"imagePath,omitempty"` // Deprecated: file on the server host
 }
 
 func (s State) IsEmpty() bool {
 	return s.ID == ""
 }
 
+type Image struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	Size     uint64 `json:"size"`
+}
+
 type StateId struct {
 	ID int `json:"id"`
 }
@@ -90,24 +126,161 @@ func (s StateId) IsEmpty() bool {
 }
 
 type Button struct {
-	ID       string   `json:"id"`
-	UUID     string   `json:"uuid"`
-	Settings Settings `json:"settings"`
-	States   []State  `json:"states"`
-	State    string   `json:"state"`
-	Title    string   `json:"title"`
+	ID         string     `json:"id"`
+	UUID       string     `json:"uuid"`
+	Settings   Settings   `json:"settings"`
+	States     []State    `json:"states"`
+	State      string     `json:"state"`
+	Title      string     `json:"title"`
+	TitleStyle TitleStyle `json:"titleStyle"`
 }
 
 func (b Button) IsEmpty() bool {
 	return b.ID == ""
 }
 
+// CurrentState returns the state matching b.State, falling back to the first state.
+func (b Button) CurrentState() State {
+	for _, state := range b.States {
+		if state.ID == b.State {
+			return state
+		}
+	}
+	if len(b.States) > 0 {
+		return b.States[0]
+	}
+	return State{}
+}
+
+// Feedback is live state a plugin draws on a button over its configured
+// image and title, such as the state of an i3 workspace, or on the touch
+// strip segment of a dial.
+type Feedback struct {
+	Title string `json:"title,omitempty"`
+	Color string `json:"color,omitempty"` // "#rrggbb" background replacing the state image
+	Level *int   `json:"level,omitempty"` // 0-100 level bar drawn on the touch strip segment of a dial
+	Image string `json:"image,omitempty"` // base64 image replacing the state image, such as album art
+}
+
+// TimerState is the state of a timer key, kept in the KV store so that
+// timers keep running across server restarts.
+type TimerState struct {
+	Running   bool      `json:"running"`
+	StartedAt time.Time `json:"startedAt,omitempty"` // start of the current run
+	Elapsed   int64     `json:"elapsed"`             // milliseconds run before StartedAt
+	Phase     int       `json:"phase,omitempty"`     // pomodoro phase, even phases are work
+	Done      bool      `json:"done,omitempty"`      // countdown reached zero
+}
+
+// Triggers of a schedule.
+const (
+	TriggerCron       = "cron"       // at the times of a cron expression
+	TriggerConnect    = "connect"    // when the device is plugged in
+	TriggerDisconnect = "disconnect" // when the device is unplugged
+	TriggerProfile    = "profile"    // when the device switches profile
+	TriggerSubject    = "subject"    // when a message is published on a NATS subject
+)
+
+// Schedule runs steps on a device when it is triggered, such as every day at
+// 10:00 or when the device switches to a profile.
+type Schedule struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Trigger string `json:"trigger"`
+	Cron    string `json:"cron,omitempty"`    // five-field cron expression such as 0 10 * * 1-5
+	Profile string `json:"profile,omitempty"` // profile ID switched to, empty for any profile
+	Subject string `json:"subject,omitempty"` // NATS subject, wildcards allowed
+	Steps   []Step `json:"steps"`
+}
+
+type TitleStyle struct {
+	FontSize  int    `json:"fontSize,omitempty"`
+	Color     string `json:"color,omitempty"`
+	Alignment string `json:"alignment,omitempty"` // top, middle or bottom
+	Hidden    bool   `json:"hidden,omitempty"`
+}
+
 type Settings struct {
-	URL     string `json:"url,omitempty"`
-	Text    string `json:"text,omitempty"`
-	Command string `json:"command,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Command   string `json:"command,omitempty"`
+	Page      int    `json:"page,omitempty"`      // 1-based page for sd.navigation.page.goto
+	Workspace string `json:"workspace,omitempty"` // i3 workspace name or number, "#n" for the n-th workspace
+	Keys      string `json:"keys,omitempty"`      // key combination such as ctrl+shift+t, or a media key
+	Sequence  string `json:"sequence,omitempty"`  // one key combination or delay such as 250ms per line
+	Slot      int    `json:"slot,omitempty"`      // 1-based clipboard history entry
+	Preview   bool   `json:"preview,omitempty"`   // show the clipboard history entry on its key
+	Profile   string `json:"profile,omitempty"`   // profile name or ID for sd.navigation.profile
+
+	Brightness int `json:"brightness,omitempty"` // percent for sd.device.brightness
+
+	Browser        string `json:"browser,omitempty"` // browser binary, empty for the default browser
+	BrowserProfile string `json:"browserProfile,omitempty"`
+	Private        bool   `json:"private,omitempty"`
+	NewWindow      bool   `json:"newWindow,omitempty"`
+	Window         string `json:"window,omitempty"` // title expression of a window to raise instead of opening the URL again
+
+	Class   string `json:"class,omitempty"`   // class expression of the windows a window action targets
+	Monitor string `json:"monitor,omitempty"` // 1-based monitor, "next" or "previous"
+
+	AudioTarget string `json:"audioTarget,omitempty"` // "sink", "source" or "app"
+	AudioDevice string `json:"audioDevice,omitempty"` // sink or source name, or application name; empty for the default
+	VolumeStep  int    `json:"volumeStep,omitempty"`  // percent per press or dial tick
+	Volume      int    `json:"volume,omitempty"`      // percent
+
+	Scene  string `json:"scene,omitempty"`  // OBS scene, empty for the current program scene
+	Source string `json:"source,omitempty"` // OBS source or audio input
+
+	Player string `json:"player,omitempty"` // MPRIS player such as spotify, empty for the active player
+	Offset int    `json:"offset,omitempty"` // seconds to seek, negative to seek back
+
+	Metric   string `json:"metric,omitempty"`   // cpu, memory, disk, network, temperature or load
+	Target   string `json:"target,omitempty"`   // mount point, network interface or temperature sensor
+	Style    string `json:"style,omitempty"`    // sparkline or gauge
+	Interval int    `json:"interval,omitempty"` // seconds between samples
+
+	Duration  string `json:"duration,omitempty"`  // countdown or pomodoro work duration such as 25m
+	Break     string `json:"break,omitempty"`     // pomodoro short break such as 5m
+	LongBreak string `json:"longBreak,omitempty"` // pomodoro break after every fourth work phase
+
+	Entity      string `json:"entity,omitempty"`      // Home Assistant entity ID such as light.kitchen
+	Service     string `json:"service,omitempty"`     // Home Assistant service such as light.turn_on
+	ServiceData string `json:"serviceData,omitempty"` // JSON object of service data
+
+	Method      string `json:"method,omitempty"`      // HTTP method, GET by default
+	Headers     string `json:"headers,omitempty"`     // Name: value per line
+	Body        string `json:"body,omitempty"`        // request body template
+	Auth        string `json:"auth,omitempty"`        // "basic" or "bearer"
+	Credentials string `json:"credentials,omitempty"` // user:password or token, ${SD_BUTTON_VAR} reads the environment
+	JSONPath    string `json:"jsonPath,omitempty"`    // dotted path of the response value to show, such as data.0.status
+	Retries     int    `json:"retries,omitempty"`
+	RetryUnsafe bool   `json:"retryUnsafe,omitempty"` // also retry POST, PUT and PATCH after server errors
+
+	WorkDir  string `json:"workDir,omitempty"`
+	Env      string `json:"env,omitempty"`      // KEY=value per line
+	Timeout  int    `json:"timeout,omitempty"`  // seconds, 0 for none
+	Terminal bool   `json:"terminal,omitempty"` // run in a terminal emulator
+	Output   string `json:"output,omitempty"`   // "title" or "state" to show the outcome on the key
+
+	Steps       []Step `json:"steps,omitempty"`       // multi-action sequence
+	ToggleSteps []Step `json:"toggleSteps,omitempty"` // second sequence of a toggle multi-action
 }
 
+// IsEmpty reports whether no setting is set. Settings added to the struct
+// are covered without listing them here.
 func (s Settings) IsEmpty() bool {
-	return s.URL == "" && s.Text == "" && s.Command == ""
+	if len(s.Steps) > 0 || len(s.ToggleSteps) > 0 {
+		return false
+	}
+	s.Steps, s.ToggleSteps = nil, nil
+	return reflect.ValueOf(s).IsZero()
+}
+
+// Step is one action of a multi-action.
+type Step struct {
+	UUID        string   `json:"uuid"`
+	Settings    Settings `json:"settings"`
+	Delay       int      `json:"delay,omitempty"` // milliseconds to wait before the step
+	StopOnError bool     `json:"stopOnError,omitempty"`
 }
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru is a byte-bounded least recently used cache of rendered images.
+type lru struct {
+	mu       sync.Mutex
+	maxBytes int
+	size     int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+func newLRU(maxBytes int) *lru {
+	return &lru{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *lru) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry).value, true
+}
+
+func (c *lru) Add(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(value) > c.maxBytes {
+		return
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.size += len(value) - len(element.Value.(*lruEntry).value)
+		element.Value.(*lruEntry).value = value
+		c.order.MoveToFront(element)
+	} else {
+		c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+		c.size += len(value)
+	}
+
+	for c.size > c.maxBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*lruEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.size -= len(entry.value)
+	}
+}
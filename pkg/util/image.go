@@ -0,0 +1,187 @@
+package util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	// Decoders for the formats accepted in the image library.
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+)
+
+// ImageFormat is the encoding a device expects key images in.
+type ImageFormat string
+
+const (
+	FormatJPEG ImageFormat = "jpeg"
+	FormatBMP  ImageFormat = "bmp"
+)
+
+// Transform is applied after scaling to match how a panel is mounted.
+type Transform string
+
+const (
+	TransformNone           Transform = ""
+	TransformRotate180      Transform = "rotate180"
+	TransformFlipHorizontal Transform = "flip-horizontal"
+	TransformFlipVertical   Transform = "flip-vertical"
+)
+
+// ImageSpec describes the image a device expects. A zero Width or Height
+// keeps the size of the source image.
+type ImageSpec struct {
+	Width     int
+	Height    int
+	Format    ImageFormat
+	Transform Transform
+	Quality   int // JPEG quality, defaults to 95
+}
+
+func (s ImageSpec) cacheKey(source []byte) string {
+	sum := sha256.Sum256(source)
+	return fmt.Sprintf("%x:%dx%d:%s:%s:%d", sum, s.Width, s.Height, s.Format, s.Transform, s.Quality)
+}
+
+var renderCache = newLRU(32 << 20)
+
+// RenderImage decodes source, crops and scales it to spec, applies its
+// transform and encodes it. Results are cached by source hash and spec.
+func RenderImage(source []byte, spec ImageSpec) ([]byte, error) {
+	key := spec.cacheKey(source)
+	if cached, ok := renderCache.Get(key); ok {
+		return cached, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(source))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	out, err := EncodeImage(TransformImage(FillImage(img, spec.Width, spec.Height), spec.Transform), spec)
+	if err != nil {
+		return nil, err
+	}
+
+	renderCache.Add(key, out)
+
+	return out, nil
+}
+
+// FillImage crops img around its centre to the aspect ratio of width x height
+// and scales it to that size over a black background.
+func FillImage(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	if width <= 0 || height <= 0 {
+		width, height = bounds.Dx(), bounds.Dy()
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.Black, image.Point{}, draw.Src)
+
+	crop := bounds
+	if bounds.Dx()*height > bounds.Dy()*width {
+		w := bounds.Dy() * width / height
+		crop.Min.X += (bounds.Dx() - w) / 2
+		crop.Max.X = crop.Min.X + w
+	} else {
+		h := bounds.Dx() * height / width
+		crop.Min.Y += (bounds.Dy() - h) / 2
+		crop.Max.Y = crop.Min.Y + h
+	}
+
+	if crop.Dx() == width && crop.Dy() == height {
+		draw.Draw(dst, dst.Bounds(), img, crop.Min, draw.Over)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Over, nil)
+	}
+
+	return dst
+}
+
+// TransformImage rotates or flips img in place and returns it.
+func TransformImage(img *image.RGBA, transform Transform) *image.RGBA {
+	b := img.Bounds()
+
+	swap := func(x1, y1, x2, y2 int) {
+		i := img.PixOffset(x1, y1)
+		j := img.PixOffset(x2, y2)
+		for k := 0; k < 4; k++ {
+			img.Pix[i+k], img.Pix[j+k] = img.Pix[j+k], img.Pix[i+k]
+		}
+	}
+
+	flipHorizontal := func() {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x1, x2 := b.Min.X, b.Max.X-1; x1 < x2; x1, x2 = x1+1, x2-1 {
+				swap(x1, y, x2, y)
+			}
+		}
+	}
+
+	flipVertical := func() {
+		for y1, y2 := b.Min.Y, b.Max.Y-1; y1 < y2; y1, y2 = y1+1, y2-1 {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				swap(x, y1, x, y2)
+			}
+		}
+	}
+
+	switch transform {
+	case TransformRotate180:
+		flipHorizontal()
+		flipVertical()
+	case TransformFlipHorizontal:
+		flipHorizontal()
+	case TransformFlipVertical:
+		flipVertical()
+	}
+
+	return img
+}
+
+// ColorImage returns a JPEG key image filled with a "#rrggbb" colour.
+func ColorImage(hex string, size int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: ParseHexColor(hex)}, image.Point{}, draw.Src)
+
+	return EncodeImage(img, ImageSpec{Format: FormatJPEG})
+}
+
+// EncodeImage encodes img in the format of spec.
+func EncodeImage(img image.Image, spec ImageSpec) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch spec.Format {
+	case FormatBMP:
+		if err := bmp.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("failed to encode bmp: %w", err)
+		}
+	case FormatJPEG, "":
+		quality := spec.Quality
+		if quality <= 0 {
+			quality = 95
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported image format: %s", spec.Format)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// IsSupportedImage reports whether data is in a format the pipeline decodes.
+func IsSupportedImage(data []byte) bool {
+	_, _, err := image.DecodeConfig(bytes.NewReader(data))
+	return err == nil
+}
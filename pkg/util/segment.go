@@ -0,0 +1,69 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+)
+
+const (
+	segmentFontSize = 20
+	segmentBarColor = "#3b82f6"
+)
+
+// RenderSegment returns a JPEG touch strip segment showing a title and, when
+// level is set, a 0-100 level bar below it, over a dimmed background image
+// when background is set.
+func RenderSegment(title string, level *int, background []byte, width int, height int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.Black, image.Point{}, draw.Src)
+
+	if len(background) > 0 {
+		bg, _, err := image.Decode(bytes.NewReader(background))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode segment background: %w", err)
+		}
+		draw.Draw(img, img.Bounds(), FillImage(bg, width, height), image.Point{}, draw.Src)
+		draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0, 0, 0, 0x99}), image.Point{}, draw.Over)
+	}
+
+	if level != nil {
+		filled := min(max(*level, 0), 100)
+
+		bar := image.Rect(16, height-34, width-16, height-18)
+		draw.Draw(img, bar, image.NewUniform(color.RGBA{0x33, 0x33, 0x33, 0xff}), image.Point{}, draw.Src)
+
+		bar.Max.X = bar.Min.X + bar.Dx()*filled/100
+		draw.Draw(img, bar, image.NewUniform(ParseHexColor(segmentBarColor)), image.Point{}, draw.Src)
+	}
+
+	if title != "" {
+		f, err := loadTitleFont()
+		if err != nil {
+			return nil, err
+		}
+
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{
+			Size:    segmentFontSize,
+			DPI:     72,
+			Hinting: font.HintingFull,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create font face: %w", err)
+		}
+		defer face.Close()
+
+		alignment := "middle"
+		if level != nil {
+			alignment = "top"
+		}
+		drawText(img, face, title, alignment, ParseHexColor(DefaultTitleColor))
+	}
+
+	return EncodeImage(img, ImageSpec{Format: FormatJPEG})
+}
@@ -0,0 +1,145 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"os"
+	"sd/pkg/env"
+	"sd/pkg/types"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	DefaultTitleFontSize = 14
+	DefaultTitleColor    = "#ffffff"
+)
+
+var (
+	titleFont     *opentype.Font
+	titleFontErr  error
+	titleFontOnce sync.Once
+)
+
+func loadTitleFont() (*opentype.Font, error) {
+	titleFontOnce.Do(func() {
+		data, err := os.ReadFile(env.Get("ASSET_PATH", "") + "fonts/Arial.ttf")
+		if err != nil {
+			titleFontErr = fmt.Errorf("failed to read title font: %w", err)
+			return
+		}
+		titleFont, titleFontErr = opentype.Parse(data)
+	})
+
+	return titleFont, titleFontErr
+}
+
+// ParseHexColor parses a "#rrggbb" string, returning white for anything invalid.
+func ParseHexColor(s string) color.RGBA {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 {
+		return color.RGBA{0xff, 0xff, 0xff, 0xff}
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{0xff, 0xff, 0xff, 0xff}
+	}
+
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+}
+
+// DrawTitle draws a title onto a JPEG key image and returns the re-encoded JPEG.
+func DrawTitle(buffer []byte, title string, style types.TitleStyle) ([]byte, error) {
+	if title == "" || style.Hidden {
+		return buffer, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(buffer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key image: %w", err)
+	}
+
+	f, err := loadTitleFont()
+	if err != nil {
+		return nil, err
+	}
+
+	size := style.FontSize
+	if size <= 0 {
+		size = DefaultTitleFontSize
+	}
+
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    float64(size),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font face: %w", err)
+	}
+	defer face.Close()
+
+	img := image.NewRGBA(src.Bounds())
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	textColor := style.Color
+	if textColor == "" {
+		textColor = DefaultTitleColor
+	}
+
+	drawText(img, face, title, style.Alignment, ParseHexColor(textColor))
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: 95}); err != nil {
+		return nil, fmt.Errorf("failed to encode key image: %w", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// drawText writes each line of text horizontally centred, with a dark outline
+// so titles stay readable on light backgrounds.
+func drawText(img *image.RGBA, face font.Face, text string, alignment string, c color.RGBA) {
+	lines := strings.Split(text, "\n")
+	metrics := face.Metrics()
+	lineHeight := metrics.Height.Ceil()
+	blockHeight := lineHeight * len(lines)
+	bounds := img.Bounds()
+
+	var top int
+	switch alignment {
+	case "top":
+		top = bounds.Min.Y + 4
+	case "middle":
+		top = bounds.Min.Y + (bounds.Dy()-blockHeight)/2
+	default:
+		top = bounds.Max.Y - blockHeight - 4
+	}
+
+	shadow := image.NewUniform(color.RGBA{0, 0, 0, 0xff})
+	fill := image.NewUniform(c)
+
+	for i, line := range lines {
+		width := font.MeasureString(face, line).Ceil()
+		x := bounds.Min.X + (bounds.Dx()-width)/2
+		y := top + i*lineHeight + metrics.Ascent.Ceil()
+
+		for _, offset := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			d := font.Drawer{Dst: img, Src: shadow, Face: face, Dot: fixed.P(x+offset[0], y+offset[1])}
+			d.DrawString(line)
+		}
+
+		d := font.Drawer{Dst: img, Src: fill, Face: face, Dot: fixed.P(x, y)}
+		d.DrawString(line)
+	}
+}
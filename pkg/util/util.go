@@ -7,7 +7,6 @@ import (
 	"path/filepath"
 	"runtime"
 
-	"github.com/h2non/bimg"
 	"github.com/karalabe/hid"
 	"github.com/rs/zerolog/log"
 
@@ -61,17 +60,9 @@ func ParseEventBuffer(buf []byte) []int {
 	return pressedButtons
 }
 
-// RotateImageBuffer rotates a JPEG image buffer by 180 degrees
+// RotateImageBuffer rotates a key image buffer by 180 degrees
 func RotateImageBuffer(buffer []byte) ([]byte, error) {
-	// Create an image object from buffer
-	image := bimg.NewImage(buffer)
-
-	// Rotate the image 180 degrees
-	options := bimg.Options{
-		Rotate: 180,
-	}
-
-	rotated, err := image.Process(options)
+	rotated, err := RenderImage(buffer, ImageSpec{Format: FormatJPEG, Transform: TransformRotate180})
 	if err != nil {
 		return nil, fmt.Errorf("failed to rotate image: %w", err)
 	}
@@ -91,14 +82,20 @@ func SetKeyFromBuffer(device *hid.Device, keyId int, buffer []byte, rotate bool)
 		content = rotated
 	}
 
+	return WriteKeyImage(device, keyId, content, ImageReportLength)
+}
+
+// WriteKeyImage writes an encoded key image to a device in reports of reportLength bytes
+func WriteKeyImage(device *hid.Device, keyId int, content []byte, reportLength int) (err error) {
+	payloadLength := reportLength - ImageReportHeaderLength
 	remainingBytes := len(content)
 	iteration := 0
 
 	if device != nil {
 		for remainingBytes > 0 {
 			// Slice the image to fit into the payload size
-			sliceLength := min(remainingBytes, ImageReportPayloadLength)
-			bytesSent := iteration * ImageReportPayloadLength
+			sliceLength := min(remainingBytes, payloadLength)
+			bytesSent := iteration * payloadLength
 
 			// Determine if this is the final chunk
 			var finalizer byte
@@ -128,7 +125,7 @@ func SetKeyFromBuffer(device *hid.Device, keyId int, buffer []byte, rotate bool)
 
 			// Slice the image data
 			payload := append(header, content[bytesSent:bytesSent+sliceLength]...)
-			padding := make([]byte, ImageReportLength-len(payload))
+			padding := make([]byte, reportLength-len(payload))
 
 			// Final payload with padding
 			finalPayload := append(payload, padding...)
@@ -148,90 +145,29 @@ func SetKeyFromBuffer(device *hid.Device, keyId int, buffer []byte, rotate bool)
 	return nil
 }
 
-func ConvertImageToBuffer(imagePath string, width int) ([]byte, error) {
-	// Read the image file into a buffer using bimg
-	buffer, err := bimg.Read(imagePath)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create an image object
-	image := bimg.NewImage(buffer)
-
-	// Force RGB color space
-	converted, err := image.Convert(bimg.JPEG)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create options for resizing
-	options := bimg.Options{
-		Width:   width,
-		Height:  100,
-		Type:    bimg.JPEG,
-		Quality: 95,
-	}
-
-	// Process the image
-	processed, err := bimg.NewImage(converted).Process(options)
-	if err != nil {
-		return nil, err
-	}
-
-	return processed, nil
-}
-
-// ConvertButtonImageToBuffer converts an image for button display (120x120)
+// ConvertButtonImageToBuffer converts an image file for button display
 func ConvertButtonImageToBuffer(imagePath string, size int) ([]byte, error) {
-	buffer, err := bimg.Read(imagePath)
+	buffer, err := os.ReadFile(imagePath)
 	if err != nil {
 		return nil, err
 	}
 
-	image := bimg.NewImage(buffer)
-
-	// Square buttons need different options
-	options := bimg.Options{
-		Width:   size,
-		Height:  size,
-		Type:    bimg.JPEG,
-		Quality: 100,
-		Gravity: bimg.GravityCentre,
-		Crop:    true,
-	}
-
-	processed, err := image.Process(options)
-	if err != nil {
-		return nil, err
-	}
+	return ConvertButtonImage(buffer, size)
+}
 
-	return processed, nil
+// ConvertButtonImage converts an image buffer for button display
+func ConvertButtonImage(buffer []byte, size int) ([]byte, error) {
+	return RenderImage(buffer, ImageSpec{Width: size, Height: size, Format: FormatJPEG})
 }
 
 // ConvertTouchScreenImageToBuffer converts an image for the touch screen (800x100 or 200x100)
 func ConvertTouchScreenImageToBuffer(imagePath string, width int) ([]byte, error) {
-	buffer, err := bimg.Read(imagePath)
-	if err != nil {
-		return nil, err
-	}
-
-	image := bimg.NewImage(buffer)
-
-	options := bimg.Options{
-		Width:   width,
-		Height:  100,
-		Type:    bimg.JPEG,
-		Quality: 95,
-		Gravity: bimg.GravityCentre,
-		Crop:    true,
-	}
-
-	processed, err := image.Process(options)
+	buffer, err := os.ReadFile(imagePath)
 	if err != nil {
 		return nil, err
 	}
 
-	return processed, nil
+	return RenderImage(buffer, ImageSpec{Width: width, Height: 100, Format: FormatJPEG})
 }
 
 // GetProjectRoot returns the absolute path to the project root directory
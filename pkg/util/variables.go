@@ -0,0 +1,86 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atotto/clipboard"
+)
+
+var variable = regexp.MustCompile(`\{[a-z]+(:[A-Za-z_][A-Za-z0-9_]*)?\}`)
+
+// clipboardMu guards clipboard.Primary, which selects the selection read by
+// clipboard.ReadAll.
+var clipboardMu sync.Mutex
+
+// ReadClipboard returns the trimmed text of the clipboard, or of the primary
+// selection when primary is set.
+func ReadClipboard(primary bool) (string, error) {
+	clipboardMu.Lock()
+	defer clipboardMu.Unlock()
+
+	clipboard.Primary = primary
+	defer func() { clipboard.Primary = false }()
+
+	text, err := clipboard.ReadAll()
+	return strings.TrimSpace(text), err
+}
+
+// WriteClipboard replaces the text of the clipboard.
+func WriteClipboard(text string) error {
+	clipboardMu.Lock()
+	defer clipboardMu.Unlock()
+
+	return clipboard.WriteAll(text)
+}
+
+// ExpandVariables replaces the {date}, {time}, {clipboard} and {selection}
+// variables of a template. Each value is passed through escape with its
+// offset in the template.
+func ExpandVariables(template string, escape func(value string, offset int) string) (string, error) {
+	return ExpandVariablesWith(template, nil, escape)
+}
+
+// ExpandVariablesWith is ExpandVariables with more variables: lookup returns
+// the value of a variable name, such as env:HOME for {env:HOME}. Unknown
+// variables are left as they are.
+func ExpandVariablesWith(template string, lookup func(name string) (string, bool), escape func(value string, offset int) string) (string, error) {
+	now := time.Now()
+
+	var b strings.Builder
+	last := 0
+
+	for _, loc := range variable.FindAllStringIndex(template, -1) {
+		b.WriteString(template[last:loc[0]])
+		last = loc[1]
+
+		var value string
+		switch name := template[loc[0]+1 : loc[1]-1]; name {
+		case "date":
+			value = now.Format("2006-01-02")
+		case "time":
+			value = now.Format("15:04")
+		case "clipboard", "selection":
+			var err error
+			if value, err = ReadClipboard(name == "selection"); err != nil {
+				return "", err
+			}
+		default:
+			var ok bool
+			if lookup != nil {
+				value, ok = lookup(name)
+			}
+			if !ok {
+				b.WriteString(template[loc[0]:loc[1]])
+				continue
+			}
+		}
+
+		b.WriteString(escape(value, loc[0]))
+	}
+	b.WriteString(template[last:])
+
+	return b.String(), nil
+}
@@ -1,23 +1,20 @@
 package watchers
 
 import (
+	"sd/pkg/models"
 	"time"
 
 	"github.com/karalabe/hid"
 	"github.com/rs/zerolog/log"
 )
 
-const (
-	VendorIDElgato = 0x0fd9
-)
-
 func WatchStreamDecks(instanceID string, onConnect ConnectHandler, onDisconnect DisconnectHandler) error {
 	// Track currently connected devices
 	connectedDevices := make(map[string]bool)
 
 	for {
 		// Find all Stream Deck devices
-		devices := hid.Enumerate(VendorIDElgato, 0)
+		devices := hid.Enumerate(models.VendorIDElgato, 0)
 
 		// Track current devices for this iteration
 		currentDevices := make(map[string]bool)
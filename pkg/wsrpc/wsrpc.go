@@ -0,0 +1,163 @@
+// Package wsrpc multiplexes requests and events over a websocket, for the
+// JSON protocols of obs-websocket and Home Assistant.
+package wsrpc
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// DispatchFunc sorts a message received on a connection. It returns the ID
+// of the request a response answers, or the event a message carries, or
+// neither for messages to ignore.
+type DispatchFunc[E any] func(data []byte) (id string, event *E)
+
+// Conn is a websocket carrying requests, their responses and events of type
+// E. Responses are matched to their requests by ID.
+type Conn[E any] struct {
+	ws       *websocket.Conn
+	dispatch DispatchFunc[E]
+	closed   error
+	timeout  time.Duration
+
+	writeMu sync.Mutex
+	nextID  atomic.Uint64
+
+	mu      sync.Mutex
+	pending map[string]chan []byte
+
+	events chan E
+	done   chan struct{}
+}
+
+// New reads ws until it fails, dispatching its messages with dispatch.
+// Requests on a lost connection fail with closed, and requests without a
+// response after timeout fail too. Up to buffer events are queued.
+func New[E any](ws *websocket.Conn, dispatch DispatchFunc[E], closed error, timeout time.Duration, buffer int) *Conn[E] {
+	c := &Conn[E]{
+		ws:       ws,
+		dispatch: dispatch,
+		closed:   closed,
+		timeout:  timeout,
+		pending:  make(map[string]chan []byte),
+		events:   make(chan E, buffer),
+		done:     make(chan struct{}),
+	}
+	go c.read()
+
+	return c
+}
+
+// read dispatches responses to their requests and queues events until the
+// connection fails.
+func (c *Conn[E]) read() {
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(c.ws, &data); err != nil {
+			break
+		}
+
+		id, event := c.dispatch(data)
+		switch {
+		case event != nil:
+			select {
+			case c.events <- *event:
+			default:
+				// Dropped rather than blocking responses to requests.
+			}
+		case id != "":
+			c.mu.Lock()
+			ch, ok := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+			if ok {
+				ch <- data
+			}
+		}
+	}
+
+	c.mu.Lock()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	close(c.done)
+	c.mu.Unlock()
+
+	close(c.events)
+}
+
+// NextID returns a new request ID.
+func (c *Conn[E]) NextID() uint64 {
+	return c.nextID.Add(1)
+}
+
+// Call sends request, named name in errors, and returns the message
+// answering id.
+func (c *Conn[E]) Call(name string, id string, request any) ([]byte, error) {
+	ch := make(chan []byte, 1)
+
+	c.mu.Lock()
+	if c.isDone() {
+		c.mu.Unlock()
+		return nil, c.closed
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	err := websocket.JSON.Send(c.ws, request)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.forget(id)
+		return nil, fmt.Errorf("failed to send %s: %w", name, err)
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	select {
+	case data, ok := <-ch:
+		if !ok {
+			return nil, c.closed
+		}
+		return data, nil
+	case <-timer.C:
+		c.forget(id)
+		return nil, fmt.Errorf("%s timed out", name)
+	}
+}
+
+func (c *Conn[E]) forget(id string) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func (c *Conn[E]) isDone() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Events returns the events received. It is closed with the connection.
+func (c *Conn[E]) Events() <-chan E {
+	return c.events
+}
+
+// Done is closed when the connection is lost.
+func (c *Conn[E]) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the connection.
+func (c *Conn[E]) Close() error {
+	return c.ws.Close()
+}